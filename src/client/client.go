@@ -0,0 +1,246 @@
+// Package client is the Go client of the engine's HTTP server (kv serve --http)
+// Client talks to a server, Fake keeps everything in memory for unit tests, both implement KV
+// Only HTTP is spoken: the gRPC server is used through the stubs in system/grpcapi/kvpb,
+// and the redis server through any redis client
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of Options
+const (
+	DEFAULT_MAX_CONNS      = 16
+	DEFAULT_MAX_RETRIES    = 3
+	DEFAULT_RETRY_BACKOFF  = 50 * time.Millisecond
+	MAX_RETRY_BACKOFF      = 2 * time.Second
+	DEFAULT_TIMEOUT        = 30 * time.Second
+	DEFAULT_SCAN_PAGE_SIZE = 100
+	MAX_SCAN_PAGE_SIZE     = 1000 // largest page the server returns
+)
+
+// Errors are *Error values that match these with errors.Is
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrRateLimited = errors.New("rate limited")
+	ErrConflict    = errors.New("conflict")
+)
+
+// Error is returned for requests that the server answered with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // time until the token bucket is filled again, set for rate limited requests
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("kv: %s (status %d)", err.Message, err.StatusCode)
+}
+
+// Unwrap returns ErrNotFound, ErrRateLimited or ErrConflict, or nil for other statuses
+func (err *Error) Unwrap() error {
+	switch err.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrConflict
+	}
+	return nil
+}
+
+// Options of a client, fields that are zero have default values
+type Options struct {
+	MaxConns     int           // most connections to the server, idle ones are kept for later calls
+	MaxRetries   int           // retries of idempotent calls after network errors and 502, 503 or 504, negative means none
+	RetryBackoff time.Duration // wait before the first retry, doubled before every next one
+	Timeout      time.Duration // deadline of calls whose context has none, negative means no deadline
+	ScanPageSize int           // records read by one request of a scan
+
+	Transport http.RoundTripper // used instead of the pooled transport if it is set
+}
+
+// Client calls a server over HTTP, it is safe for concurrent use
+// Calls that can be repeated (reads, puts, deletes and creating sketches) are retried when the server can't be reached,
+// others are not, since the server may have run them before the connection failed
+type Client struct {
+	base    string // scheme and host of the server
+	http    *http.Client
+	options Options
+}
+
+var _ KV = (*Client)(nil)
+
+// New returns a client of the server at the address, e.g. "127.0.0.1:8080" or "http://kv.local:8080"
+// The address must be the one given to --http, addresses of the gRPC and redis servers don't work
+func New(address string, options Options) (*Client, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	base, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("kv: invalid address %q", address)
+	}
+	if options.MaxConns <= 0 {
+		options.MaxConns = DEFAULT_MAX_CONNS
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DEFAULT_MAX_RETRIES
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = DEFAULT_RETRY_BACKOFF
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DEFAULT_TIMEOUT
+	}
+	if options.ScanPageSize <= 0 {
+		options.ScanPageSize = DEFAULT_SCAN_PAGE_SIZE
+	}
+	options.ScanPageSize = min(options.ScanPageSize, MAX_SCAN_PAGE_SIZE)
+	transport := options.Transport
+	if transport == nil {
+		transport = &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			MaxIdleConns:        options.MaxConns,
+			MaxIdleConnsPerHost: options.MaxConns,
+			MaxConnsPerHost:     options.MaxConns,
+			IdleConnTimeout:     90 * time.Second,
+		}
+	}
+	return &Client{
+		base:    base.Scheme + "://" + base.Host,
+		http:    &http.Client{Transport: transport},
+		options: options,
+	}, nil
+}
+
+// Close closes idle connections, calls that are running aren't stopped
+func (client *Client) Close() {
+	client.http.CloseIdleConnections()
+}
+
+// request sent by a call
+type call struct {
+	method      string
+	path        string // escaped
+	query       url.Values
+	header      http.Header
+	body        []byte
+	contentType string
+	idempotent  bool
+}
+
+// sends the request, retrying it if it is idempotent, and returns the body of a successful response
+func (client *Client) do(ctx context.Context, c call) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok && client.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.options.Timeout)
+		defer cancel()
+	}
+	backoff := client.options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		body, retry, err := client.send(ctx, c)
+		if err == nil || !retry || !c.idempotent || attempt >= client.options.MaxRetries {
+			return body, err
+		}
+		// the wait is random up to the backoff, so clients that failed together don't retry together
+		timer := time.NewTimer(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, MAX_RETRY_BACKOFF)
+	}
+}
+
+// sends the request once, retry is true if it failed in a way that can be retried
+func (client *Client) send(ctx context.Context, c call) ([]byte, bool, error) {
+	address := client.base + c.path
+	if len(c.query) > 0 {
+		address += "?" + c.query.Encode()
+	}
+	var body io.Reader
+	if c.body != nil {
+		body = bytes.NewReader(c.body)
+	}
+	request, err := http.NewRequestWithContext(ctx, c.method, address, body)
+	if err != nil {
+		return nil, false, err
+	}
+	for name, values := range c.header {
+		request.Header[name] = values
+	}
+	if c.contentType != "" {
+		request.Header.Set("Content-Type", c.contentType)
+	}
+	response, err := client.http.Do(request)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	if response.StatusCode < 300 {
+		return data, false, nil
+	}
+
+	apiErr := &Error{StatusCode: response.StatusCode, Message: http.StatusText(response.StatusCode)}
+	var message struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &message) == nil && message.Error != "" {
+		apiErr.Message = message.Error
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, true, apiErr
+	}
+	return nil, false, apiErr
+}
+
+// sends a JSON body and decodes the JSON response into result, if it isn't nil
+func (client *Client) doJSON(ctx context.Context, c call, body any, result any) error {
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		c.body = data
+		c.contentType = "application/json"
+	}
+	data, err := client.do(ctx, c)
+	if err != nil || result == nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// Ping returns nil if the server answers
+func (client *Client) Ping(ctx context.Context) error {
+	_, err := client.do(ctx, call{method: http.MethodGet, path: "/healthz", idempotent: true})
+	return err
+}
@@ -0,0 +1,602 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	hyperLogLog "github.com/natasakasikovic/Key-Value-engine/src/structs/HyperLogLog"
+	simHash "github.com/natasakasikovic/Key-Value-engine/src/structs/simHash"
+)
+
+// Fake keeps records and sketches in memory, for unit tests of code that uses KV, it is safe for concurrent use
+// Errors are *Error values with the status codes the server sends
+// Sketches are exact: filters have no false positives and counts aren't estimated, answers of the server are never below them
+// Keys reserved for sketches by the engine aren't rejected
+type Fake struct {
+	lock      sync.Mutex
+	failures  []error
+	records   map[string][]byte
+	blooms    map[string]map[string]bool
+	cms       map[string]map[string]uint32
+	hlls      map[string]map[string]bool
+	cuckoos   map[string]map[string]int
+	topKs     map[string]*fakeTopK
+	simHashes map[string]uint64
+}
+
+type fakeTopK struct {
+	k      uint32
+	counts map[string]uint32
+}
+
+var _ KV = (*Fake)(nil)
+
+func NewFake() *Fake {
+	return &Fake{
+		records:   make(map[string][]byte),
+		blooms:    make(map[string]map[string]bool),
+		cms:       make(map[string]map[string]uint32),
+		hlls:      make(map[string]map[string]bool),
+		cuckoos:   make(map[string]map[string]int),
+		topKs:     make(map[string]*fakeTopK),
+		simHashes: make(map[string]uint64),
+	}
+}
+
+// FailNext makes the next calls return the passed errors, one per call, without running them
+// e.g. &Error{StatusCode: http.StatusTooManyRequests} to test how rate limiting is handled
+func (fake *Fake) FailNext(errs ...error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.failures = append(fake.failures, errs...)
+}
+
+// locks the fake and returns the next failure or the context error, the caller unlocks it
+func (fake *Fake) begin(ctx context.Context) error {
+	fake.lock.Lock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(fake.failures) > 0 {
+		err := fake.failures[0]
+		fake.failures = fake.failures[1:]
+		return err
+	}
+	return nil
+}
+
+func badRequest(format string, args ...any) *Error {
+	return &Error{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// names of the structures in messages of the engine
+var sketchNames = map[SketchType]string{
+	BLOOM_FILTER:  "bloomFilter",
+	COUNT_MIN:     "countMinSketch",
+	HYPERLOGLOG:   "hyperLogLog",
+	CUCKOO_FILTER: "cuckooFilter",
+	TOP_K:         "topK",
+	SIMHASH:       "simhash",
+}
+
+func sketchNotFound(sketch SketchType, name string) *Error {
+	return &Error{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("%s %s does not exist", sketchNames[sketch], name)}
+}
+
+func checkName(name string) error {
+	if name == "" {
+		return badRequest("name must not be empty")
+	}
+	return nil
+}
+
+func checkRate(epsilon float64, delta float64) error {
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return badRequest("epsilon and delta must be between 0 and 1")
+	}
+	return nil
+}
+
+// returns the sketch with the name from the map of its type
+func find[T any](sketches map[string]T, sketch SketchType, name string) (T, error) {
+	value, ok := sketches[name]
+	if !ok {
+		return value, sketchNotFound(sketch, name)
+	}
+	return value, nil
+}
+
+// returns the sketches with the names, for merging
+func findAll[T any](sketches map[string]T, sketch SketchType, names []string) ([]T, error) {
+	if len(names) == 0 {
+		return nil, badRequest("names must not be empty")
+	}
+	var found []T
+	for _, name := range names {
+		value, err := find(sketches, sketch, name)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, value)
+	}
+	return found, nil
+}
+
+func (fake *Fake) Ping(ctx context.Context) error {
+	defer fake.lock.Unlock()
+	return fake.begin(ctx)
+}
+
+func (fake *Fake) Get(ctx context.Context, key string) ([]byte, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return nil, err
+	}
+	value, ok := fake.records[key]
+	if !ok {
+		return nil, &Error{StatusCode: http.StatusNotFound, Message: "key not found"}
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (fake *Fake) Put(ctx context.Context, key string, value []byte) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if key == "" {
+		return badRequest("invalid key")
+	}
+	fake.records[key] = append([]byte{}, value...)
+	return nil
+}
+
+func (fake *Fake) PutIfAbsent(ctx context.Context, key string, value []byte) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if key == "" {
+		return badRequest("invalid key")
+	}
+	if _, ok := fake.records[key]; ok {
+		return &Error{StatusCode: http.StatusPreconditionFailed, Message: "key already exists"}
+	}
+	fake.records[key] = append([]byte{}, value...)
+	return nil
+}
+
+func (fake *Fake) Delete(ctx context.Context, key string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if key == "" {
+		return badRequest("invalid key")
+	}
+	delete(fake.records, key)
+	return nil
+}
+
+func (fake *Fake) Batch(ctx context.Context, operations []Operation) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	for i, operation := range operations {
+		if operation.Key == "" {
+			return badRequest("operation %d: invalid key", i)
+		}
+	}
+	for _, operation := range operations {
+		if operation.Delete {
+			delete(fake.records, operation.Key)
+		} else {
+			fake.records[operation.Key] = append([]byte{}, operation.Value...)
+		}
+	}
+	return nil
+}
+
+func (fake *Fake) ScanPrefix(ctx context.Context, prefix string, fn func(record Record) bool) error {
+	return fake.scan(ctx, func(key string) bool { return strings.HasPrefix(key, prefix) }, fn)
+}
+
+func (fake *Fake) ScanRange(ctx context.Context, start string, end string, fn func(record Record) bool) error {
+	return fake.scan(ctx, func(key string) bool { return key >= start && key <= end }, fn)
+}
+
+// fn is called after the fake is unlocked, so it can call the fake
+func (fake *Fake) scan(ctx context.Context, match func(key string) bool, fn func(record Record) bool) error {
+	var records []Record
+	err := func() error {
+		defer fake.lock.Unlock()
+		if err := fake.begin(ctx); err != nil {
+			return err
+		}
+		for key, value := range fake.records {
+			if match(key) {
+				records = append(records, Record{Key: key, Value: append([]byte{}, value...)})
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	for _, record := range records {
+		if !fn(record) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (fake *Fake) BloomCreate(ctx context.Context, name string, expected int, falsePositiveRate float64) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if expected < 1 {
+		return badRequest("expected number of elements must be positive")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return badRequest("false positive rate must be between 0 and 1")
+	}
+	if err := checkName(name); err != nil {
+		return err
+	}
+	fake.blooms[name] = make(map[string]bool)
+	return nil
+}
+
+func (fake *Fake) BloomAdd(ctx context.Context, name string, elements ...string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	set, err := find(fake.blooms, BLOOM_FILTER, name)
+	if err != nil {
+		return err
+	}
+	for _, element := range elements {
+		set[element] = true
+	}
+	return nil
+}
+
+func (fake *Fake) BloomExists(ctx context.Context, name string, element string) (bool, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return false, err
+	}
+	set, err := find(fake.blooms, BLOOM_FILTER, name)
+	return set[element], err
+}
+
+func (fake *Fake) MergeBloomFilters(ctx context.Context, dst string, names []string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	sets, err := findAll(fake.blooms, BLOOM_FILTER, names)
+	if err != nil {
+		return err
+	}
+	fake.blooms[dst] = union(sets)
+	return nil
+}
+
+func union(sets []map[string]bool) map[string]bool {
+	result := make(map[string]bool)
+	for _, set := range sets {
+		for element := range set {
+			result[element] = true
+		}
+	}
+	return result
+}
+
+func (fake *Fake) CMSCreate(ctx context.Context, name string, epsilon float64, delta float64) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if err := checkRate(epsilon, delta); err != nil {
+		return err
+	}
+	if err := checkName(name); err != nil {
+		return err
+	}
+	fake.cms[name] = make(map[string]uint32)
+	return nil
+}
+
+func (fake *Fake) CMSIncr(ctx context.Context, name string, events ...string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	counts, err := find(fake.cms, COUNT_MIN, name)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		counts[event]++
+	}
+	return nil
+}
+
+func (fake *Fake) CMSQuery(ctx context.Context, name string, event string) (uint32, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return 0, err
+	}
+	counts, err := find(fake.cms, COUNT_MIN, name)
+	return counts[event], err
+}
+
+func (fake *Fake) MergeCMS(ctx context.Context, dst string, names []string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	sketches, err := findAll(fake.cms, COUNT_MIN, names)
+	if err != nil {
+		return err
+	}
+	result := make(map[string]uint32)
+	for _, counts := range sketches {
+		for event, count := range counts {
+			result[event] += count
+		}
+	}
+	fake.cms[dst] = result
+	return nil
+}
+
+func (fake *Fake) HLLCreate(ctx context.Context, name string, precision uint8) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if precision < hyperLogLog.HLL_MIN_PRECISION || precision > hyperLogLog.HLL_MAX_PRECISION {
+		return badRequest("precision must be between %d and %d", hyperLogLog.HLL_MIN_PRECISION, hyperLogLog.HLL_MAX_PRECISION)
+	}
+	if err := checkName(name); err != nil {
+		return err
+	}
+	fake.hlls[name] = make(map[string]bool)
+	return nil
+}
+
+func (fake *Fake) HLLAdd(ctx context.Context, name string, elements ...string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	set, err := find(fake.hlls, HYPERLOGLOG, name)
+	if err != nil {
+		return err
+	}
+	for _, element := range elements {
+		set[element] = true
+	}
+	return nil
+}
+
+func (fake *Fake) HLLCount(ctx context.Context, name string) (uint64, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return 0, err
+	}
+	set, err := find(fake.hlls, HYPERLOGLOG, name)
+	return uint64(len(set)), err
+}
+
+func (fake *Fake) MergeHLL(ctx context.Context, dst string, names []string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	sets, err := findAll(fake.hlls, HYPERLOGLOG, names)
+	if err != nil {
+		return err
+	}
+	fake.hlls[dst] = union(sets)
+	return nil
+}
+
+func (fake *Fake) CuckooCreate(ctx context.Context, name string, expected int) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if expected < 1 {
+		return badRequest("expected number of elements must be positive")
+	}
+	if err := checkName(name); err != nil {
+		return err
+	}
+	fake.cuckoos[name] = make(map[string]int)
+	return nil
+}
+
+func (fake *Fake) CuckooAdd(ctx context.Context, name string, elements ...string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	counts, err := find(fake.cuckoos, CUCKOO_FILTER, name)
+	if err != nil {
+		return err
+	}
+	for _, element := range elements {
+		counts[element]++
+	}
+	return nil
+}
+
+func (fake *Fake) CuckooExists(ctx context.Context, name string, element string) (bool, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return false, err
+	}
+	counts, err := find(fake.cuckoos, CUCKOO_FILTER, name)
+	return counts[element] > 0, err
+}
+
+func (fake *Fake) CuckooRemove(ctx context.Context, name string, element string) (bool, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return false, err
+	}
+	counts, err := find(fake.cuckoos, CUCKOO_FILTER, name)
+	if err != nil || counts[element] == 0 {
+		return false, err
+	}
+	counts[element]--
+	if counts[element] == 0 {
+		delete(counts, element)
+	}
+	return true, nil
+}
+
+func (fake *Fake) CuckooCount(ctx context.Context, name string) (uint64, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return 0, err
+	}
+	counts, err := find(fake.cuckoos, CUCKOO_FILTER, name)
+	var total uint64 = 0
+	for _, count := range counts {
+		total += uint64(count)
+	}
+	return total, err
+}
+
+func (fake *Fake) TopKCreate(ctx context.Context, name string, k uint32, epsilon float64, delta float64) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if k < 1 {
+		return badRequest("k must be positive")
+	}
+	if err := checkRate(epsilon, delta); err != nil {
+		return err
+	}
+	if err := checkName(name); err != nil {
+		return err
+	}
+	fake.topKs[name] = &fakeTopK{k: k, counts: make(map[string]uint32)}
+	return nil
+}
+
+func (fake *Fake) TopKAdd(ctx context.Context, name string, items ...string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	topK, err := find(fake.topKs, TOP_K, name)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		topK.counts[item]++
+	}
+	return nil
+}
+
+// items with equal counts are ordered by key, as the engine orders them
+func (fake *Fake) TopKList(ctx context.Context, name string) ([]TopKItem, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return nil, err
+	}
+	topK, err := find(fake.topKs, TOP_K, name)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]TopKItem, 0, len(topK.counts))
+	for key, count := range topK.counts {
+		list = append(list, TopKItem{Key: key, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list[:min(len(list), int(topK.k))], nil
+}
+
+func (fake *Fake) TopKQuery(ctx context.Context, name string, item string) (uint32, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return 0, err
+	}
+	topK, err := find(fake.topKs, TOP_K, name)
+	if err != nil {
+		return 0, err
+	}
+	return topK.counts[item], nil
+}
+
+// fingerprints are computed as the engine computes them
+func (fake *Fake) SimHashFingerprint(ctx context.Context, name string, text string) (uint64, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return 0, err
+	}
+	if err := checkName(name); err != nil {
+		return 0, err
+	}
+	fingerprint := simHash.GetFingerprint(text)
+	fake.simHashes[name] = fingerprint
+	return fingerprint, nil
+}
+
+func (fake *Fake) SimHashDistance(ctx context.Context, name1 string, name2 string) (uint8, error) {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return 0, err
+	}
+	fingerprints, err := findAll(fake.simHashes, SIMHASH, []string{name1, name2})
+	if err != nil {
+		return 0, err
+	}
+	return simHash.HammingDistance(fingerprints[0], fingerprints[1]), nil
+}
+
+func (fake *Fake) DeleteSketch(ctx context.Context, sketch SketchType, name string) error {
+	defer fake.lock.Unlock()
+	if err := fake.begin(ctx); err != nil {
+		return err
+	}
+	if err := checkName(name); err != nil {
+		return err
+	}
+	switch sketch {
+	case BLOOM_FILTER:
+		delete(fake.blooms, name)
+	case COUNT_MIN:
+		delete(fake.cms, name)
+	case HYPERLOGLOG:
+		delete(fake.hlls, name)
+	case CUCKOO_FILTER:
+		delete(fake.cuckoos, name)
+	case TOP_K:
+		delete(fake.topKs, name)
+	case SIMHASH:
+		delete(fake.simHashes, name)
+	default:
+		return &Error{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("unknown structure type %q", sketch)}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// KV is the API of the engine as it is used by clients, implemented by Client and Fake
+// Methods have the names of Engine methods, sketch methods add all the passed elements
+type KV interface {
+	Ping(ctx context.Context) error
+
+	Get(ctx context.Context, key string) ([]byte, error) // ErrNotFound if the key doesn't exist
+	Put(ctx context.Context, key string, value []byte) error
+	PutIfAbsent(ctx context.Context, key string, value []byte) error // ErrConflict if the key exists
+	Delete(ctx context.Context, key string) error                    // deleting a key that doesn't exist isn't an error
+	Batch(ctx context.Context, operations []Operation) error
+	ScanPrefix(ctx context.Context, prefix string, fn func(record Record) bool) error
+	ScanRange(ctx context.Context, start string, end string, fn func(record Record) bool) error
+
+	BloomCreate(ctx context.Context, name string, expected int, falsePositiveRate float64) error
+	BloomAdd(ctx context.Context, name string, elements ...string) error
+	BloomExists(ctx context.Context, name string, element string) (bool, error)
+	MergeBloomFilters(ctx context.Context, dst string, names []string) error
+
+	CMSCreate(ctx context.Context, name string, epsilon float64, delta float64) error
+	CMSIncr(ctx context.Context, name string, events ...string) error
+	CMSQuery(ctx context.Context, name string, event string) (uint32, error)
+	MergeCMS(ctx context.Context, dst string, names []string) error
+
+	HLLCreate(ctx context.Context, name string, precision uint8) error
+	HLLAdd(ctx context.Context, name string, elements ...string) error
+	HLLCount(ctx context.Context, name string) (uint64, error)
+	MergeHLL(ctx context.Context, dst string, names []string) error
+
+	CuckooCreate(ctx context.Context, name string, expected int) error
+	CuckooAdd(ctx context.Context, name string, elements ...string) error
+	CuckooExists(ctx context.Context, name string, element string) (bool, error)
+	CuckooRemove(ctx context.Context, name string, element string) (bool, error)
+	CuckooCount(ctx context.Context, name string) (uint64, error)
+
+	TopKCreate(ctx context.Context, name string, k uint32, epsilon float64, delta float64) error
+	TopKAdd(ctx context.Context, name string, items ...string) error
+	TopKList(ctx context.Context, name string) ([]TopKItem, error)
+	TopKQuery(ctx context.Context, name string, item string) (uint32, error)
+
+	SimHashFingerprint(ctx context.Context, name string, text string) (uint64, error)
+	SimHashDistance(ctx context.Context, name1 string, name2 string) (uint8, error)
+
+	DeleteSketch(ctx context.Context, sketch SketchType, name string) error
+}
+
+type Record struct {
+	Key   string
+	Value []byte
+}
+
+// Operation of a batch, it puts the value or deletes the key if Delete is true
+type Operation struct {
+	Key    string
+	Value  []byte
+	Delete bool
+}
+
+// record as the server sends it, values that aren't valid UTF-8 are encoded in base64
+type jsonRecord struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"`
+}
+
+func (record jsonRecord) decode() (Record, error) {
+	if record.Encoding != "base64" {
+		return Record{Key: record.Key, Value: []byte(record.Value)}, nil
+	}
+	value, err := base64.StdEncoding.DecodeString(record.Value)
+	return Record{Key: record.Key, Value: value}, err
+}
+
+type scanPage struct {
+	Records []jsonRecord `json:"records"`
+	More    bool         `json:"more"`
+}
+
+type batchOperation struct {
+	Op       string `json:"op"`
+	Key      string `json:"key"`
+	Value    string `json:"value,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+func keyPath(key string) string {
+	return "/kv/" + url.PathEscape(key)
+}
+
+func (client *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	header := http.Header{"Accept": {"application/octet-stream"}}
+	return client.do(ctx, call{method: http.MethodGet, path: keyPath(key), header: header, idempotent: true})
+}
+
+func (client *Client) Put(ctx context.Context, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := client.do(ctx, call{method: http.MethodPut, path: keyPath(key), body: value,
+		contentType: "application/octet-stream", idempotent: true})
+	return err
+}
+
+// PutIfAbsent isn't retried, a retry after a put that succeeded would fail with ErrConflict
+func (client *Client) PutIfAbsent(ctx context.Context, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := client.do(ctx, call{method: http.MethodPut, path: keyPath(key), body: value,
+		contentType: "application/octet-stream", header: http.Header{"If-None-Match": {"*"}}})
+	return err
+}
+
+func (client *Client) Delete(ctx context.Context, key string) error {
+	_, err := client.do(ctx, call{method: http.MethodDelete, path: keyPath(key), idempotent: true})
+	return err
+}
+
+// Batch runs the operations in order on the server, no other request runs between them
+// If one fails, the ones before it stay applied, the error tells which one failed
+func (client *Client) Batch(ctx context.Context, operations []Operation) error {
+	body := struct {
+		Operations []batchOperation `json:"operations"`
+	}{make([]batchOperation, 0, len(operations))}
+	for _, operation := range operations {
+		if operation.Delete {
+			body.Operations = append(body.Operations, batchOperation{Op: "delete", Key: operation.Key})
+			continue
+		}
+		body.Operations = append(body.Operations, batchOperation{Op: "put", Key: operation.Key,
+			Value: base64.StdEncoding.EncodeToString(operation.Value), Encoding: "base64"})
+	}
+	return client.doJSON(ctx, call{method: http.MethodPost, path: "/kv"}, body, nil)
+}
+
+// ScanPrefix calls fn for every record whose key begins with the prefix, in key order, until fn returns false
+// Records are read in pages, each one continues after the last key of the previous one,
+// so records written during the scan may be returned if their keys are after it
+func (client *Client) ScanPrefix(ctx context.Context, prefix string, fn func(record Record) bool) error {
+	return client.scan(ctx, url.Values{"prefix": {prefix}}, fn)
+}
+
+// ScanRange does the same as ScanPrefix, for records whose keys are between start and end, both included
+func (client *Client) ScanRange(ctx context.Context, start string, end string, fn func(record Record) bool) error {
+	return client.scan(ctx, url.Values{"start": {start}, "end": {end}}, fn)
+}
+
+func (client *Client) scan(ctx context.Context, query url.Values, fn func(record Record) bool) error {
+	query.Set("size", strconv.Itoa(client.options.ScanPageSize))
+	for {
+		var page scanPage
+		if err := client.doJSON(ctx, call{method: http.MethodGet, path: "/kv", query: query, idempotent: true}, nil, &page); err != nil {
+			return err
+		}
+		for _, jsonRecord := range page.Records {
+			record, err := jsonRecord.decode()
+			if err != nil {
+				return err
+			}
+			if !fn(record) {
+				return nil
+			}
+		}
+		if !page.More || len(page.Records) == 0 {
+			return nil
+		}
+		query.Set("after", page.Records[len(page.Records)-1].Key)
+	}
+}
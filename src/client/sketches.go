@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SketchType is the type of a probabilistic structure, as it is written in paths of the server
+type SketchType string
+
+const (
+	BLOOM_FILTER  SketchType = "bf"
+	COUNT_MIN     SketchType = "cms"
+	HYPERLOGLOG   SketchType = "hll"
+	CUCKOO_FILTER SketchType = "cf"
+	TOP_K         SketchType = "topk"
+	SIMHASH       SketchType = "simhash"
+)
+
+type TopKItem struct {
+	Key   string `json:"key"`
+	Count uint32 `json:"count"`
+}
+
+func sketchPath(sketch SketchType, name string, operation string) string {
+	path := "/sketches/" + string(sketch) + "/" + url.PathEscape(name)
+	if operation != "" {
+		path += "/" + operation
+	}
+	return path
+}
+
+// creating a structure replaces the one with the same name, so it can be retried
+func (client *Client) create(ctx context.Context, sketch SketchType, name string, parameters any) error {
+	return client.doJSON(ctx, call{method: http.MethodPut, path: sketchPath(sketch, name, ""), idempotent: true}, parameters, nil)
+}
+
+// adding elements isn't retried, since adding them twice changes counts
+func (client *Client) add(ctx context.Context, sketch SketchType, name string, operation string, elements []string) error {
+	body := map[string][]string{"elements": elements}
+	return client.doJSON(ctx, call{method: http.MethodPost, path: sketchPath(sketch, name, operation)}, body, nil)
+}
+
+// merging saves the same union again when it is repeated, so it can be retried
+func (client *Client) merge(ctx context.Context, sketch SketchType, dst string, names []string) error {
+	body := map[string][]string{"names": names}
+	return client.doJSON(ctx, call{method: http.MethodPost, path: sketchPath(sketch, dst, "merge"), idempotent: true}, body, nil)
+}
+
+// sends a GET request for the operation and decodes the response into result
+func (client *Client) query(ctx context.Context, sketch SketchType, name string, operation string, query url.Values, result any) error {
+	return client.doJSON(ctx, call{method: http.MethodGet, path: sketchPath(sketch, name, operation), query: query, idempotent: true}, nil, result)
+}
+
+func (client *Client) exists(ctx context.Context, sketch SketchType, name string, element string) (bool, error) {
+	var result struct {
+		Exists bool `json:"exists"`
+	}
+	err := client.query(ctx, sketch, name, "exists", url.Values{"element": {element}}, &result)
+	return result.Exists, err
+}
+
+func (client *Client) BloomCreate(ctx context.Context, name string, expected int, falsePositiveRate float64) error {
+	return client.create(ctx, BLOOM_FILTER, name, map[string]any{"expected": expected, "fp": falsePositiveRate})
+}
+
+// BloomAdd is retried, adding an element to a bloom filter twice doesn't change it
+func (client *Client) BloomAdd(ctx context.Context, name string, elements ...string) error {
+	body := map[string][]string{"elements": elements}
+	return client.doJSON(ctx, call{method: http.MethodPost, path: sketchPath(BLOOM_FILTER, name, "add"), idempotent: true}, body, nil)
+}
+
+func (client *Client) BloomExists(ctx context.Context, name string, element string) (bool, error) {
+	return client.exists(ctx, BLOOM_FILTER, name, element)
+}
+
+func (client *Client) MergeBloomFilters(ctx context.Context, dst string, names []string) error {
+	return client.merge(ctx, BLOOM_FILTER, dst, names)
+}
+
+func (client *Client) CMSCreate(ctx context.Context, name string, epsilon float64, delta float64) error {
+	return client.create(ctx, COUNT_MIN, name, map[string]float64{"epsilon": epsilon, "delta": delta})
+}
+
+func (client *Client) CMSIncr(ctx context.Context, name string, events ...string) error {
+	return client.add(ctx, COUNT_MIN, name, "incr", events)
+}
+
+func (client *Client) CMSQuery(ctx context.Context, name string, event string) (uint32, error) {
+	var result struct {
+		Count uint32 `json:"count"`
+	}
+	err := client.query(ctx, COUNT_MIN, name, "query", url.Values{"element": {event}}, &result)
+	return result.Count, err
+}
+
+func (client *Client) MergeCMS(ctx context.Context, dst string, names []string) error {
+	return client.merge(ctx, COUNT_MIN, dst, names)
+}
+
+func (client *Client) HLLCreate(ctx context.Context, name string, precision uint8) error {
+	return client.create(ctx, HYPERLOGLOG, name, map[string]uint8{"precision": precision})
+}
+
+// HLLAdd is retried, adding an element to a hyperloglog twice doesn't change it
+func (client *Client) HLLAdd(ctx context.Context, name string, elements ...string) error {
+	body := map[string][]string{"elements": elements}
+	return client.doJSON(ctx, call{method: http.MethodPost, path: sketchPath(HYPERLOGLOG, name, "add"), idempotent: true}, body, nil)
+}
+
+func (client *Client) HLLCount(ctx context.Context, name string) (uint64, error) {
+	var result struct {
+		Count uint64 `json:"count"`
+	}
+	err := client.query(ctx, HYPERLOGLOG, name, "count", nil, &result)
+	return result.Count, err
+}
+
+func (client *Client) MergeHLL(ctx context.Context, dst string, names []string) error {
+	return client.merge(ctx, HYPERLOGLOG, dst, names)
+}
+
+func (client *Client) CuckooCreate(ctx context.Context, name string, expected int) error {
+	return client.create(ctx, CUCKOO_FILTER, name, map[string]int{"expected": expected})
+}
+
+func (client *Client) CuckooAdd(ctx context.Context, name string, elements ...string) error {
+	return client.add(ctx, CUCKOO_FILTER, name, "add", elements)
+}
+
+func (client *Client) CuckooExists(ctx context.Context, name string, element string) (bool, error) {
+	return client.exists(ctx, CUCKOO_FILTER, name, element)
+}
+
+// CuckooRemove returns false if the element wasn't in the filter
+func (client *Client) CuckooRemove(ctx context.Context, name string, element string) (bool, error) {
+	var result struct {
+		Removed bool `json:"removed"`
+	}
+	body := map[string]string{"element": element}
+	err := client.doJSON(ctx, call{method: http.MethodPost, path: sketchPath(CUCKOO_FILTER, name, "remove")}, body, &result)
+	return result.Removed, err
+}
+
+func (client *Client) CuckooCount(ctx context.Context, name string) (uint64, error) {
+	var result struct {
+		Count uint64 `json:"count"`
+	}
+	err := client.query(ctx, CUCKOO_FILTER, name, "count", nil, &result)
+	return result.Count, err
+}
+
+func (client *Client) TopKCreate(ctx context.Context, name string, k uint32, epsilon float64, delta float64) error {
+	return client.create(ctx, TOP_K, name, map[string]any{"k": k, "epsilon": epsilon, "delta": delta})
+}
+
+func (client *Client) TopKAdd(ctx context.Context, name string, items ...string) error {
+	return client.add(ctx, TOP_K, name, "add", items)
+}
+
+// TopKList returns the k most frequent items, from the most frequent one
+func (client *Client) TopKList(ctx context.Context, name string) ([]TopKItem, error) {
+	var result struct {
+		Items []TopKItem `json:"items"`
+	}
+	err := client.query(ctx, TOP_K, name, "list", nil, &result)
+	return result.Items, err
+}
+
+func (client *Client) TopKQuery(ctx context.Context, name string, item string) (uint32, error) {
+	var result struct {
+		Count uint32 `json:"count"`
+	}
+	err := client.query(ctx, TOP_K, name, "query", url.Values{"element": {item}}, &result)
+	return result.Count, err
+}
+
+// SimHashFingerprint saves the fingerprint of the text under the name and returns it
+func (client *Client) SimHashFingerprint(ctx context.Context, name string, text string) (uint64, error) {
+	var result struct {
+		Fingerprint string `json:"fingerprint"`
+	}
+	err := client.doJSON(ctx, call{method: http.MethodPut, path: sketchPath(SIMHASH, name, ""), idempotent: true},
+		map[string]string{"text": text}, &result)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(result.Fingerprint, 16, 64)
+}
+
+func (client *Client) SimHashDistance(ctx context.Context, name1 string, name2 string) (uint8, error) {
+	var result struct {
+		Distance uint8 `json:"distance"`
+	}
+	err := client.query(ctx, SIMHASH, name1, "distance", url.Values{"other": {name2}}, &result)
+	return result.Distance, err
+}
+
+func (client *Client) DeleteSketch(ctx context.Context, sketch SketchType, name string) error {
+	_, err := client.do(ctx, call{method: http.MethodDelete, path: sketchPath(sketch, name, ""), idempotent: true})
+	return err
+}
@@ -7,23 +7,40 @@ import (
 
 type Config struct {
 	//"wal_size":5,"memtable_size":2,"memtable_structure":"skipList"
-	WalSize              uint32 `json:"wal_size"`
-	MemtableSize         uint32 `json:"memtable_size"`
-	MemtableStructure    string `json:"memtable_structure"`
-	MemTableMaxInstances uint32 `json:"memtable_max_instances"`
-	SkipListMaxHeight    uint32 `json:"skip_list_max_height"`
-	BTreeOrder           uint32 `json:"b_tree_order"`
-	LRUCacheMaxSize      uint32 `json:"lru_cache_max_size"`
-	IndexDegree          uint32 `json:"index_degree"`
-	SummaryDegree        uint32 `json:"summary_degree"`
-	SSTableInSameFile    bool   `json:"ss_table_in_same_file"`
-	CompressionOn        bool   `json:"compression_on"`
-	LSMTreeMaxDepth      uint32 `json:"lsm_tree_max_depth"`
-	NumberOfTokens       uint32 `json:"number_of_tokens"`
-	TokenResetInterval   uint32 `json:"token_reset_interval"`
-	LSMFirstLevelSize    uint32 `json:"LSMFirstLevelSize"`
-	LSMGrowthFactor      uint32 `json:"LSMGrowthFactor"`
-	LSMCompactionType    string `json:"LSMCompactionType"`
+	WalSize              uint32    `json:"wal_size"`
+	MemtableSize         uint32    `json:"memtable_size"`
+	MemtableStructure    string    `json:"memtable_structure"`
+	MemTableMaxInstances uint32    `json:"memtable_max_instances"`
+	SkipListMaxHeight    uint32    `json:"skip_list_max_height"`
+	BTreeOrder           uint32    `json:"b_tree_order"`
+	LRUCacheMaxSize      uint32    `json:"lru_cache_max_size"`
+	IndexDegree          uint32    `json:"index_degree"`
+	SummaryDegree        uint32    `json:"summary_degree"`
+	SSTableInSameFile    bool      `json:"ss_table_in_same_file"`
+	CompressionOn        bool      `json:"compression_on"`
+	LSMTreeMaxDepth      uint32    `json:"lsm_tree_max_depth"`
+	NumberOfTokens       uint32    `json:"number_of_tokens"`
+	TokenResetInterval   uint32    `json:"token_reset_interval"`
+	LSMFirstLevelSize    uint32    `json:"LSMFirstLevelSize"`
+	LSMGrowthFactor      uint32    `json:"LSMGrowthFactor"`
+	LSMCompactionType    string    `json:"LSMCompactionType"`
+	LSMMaxTableRecords   uint32    `json:"LSMMaxTableRecords"`
+	LSMSizeRatio         uint32    `json:"LSMSizeRatio"`
+	LSMMaxSpaceAmp       uint32    `json:"LSMMaxSpaceAmplification"`
+	LSMCompactionRate    uint64    `json:"LSMCompactionRateLimit"`
+	LSMWindowSeconds     uint64    `json:"LSMTimeWindowSeconds"`
+	LSMWindowTTLSeconds  uint64    `json:"LSMTimeWindowTTLSeconds"`
+	BloomFilterType      string    `json:"bloom_filter_type"`
+	BloomBitsPerKey      []float64 `json:"bloom_bits_per_key"`
+	PrefixExtractor      string    `json:"prefix_extractor"`
+	PrefixLength         int       `json:"prefix_length"`
+	PrefixDelimiter      string    `json:"prefix_delimiter"`
+	AccessStatsOn        bool      `json:"access_stats_on"`
+	AccessSampleRate     uint32    `json:"access_stats_sample_rate"`
+	AccessTopK           uint32    `json:"access_stats_top_k"`
+	AccessPrefixLength   int       `json:"access_stats_prefix_length"`
+	PinHotKeys           bool      `json:"pin_hot_keys"`
+	MerkleHash           string    `json:"merkle_hash"`
 }
 
 func LoadConfig(filename string) (*Config, error) {
@@ -49,6 +66,23 @@ func LoadConfig(filename string) (*Config, error) {
 		LSMFirstLevelSize:    10,
 		LSMGrowthFactor:      10,
 		LSMCompactionType:    "sizetiered",
+		LSMMaxTableRecords:   0,
+		LSMSizeRatio:         100,
+		LSMMaxSpaceAmp:       200,
+		LSMCompactionRate:    0,
+		LSMWindowSeconds:     3600,
+		LSMWindowTTLSeconds:  0,
+		BloomFilterType:      "standard",
+		BloomBitsPerKey:      []float64{},
+		PrefixExtractor:      "none",
+		PrefixLength:         0,
+		PrefixDelimiter:      "",
+		AccessStatsOn:        false,
+		AccessSampleRate:     1,
+		AccessTopK:           10,
+		AccessPrefixLength:   3,
+		PinHotKeys:           false,
+		MerkleHash:           "sha256",
 	}
 	err = json.Unmarshal(data, &config)
 	if err != nil {
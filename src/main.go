@@ -1,7 +1,11 @@
 package main
 
-import consoleinterface "github.com/natasakasikovic/Key-Value-engine/src/system/consoleInterface"
+import (
+	"os"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/system/cli"
+)
 
 func main() {
-	consoleinterface.StartEngine()
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
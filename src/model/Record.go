@@ -6,6 +6,7 @@ import (
 	"errors"
 	"hash/crc32"
 	"io"
+	"math"
 	"os"
 
 	"fmt"
@@ -55,7 +56,7 @@ func (r *Record) Serialize(compressionOn bool, compressionMap map[string]uint64)
 	var buffer bytes.Buffer
 	binary.Write(&buffer, binary.BigEndian, r.KeySize)
 	buffer.Write([]byte(r.Key))
-	binary.Write(&buffer, binary.BigEndian, r.Crc)
+	binary.Write(&buffer, binary.BigEndian, r.crcToWrite())
 	binary.Write(&buffer, binary.BigEndian, r.Timestamp)
 	buffer.WriteByte(r.Tombstone)
 
@@ -68,6 +69,46 @@ func (r *Record) Serialize(compressionOn bool, compressionMap map[string]uint64)
 	return buffer.Bytes(), nil
 }
 
+// Values of deleted records aren't written, so their CRC is computed from the key only
+// A record deleted while it was in the memtable still has the CRC of its old value, which couldn't be checked when it is read
+// Sstables written before tombstones were written this way can hold tombstones with the CRC of their old value,
+// the readers reject them as they always did (see checkCrc) and verify reports the tables that hold them
+func (r *Record) crcToWrite() uint32 {
+	if r.Tombstone == 1 {
+		return CRC32([]byte(r.Key))
+	}
+	return r.Crc
+}
+
+// fields larger than this are read in parts, see readField
+const FIELD_READ_SIZE = 64 * 1024
+
+// reads a key or a value of the passed size, which was read from the file as well
+// a damaged size can be far larger than the file, so large fields are read in parts and the buffer grows only as bytes arrive
+func readField(file io.Reader, size uint64) ([]byte, error) {
+	if size <= FIELD_READ_SIZE {
+		field := make([]byte, size)
+		_, err := io.ReadFull(file, field)
+		return field, err
+	}
+	if size > math.MaxInt64 {
+		return nil, errors.New("size of the field is not valid")
+	}
+	var field bytes.Buffer
+	_, err := io.CopyN(&field, file, int64(size))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return field.Bytes(), err
+}
+
+// checkCrc checks the CRC of a read record against its key and value, the value of a tombstone is empty
+// Only this form is accepted: an older tombstone with the CRC of the value it replaced can't be checked,
+// because that value isn't on disk, and accepting any CRC for tombstones would let damaged ones through
+func checkCrc(record *Record) bool {
+	return CRC32(append([]byte(record.Key), record.Value...)) == record.Crc
+}
+
 func CRC32(data []byte) uint32 {
 	return crc32.ChecksumIEEE(data)
 }
@@ -88,8 +129,7 @@ func Deserialize(file *os.File, compressionOn bool, compressionMap map[string]ui
 	}
 	record.KeySize = binary.BigEndian.Uint64(keySizeBuffer)
 
-	var bufferKey []byte = make([]byte, record.KeySize)
-	_, err = io.ReadAtLeast(file, bufferKey, int(record.KeySize))
+	bufferKey, err := readField(file, record.KeySize)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -128,9 +168,7 @@ func Deserialize(file *os.File, compressionOn bool, compressionMap map[string]ui
 		}
 		record.ValueSize = binary.BigEndian.Uint64(valueSizeBuffer)
 
-		var valueBuffer []byte = make([]byte, record.ValueSize)
-		_, err = io.ReadAtLeast(file, valueBuffer, int(record.ValueSize))
-
+		valueBuffer, err := readField(file, record.ValueSize)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -141,8 +179,7 @@ func Deserialize(file *os.File, compressionOn bool, compressionMap map[string]ui
 		record.Value = []byte{}
 	}
 
-	crcChech := append([]byte(record.Key), record.Value...)
-	if CRC32(crcChech) != record.Crc {
+	if !checkCrc(&record) {
 		return nil, read, errors.New("not valid record")
 	}
 
@@ -158,7 +195,7 @@ func SerializeWithCompression(r *Record, compressionMap map[string]uint64) ([]by
 		return nil, err
 	}
 
-	utils.PutUvarint(&buf, uint64(r.Crc))
+	utils.PutUvarint(&buf, uint64(r.crcToWrite()))
 	utils.PutUvarint(&buf, r.Timestamp)
 	buf.WriteByte(r.Tombstone)
 	if r.Tombstone != 1 {
@@ -214,17 +251,15 @@ func deserializeWithCompression(file *os.File, compressionMap map[string]uint64)
 		record.ValueSize = valueSize
 		totalBytesRead += bytesRead
 
-		valueBuf := make([]byte, valueSize)
-		n, err := io.ReadFull(file, valueBuf)
+		valueBuf, err := readField(file, valueSize)
 		if err != nil {
-			return nil, totalBytesRead + uint64(n), err
+			return nil, totalBytesRead + uint64(len(valueBuf)), err
 		}
-		totalBytesRead += uint64(n)
+		totalBytesRead += valueSize
 		record.Value = valueBuf
 	}
 
-	crcChech := append([]byte(record.Key), record.Value...)
-	if CRC32(crcChech) != record.Crc {
+	if !checkCrc(record) {
 		return nil, totalBytesRead, errors.New("not valid record")
 	}
 
@@ -3,6 +3,7 @@ package countminsketch
 import (
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
 	"encoding/binary"
+	"errors"
 	"math"
 )
 
@@ -10,7 +11,7 @@ type CMS struct {
 	table  [][]uint32     // Table of occurrences
 	k      uint32         // Number of hash functions / number of rows
 	m      uint32         // Number of columns
-	hashes []hash.HashWithSeed // Array of used hash functions
+	hashes hash.Family    // Used hash functions
 }
 
 // Allocates memory for the value table and hash list, and returns an empty CMS object
@@ -22,7 +23,7 @@ func CreateCMS(epsilon float64, delta float64) *CMS {
 		table[i] = make([]uint32, m)
 	}
 
-	hashes := hash.CreateHashFunctions(uint(k))
+	hashes := hash.NewFamily(uint(k))
 
 	cms := &CMS{table: table, k: k, m: m, hashes: hashes}
 
@@ -30,18 +31,16 @@ func CreateCMS(epsilon float64, delta float64) *CMS {
 }
 
 func (cms *CMS) Insert(new_element string) {
-	for hash_index, hash := range cms.hashes {
-		index := hash.Hash([]byte(new_element))
-		index %= uint64(cms.m)
+	for hash_index, hash := range cms.hashes.Hashes([]byte(new_element)) {
+		index := hash % uint64(cms.m)
 		cms.table[hash_index][index] += 1
 	}
 }
 
 func (cms *CMS) Search(new_element string) uint32 {
 	var minimum uint32 = math.MaxUint32
-	for hash_index, hash := range cms.hashes {
-		index := hash.Hash([]byte(new_element))
-		index %= uint64(cms.m)
+	for hash_index, hash := range cms.hashes.Hashes([]byte(new_element)) {
+		index := hash % uint64(cms.m)
 
 		counter := cms.table[hash_index][index]
 		if counter < minimum {
@@ -51,31 +50,46 @@ func (cms *CMS) Search(new_element string) uint32 {
 	return minimum
 }
 
+// Adds counts of the other CMS to this one, counters that would overflow stay at the max value
+// Sketches can be merged only if they have the same dimensions and hash functions
+func (cms *CMS) Merge(other *CMS) error {
+	if cms.k != other.k || cms.m != other.m || !hash.SameFamily(cms.hashes, other.hashes) {
+		return errors.New("count min sketches have different parameters and can't be merged")
+	}
+	for i := 0; i < int(cms.k); i++ {
+		for j := 0; j < int(cms.m); j++ {
+			if cms.table[i][j] > math.MaxUint32-other.table[i][j] {
+				cms.table[i][j] = math.MaxUint32
+			} else {
+				cms.table[i][j] += other.table[i][j]
+			}
+		}
+	}
+	return nil
+}
+
 // Returns a byte array representing a serialized CMS object
+// Marker, version of the hash family, K, M, parameters of the hash family, table
 func (cms *CMS) Serialize() []byte {
+	hashes_data := cms.hashes.Serialize()
+
 	var size uint32 = 0
-	size += 4                 // 4 Bytes for K
-	size += 4                 // 4 Bytes for M
-	size += 4 * cms.k         // 4 Bytes K times because of K seeds
-	size += 4 * cms.k * cms.m // 4 Bytes per table field
+	size += 4                       // 4 Bytes for the marker
+	size += 4                       // 4 Bytes for the version of the hash family
+	size += 4                       // 4 Bytes for K
+	size += 4                       // 4 Bytes for M
+	size += uint32(len(hashes_data)) // Parameters of the hash family
+	size += 4 * cms.k * cms.m       // 4 Bytes per table field
 
 	data := make([]byte, size)
 
-	binary.BigEndian.PutUint32(data, cms.k)
-
-	m_slice := data[4:8]
-	binary.BigEndian.PutUint32(m_slice, cms.m)
+	binary.BigEndian.PutUint32(data[0:4], hash.VERSION_MARKER)
+	binary.BigEndian.PutUint32(data[4:8], cms.hashes.Version())
+	binary.BigEndian.PutUint32(data[8:12], cms.k)
+	binary.BigEndian.PutUint32(data[12:16], cms.m)
 
-	hashes_slice_end := 8 + cms.k*4
-	hashes_slice := data[8:hashes_slice_end]
-
-	// Serialization of hash list
-	for i, hash := range cms.hashes {
-		seedBytes := hash.Seed
-		for j := 0; j < len(seedBytes); j++ {
-			hashes_slice[i*len(seedBytes)+j] = seedBytes[j]
-		}
-	}
+	hashes_slice_end := 16 + uint32(len(hashes_data))
+	copy(data[16:hashes_slice_end], hashes_data)
 
 	// Serialization of table data
 	tableData := data[hashes_slice_end:]
@@ -91,27 +105,26 @@ func (cms *CMS) Serialize() []byte {
 }
 
 // Creates and returns a new CMS object from serialized data
+// Data serialized before the hash family version was saved starts with K and uses md5 hashes
+// Returns nil if the hash family is unknown
 func Deserialize(data []byte) *CMS {
 	cms := &CMS{}
 
+	version := hash.MD5_SEEDED
+	if binary.BigEndian.Uint32(data[0:4]) == hash.VERSION_MARKER {
+		version = binary.BigEndian.Uint32(data[4:8])
+		data = data[8:]
+	}
+
 	K := binary.BigEndian.Uint32(data[0:4])
 	M := binary.BigEndian.Uint32(data[4:8])
 
-	hash_list := make([]hash.HashWithSeed, K)
-
-	hashes_slice := data[8:]
-	// i is the number in order of the hash we are currently deserializing
-	for i := 0; i < int(K); i++ {
-		hash_slice := hashes_slice[i*4 : i*4+4]
-		seed_value := binary.BigEndian.Uint32(hash_slice) // Value of seed as a 32bit Integer
-
-		seed := make([]byte, 4) // Value of seed as an array of 4 bytes
-
-		binary.BigEndian.PutUint32(seed, seed_value) //Copy of seed value into the seed array
-		hash_list[i] = hash.HashWithSeed{Seed: seed}
+	hashes, read, err := hash.DeserializeFamily(version, uint(K), data[8:])
+	if err != nil {
+		return nil
 	}
 
-	hashes_slice_end := 8 + K*4
+	hashes_slice_end := 8 + uint32(read)
 
 	// Allocation of table matrix
 	table := make([][]uint32, K)
@@ -132,7 +145,7 @@ func Deserialize(data []byte) *CMS {
 
 	cms.k = K
 	cms.m = M
-	cms.hashes = hash_list
+	cms.hashes = hashes
 	cms.table = table
 
 	return cms
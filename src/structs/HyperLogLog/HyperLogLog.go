@@ -2,9 +2,13 @@ package HyperLogLog
 
 import (
 	"encoding/binary"
+	"errors"
 	"hash/fnv"
 	"math"
 	"math/bits"
+	"sort"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
 )
 
 const (
@@ -12,98 +16,305 @@ const (
 	HLL_MAX_PRECISION = 16
 )
 
-func firstKbits(value, k uint64) uint64 {
-	return value >> (64 - k)
-}
+// precision of the sparse representation, it counts small sets much more precisely than the registers
+const SPARSE_PRECISION = 25
 
-func trailingZeroBits(value uint64) int {
-	return bits.TrailingZeros64(value)
-}
+// version of the HLL++ format, saved after hash.VERSION_MARKER
+// HLLs saved before it start with the precision and are read as legacy HLLs
+const HLL_PLUS_PLUS uint32 = 1
 
+// bits of a sparse entry that hold its value, which is at most 64 - SPARSE_PRECISION + 1
+const SPARSE_VALUE_BITS = 6
+
+// HLL is HyperLogLog++: it starts with a sparse representation, which keeps only the registers that were set,
+// and upgrades to 1<<p dense registers once they take less space
+// Elements are hashed with 64 bit xxhash, so no large range correction is needed,
+// and estimates of small sets are corrected with empirically measured bias
+// Legacy HLLs, read from the old format, are always dense and keep using the old fnv hashing,
+// so elements inserted before and after loading them land in the same registers
 type HLL struct {
-	m   uint64
-	p   uint8
-	reg []uint8
+	m      uint64
+	p      uint8
+	reg    []uint8          // dense registers, nil while the HLL is sparse
+	sparse map[uint32]uint8 // index with SPARSE_PRECISION bits -> number of leading zeros + 1
+	legacy bool
 }
 
 func CreateHLL(p uint8) *HLL {
-	var m uint64 = 1 << p
-	reg := make([]uint8, m)
-	hll := &HLL{m: m, p: p, reg: reg}
-	return hll
+	return &HLL{m: 1 << p, p: p, sparse: make(map[uint32]uint8)}
 }
 
-func (hll *HLL) Estimate() float64 {
-	sum := 0.0
-	for _, val := range hll.reg {
-		sum += math.Pow(math.Pow(2.0, float64(val)), -1)
+func (hll *HLL) IsSparse() bool {
+	return hll.reg == nil
+}
+
+// returns the index of the register and its value for the hash: the first p bits and the number of leading zeros + 1 of the rest
+func registerOf(value uint64, p uint8) (uint32, uint8) {
+	index := uint32(value >> (64 - p))
+	rest := value << p
+	if rest == 0 {
+		return index, 64 - p + 1
 	}
+	return index, uint8(bits.LeadingZeros64(rest)) + 1
+}
+
+func hashStringToUint64(input string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(input))
+	return h.Sum64()
+}
 
-	alpha := 0.7213 / (1.0 + 1.079/float64(hll.m))
-	estimation := alpha * math.Pow(float64(hll.m), 2.0) / sum
-	emptyRegs := hll.emptyCount()
-	if estimation <= 2.5*float64(hll.m) { // do small range correction
-		if emptyRegs > 0 {
-			estimation = float64(hll.m) * math.Log(float64(hll.m)/float64(emptyRegs))
+func (hll *HLL) Insert(str string) {
+	if hll.legacy {
+		value := hashStringToUint64(str)
+		index := value >> (64 - uint64(hll.p))
+		zeroBits := bits.TrailingZeros64(value)
+		if zeroBits+1 > int(hll.reg[index]) {
+			hll.reg[index] = uint8(zeroBits) + 1
 		}
-	} else if estimation > 1/30.0*math.Pow(2.0, 32.0) { // do large range correction
-		estimation = -math.Pow(2.0, 32.0) * math.Log(1.0-estimation/math.Pow(2.0, 32.0))
+		return
 	}
-	return estimation
+
+	value := hash.XXHash64([]byte(str), hash.DEFAULT_SEED)
+	if hll.IsSparse() {
+		index, rho := registerOf(value, SPARSE_PRECISION)
+		if rho > hll.sparse[index] {
+			hll.sparse[index] = rho
+		}
+		// a sparse entry takes 4 bytes when serialized and a register takes 1
+		if uint64(len(hll.sparse))*4 > hll.m {
+			hll.toDense()
+		}
+		return
+	}
+
+	index, rho := registerOf(value, hll.p)
+	if rho > hll.reg[index] {
+		hll.reg[index] = rho
+	}
+}
+
+// returns the dense registers of the HLL, made from the sparse entries if it is sparse
+func (hll *HLL) registers() []uint8 {
+	if !hll.IsSparse() {
+		return hll.reg
+	}
+	reg := make([]uint8, hll.m)
+	for sparseIndex, sparseRho := range hll.sparse {
+		index, rho := sparseToDense(sparseIndex, sparseRho, hll.p)
+		if rho > reg[index] {
+			reg[index] = rho
+		}
+	}
+	return reg
 }
 
-func (hll *HLL) emptyCount() int {
-	sum := 0
+// returns the register the sparse entry belongs to, and the value it would have if the element was inserted into the registers
+// the first p bits of the sparse index are the register, if the other bits aren't all zero, they hold the first set bit
+func sparseToDense(sparseIndex uint32, sparseRho uint8, p uint8) (uint32, uint8) {
+	var extraBits uint8 = SPARSE_PRECISION - p
+	index := sparseIndex >> extraBits
+	extra := sparseIndex & (1<<extraBits - 1)
+	if extra == 0 {
+		return index, extraBits + sparseRho
+	}
+	return index, uint8(bits.LeadingZeros32(extra)) - (32 - extraBits) + 1
+}
+
+func (hll *HLL) toDense() {
+	hll.reg = hll.registers()
+	hll.sparse = nil
+}
+
+func (hll *HLL) Estimate() float64 {
+	if hll.IsSparse() {
+		// linear counting with the registers of the sparse precision
+		var m float64 = float64(uint64(1) << SPARSE_PRECISION)
+		return m * math.Log(m/(m-float64(len(hll.sparse))))
+	}
+
+	m := float64(hll.m)
+	sum := 0.0
+	emptyRegs := 0
 	for _, val := range hll.reg {
+		sum += math.Ldexp(1, -int(val))
 		if val == 0 {
-			sum++
+			emptyRegs++
+		}
+	}
+	estimation := alpha(hll.m) * m * m / sum
+	if estimation <= 5*m {
+		estimation -= estimateBias(hll.p, estimation)
+	}
+	if emptyRegs > 0 {
+		linearCounting := m * math.Log(m/float64(emptyRegs))
+		if linearCounting <= threshold[hll.p-HLL_MIN_PRECISION] {
+			return linearCounting
 		}
 	}
-	return sum
+	return estimation
 }
 
-func hashStringToUint64(input string) uint64 {
-	h := fnv.New64a()
-	h.Write([]byte(input))
-	return h.Sum64()
+func alpha(m uint64) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1.0 + 1.079/float64(m))
+	}
 }
 
-func (hll *HLL) Insert(str string) {
-	value := hashStringToUint64(str)
-	zero_bits := trailingZeroBits(value)
-	bucket_index := firstKbits(value, uint64(hll.p))
+// returns the bias of the raw estimate, as the mean bias of the 6 nearest measured raw estimates
+func estimateBias(p uint8, estimation float64) float64 {
+	estimates := rawEstimateData[p-HLL_MIN_PRECISION]
+	biases := biasData[p-HLL_MIN_PRECISION]
 
-	existing_value := hll.reg[bucket_index]
+	// estimates are sorted, the nearest ones are around the first estimate that isn't smaller
+	var neighbours int = 6
+	right := sort.SearchFloat64s(estimates, estimation)
+	left := right - 1
+	var sum float64 = 0
+	for n := 0; n < neighbours && (left >= 0 || right < len(estimates)); n++ {
+		if right >= len(estimates) || (left >= 0 && estimation-estimates[left] < estimates[right]-estimation) {
+			sum += biases[left]
+			left--
+		} else {
+			sum += biases[right]
+			right++
+		}
+	}
+	return sum / float64(neighbours)
+}
+
+// Merges the other HLL into this one, so it estimates the number of distinct elements inserted into either of them
+// HLLs can be merged only if they have the same precision and hashing
+func (hll *HLL) Merge(other *HLL) error {
+	if hll.p != other.p || hll.m != other.m || hll.legacy != other.legacy {
+		return errors.New("hyperloglogs have different precisions or hashing and can't be merged")
+	}
+	if hll.IsSparse() && other.IsSparse() {
+		for index, rho := range other.sparse {
+			if rho > hll.sparse[index] {
+				hll.sparse[index] = rho
+			}
+		}
+		if uint64(len(hll.sparse))*4 > hll.m {
+			hll.toDense()
+		}
+		return nil
+	}
 
-	if (zero_bits + 1) > int(existing_value) {
-		hll.reg[bucket_index] = uint8(zero_bits) + 1
+	hll.toDense()
+	otherReg := other.registers()
+	for i := 0; i < int(hll.m); i++ {
+		if otherReg[i] > hll.reg[i] {
+			hll.reg[i] = otherReg[i]
+		}
 	}
+	return nil
 }
+
+// Legacy HLLs are saved in the old format: p, m, 4 bytes per register
+// Others are saved as marker, version, p, 1 if sparse or 0 if dense, then
+// the number of sparse entries and entries sorted by index, each index << SPARSE_VALUE_BITS | value,
+// or 1 byte per dense register
 func (hll HLL) Serialize() []byte {
-	var size int = 4 + 8 + 4*len(hll.reg)
+	if hll.legacy {
+		var size int = 4 + 8 + 4*len(hll.reg)
 
-	bytes := make([]byte, size)
-	binary.BigEndian.PutUint32(bytes[0:4], uint32(hll.p))
-	binary.BigEndian.PutUint64(bytes[4:12], hll.m)
+		bytes := make([]byte, size)
+		binary.BigEndian.PutUint32(bytes[0:4], uint32(hll.p))
+		binary.BigEndian.PutUint64(bytes[4:12], hll.m)
 
-	for i := 0; i < int(hll.m); i++ {
-		binary.BigEndian.PutUint32(bytes[12+4*i:16+4*i], uint32(hll.reg[i]))
+		for i := 0; i < int(hll.m); i++ {
+			binary.BigEndian.PutUint32(bytes[12+4*i:16+4*i], uint32(hll.reg[i]))
+		}
+		return bytes
+	}
+
+	bytes := make([]byte, 10)
+	binary.BigEndian.PutUint32(bytes[0:4], hash.VERSION_MARKER)
+	binary.BigEndian.PutUint32(bytes[4:8], HLL_PLUS_PLUS)
+	bytes[8] = hll.p
+	if !hll.IsSparse() {
+		bytes[9] = 0
+		return append(bytes, hll.reg...)
+	}
+
+	bytes[9] = 1
+	entries := make([]uint32, 0, len(hll.sparse))
+	for index, rho := range hll.sparse {
+		entries = append(entries, index<<SPARSE_VALUE_BITS|uint32(rho))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+	bytes = binary.BigEndian.AppendUint32(bytes, uint32(len(entries)))
+	for _, entry := range entries {
+		bytes = binary.BigEndian.AppendUint32(bytes, entry)
 	}
 	return bytes
 }
 
+// Reads both formats, returns nil if the bytes aren't a valid HLL
 func Deserialize(bytes []byte) *HLL {
+	if len(bytes) < 12 {
+		return nil
+	}
+	if binary.BigEndian.Uint32(bytes[0:4]) != hash.VERSION_MARKER {
+		return deserializeLegacy(bytes)
+	}
+
+	if binary.BigEndian.Uint32(bytes[4:8]) != HLL_PLUS_PLUS {
+		return nil
+	}
+	p := bytes[8]
+	if p < HLL_MIN_PRECISION || p > HLL_MAX_PRECISION {
+		return nil
+	}
+	hll := &HLL{m: 1 << p, p: p}
+	data := bytes[10:]
+	if bytes[9] == 0 {
+		if uint64(len(data)) != hll.m {
+			return nil
+		}
+		hll.reg = append([]uint8{}, data...)
+		return hll
+	}
+
+	if len(data) < 4 {
+		return nil
+	}
+	count := binary.BigEndian.Uint32(data[0:4])
+	data = data[4:]
+	if uint64(len(data)) != uint64(count)*4 {
+		return nil
+	}
+	hll.sparse = make(map[uint32]uint8, count)
+	for i := 0; i < int(count); i++ {
+		entry := binary.BigEndian.Uint32(data[4*i : 4*i+4])
+		hll.sparse[entry>>SPARSE_VALUE_BITS] = uint8(entry & (1<<SPARSE_VALUE_BITS - 1))
+	}
+	return hll
+}
 
+func deserializeLegacy(bytes []byte) *HLL {
 	p := uint32(binary.BigEndian.Uint32(bytes[0:4]))
 	m := uint64(binary.BigEndian.Uint64(bytes[4:12]))
+	if p < HLL_MIN_PRECISION || p > HLL_MAX_PRECISION || m != 1<<p || uint64(len(bytes)) < 12+4*m {
+		return nil
+	}
 
 	reg := make([]uint8, m)
 	for i := 0; i < int(m); i++ {
 		reg[i] = uint8(uint32(binary.BigEndian.Uint32(bytes[12+4*i : 16+4*i])))
 	}
 	return &HLL{
-		p:   uint8(p),
-		m:   m,
-		reg: reg,
+		p:      uint8(p),
+		m:      m,
+		reg:    reg,
+		legacy: true,
 	}
 }
@@ -4,7 +4,6 @@ import (
 	"container/list"
 	"fmt"
 	"github.com/natasakasikovic/Key-Value-engine/src/model"
-	"github.com/natasakasikovic/Key-Value-engine/src/structs/memtable"
 )
 
 type Data struct {
@@ -21,24 +20,48 @@ type LRUCache struct {
 	list       *list.List
 	limit      uint32
 	numOfElems uint32
+	pinned     map[string]bool //Pinned keys are never evicted, but can still be removed
 }
 
 func NewLRUCache(limit uint32) *LRUCache {
-	return &LRUCache{hashMap: make(map[string]*list.Element), list: list.New(), limit: limit, numOfElems: 0}
+	return &LRUCache{hashMap: make(map[string]*list.Element), list: list.New(), limit: limit, numOfElems: 0, pinned: make(map[string]bool)}
 }
 
 func (lru *LRUCache) Add(key string, value []byte) {
+	//A key that is already cached is just updated
+	if elem, exists := lru.hashMap[key]; exists {
+		elem.Value.(*Data).value = value
+		lru.list.MoveToFront(elem)
+		return
+	}
+
 	data := NewData(value, key)
 	lru.hashMap[key] = lru.list.PushFront(data)
 	lru.numOfElems++
 	if lru.numOfElems > lru.limit {
-		//Delete from hashmap
-		delete(lru.hashMap, lru.list.Back().Value.(*Data).key)
-		//Delete from list
-		lru.list.Remove(lru.list.Back())
+		//The least recently used key that isn't pinned is evicted
+		//If all keys are pinned, the cache holds more than limit keys until some are unpinned
+		for elem := lru.list.Back(); elem != nil; elem = elem.Prev() {
+			if !lru.pinned[elem.Value.(*Data).key] {
+				lru.Remove(elem.Value.(*Data).key)
+				break
+			}
+		}
 	}
 }
 
+// SetPinned replaces the pinned keys with the passed ones
+func (lru *LRUCache) SetPinned(keys []string) {
+	lru.pinned = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		lru.pinned[key] = true
+	}
+}
+
+func (lru *LRUCache) IsPinned(key string) bool {
+	return lru.pinned[key]
+}
+
 func (lru *LRUCache) Get(key string) []byte {
 	elem, exists := lru.hashMap[key]
 	if !exists {
@@ -49,12 +72,26 @@ func (lru *LRUCache) Get(key string) []byte {
 	return elem.Value.(*Data).value
 }
 
+func (lru *LRUCache) Remove(key string) {
+	elem, exists := lru.hashMap[key]
+	if !exists {
+		return
+	}
+	delete(lru.hashMap, key)
+	lru.list.Remove(elem)
+	lru.numOfElems--
+}
+
+// updates cached values of the flushed records, deleted records are removed from the cache
 func (lru *LRUCache) UpdateKeys(records []*model.Record) {
 	for i := 0; i < len(records); i++ {
 		elem, exists := lru.hashMap[records[i].Key]
 		if exists {
-			record, _ := memtable.Get(records[i].Key)
-			elem.Value.(*Data).value = record.Value
+			if records[i].Tombstone == 1 {
+				lru.Remove(records[i].Key)
+			} else {
+				elem.Value.(*Data).value = records[i].Value
+			}
 		}
 	}
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/natasakasikovic/Key-Value-engine/src/model"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/iterators"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/sstable"
 	"github.com/natasakasikovic/Key-Value-engine/src/utils"
 )
@@ -15,7 +16,7 @@ import (
 type LSMTree struct {
 	sstableArrays  [][]*sstable.SSTable //Array of arrays of SSTable pointers
 	maxDepth       uint32
-	compactionType string
+	strategy       CompactionStrategy
 	firstLevelSize uint32
 	growthFactor   uint32
 
@@ -24,6 +25,15 @@ type LSMTree struct {
 	sstableInSameFile    bool
 	sstableCompressionOn bool
 	compressionMap       map[string]uint64
+	maxTableRecords      uint32 //Max number of records in an sstable made by compaction, 0 means no limit
+
+	bloomBlocked    bool                    //If true, sstables get blocked bloom filters
+	bloomBitsPerKey []float64               //Bits per key of bloom filters for each level, the last value is used for all deeper levels
+	prefixExtractor sstable.PrefixExtractor //If on, sstables get a bloom filter of key prefixes, used by prefix scans
+	merkleHash      merkletree.HashType     //Hash of merkle trees of new sstables
+
+	compactionPaused    bool   //If true, adding sstables doesn't trigger compaction
+	compactionRateLimit uint64 //Max number of bytes per second written by compaction, 0 means no limit
 }
 
 func isSSTableInSingleFile(tableName string) (bool, error) {
@@ -75,6 +85,11 @@ func loadAllSStables() ([]*sstable.SSTable, error) {
 		if err != nil {
 			return make([]*sstable.SSTable, 0), err
 		}
+		err = table.LoadMaxTimestamp(!isSingleFile, path)
+		if err != nil {
+			closeSSTable(table)
+			return make([]*sstable.SSTable, 0), err
+		}
 
 		table.Name = sstableNames[i]
 		table.Data.Close()
@@ -86,12 +101,12 @@ func loadAllSStables() ([]*sstable.SSTable, error) {
 	return tables, nil
 }
 
-func makeEmptyLSMTree(maxDepth uint32, compactionType string, firstLevelSize uint32, growthFactor uint32,
+func makeEmptyLSMTree(maxDepth uint32, strategy CompactionStrategy, firstLevelSize uint32, growthFactor uint32,
 	sstableIndexDegree uint32, sstableSummaryDegree uint32, sstableInSameFile bool, sstableCompressionOn bool,
-	compressionMap map[string]uint64) *LSMTree {
+	compressionMap map[string]uint64, maxTableRecords uint32, bloomBlocked bool, bloomBitsPerKey []float64, prefixExtractor sstable.PrefixExtractor) *LSMTree {
 	var tree *LSMTree = &LSMTree{
 		maxDepth:             maxDepth,
-		compactionType:       compactionType,
+		strategy:             strategy,
 		firstLevelSize:       firstLevelSize,
 		growthFactor:         growthFactor,
 		sstableIndexDegree:   sstableIndexDegree,
@@ -99,6 +114,10 @@ func makeEmptyLSMTree(maxDepth uint32, compactionType string, firstLevelSize uin
 		sstableInSameFile:    sstableInSameFile,
 		sstableCompressionOn: sstableCompressionOn,
 		compressionMap:       compressionMap,
+		maxTableRecords:      maxTableRecords,
+		bloomBlocked:         bloomBlocked,
+		bloomBitsPerKey:      bloomBitsPerKey,
+		prefixExtractor:      prefixExtractor,
 	}
 
 	tree.sstableArrays = make([][]*sstable.SSTable, maxDepth)
@@ -110,19 +129,23 @@ func makeEmptyLSMTree(maxDepth uint32, compactionType string, firstLevelSize uin
 }
 
 // Creates a new LSM Tree and loads existing sstables into it
-func NewLSMTree(maxDepth uint32, compactionType string, firstLevelSize uint32, growthFactor uint32,
+func NewLSMTree(maxDepth uint32, strategy CompactionStrategy, firstLevelSize uint32, growthFactor uint32,
 	sstableIndexDegree uint32, sstableSummaryDegree uint32, sstableInSameFile bool, sstableCompressionOn bool,
-	compressionMap map[string]uint64) (*LSMTree, error) {
+	compressionMap map[string]uint64, maxTableRecords uint32, bloomBlocked bool, bloomBitsPerKey []float64, prefixExtractor sstable.PrefixExtractor) (*LSMTree, error) {
 	var tree *LSMTree = makeEmptyLSMTree(
 		maxDepth,
-		compactionType,
+		strategy,
 		firstLevelSize,
 		growthFactor,
 		sstableIndexDegree,
 		sstableSummaryDegree,
 		sstableInSameFile,
 		sstableCompressionOn,
-		compressionMap)
+		compressionMap,
+		maxTableRecords,
+		bloomBlocked,
+		bloomBitsPerKey,
+		prefixExtractor)
 
 	tables, err := loadAllSStables()
 	if err != nil {
@@ -137,20 +160,49 @@ func NewLSMTree(maxDepth uint32, compactionType string, firstLevelSize uint32, g
 
 const LSM_PATH string = "../data/LSMTree.json"
 
+// Loads only names of sstables from LSM_PATH, sstables aren't opened, so it works even if some of them are damaged
+// The returned tree is meant only for checking and quarantining sstables, it can't be used for reads or compactions
+func LoadTableNames(maxDepth uint32) (*LSMTree, error) {
+	jsonData, err := os.ReadFile(LSM_PATH)
+	if err != nil {
+		return nil, err
+	}
+	var sstableNames [][]string
+	err = json.Unmarshal(jsonData, &sstableNames)
+	if err != nil {
+		return nil, err
+	}
+	if len(sstableNames) > int(maxDepth) {
+		return nil, errors.New("LSM tree has more levels than the config allows")
+	}
+
+	var tree *LSMTree = makeEmptyLSMTree(maxDepth, nil, 0, 0, 0, 0, false, false, nil, 0, false, nil, sstable.PrefixExtractor{})
+	for i, names := range sstableNames {
+		for _, name := range names {
+			tree.sstableArrays[i] = append(tree.sstableArrays[i], &sstable.SSTable{Name: name})
+		}
+	}
+	return tree, nil
+}
+
 // Returns a pointer to the lsm tree if loaded successfuly
 // Otherwise, returns nil
-func LoadLSMTreeFromFile(maxDepth uint32, compactionType string, firstLevelSize uint32, growthFactor uint32,
+func LoadLSMTreeFromFile(maxDepth uint32, strategy CompactionStrategy, firstLevelSize uint32, growthFactor uint32,
 	sstableIndexDegree uint32, sstableSummaryDegree uint32, sstableInSameFile bool, sstableCompressionOn bool,
-	compressionMap map[string]uint64) (*LSMTree, error) {
+	compressionMap map[string]uint64, maxTableRecords uint32, bloomBlocked bool, bloomBitsPerKey []float64, prefixExtractor sstable.PrefixExtractor) (*LSMTree, error) {
 	lsm := makeEmptyLSMTree(maxDepth,
-		compactionType,
+		strategy,
 		firstLevelSize,
 		growthFactor,
 		sstableIndexDegree,
 		sstableSummaryDegree,
 		sstableInSameFile,
 		sstableCompressionOn,
-		compressionMap)
+		compressionMap,
+		maxTableRecords,
+		bloomBlocked,
+		bloomBitsPerKey,
+		prefixExtractor)
 
 	jsonData, err := os.ReadFile(LSM_PATH)
 
@@ -202,6 +254,11 @@ func LoadLSMTreeFromFile(maxDepth uint32, compactionType string, firstLevelSize
 			if err != nil {
 				return nil, err
 			}
+			err = table.LoadMaxTimestamp(len(content) != 1, path)
+			if err != nil {
+				closeSSTable(table)
+				return nil, err
+			}
 
 			table.Name = dirName
 			closeSSTable(table)
@@ -215,9 +272,9 @@ func LoadLSMTreeFromFile(maxDepth uint32, compactionType string, firstLevelSize
 		return nil, err
 	}
 
-	//Check if all levels are sorted if the compaction type is leveled
-	if lsm.compactionType == "leveled" {
-		for i := 0; i < int(lsm.maxDepth); i++ {
+	//Check if all levels that the compaction strategy keeps sorted are sorted
+	for i := 0; i < int(lsm.maxDepth); i++ {
+		if lsm.strategy.IsLevelSorted(lsm, uint32(i)) {
 			for j := 0; j < len(lsm.sstableArrays[i])-1; j++ {
 				//If the left table contains a key larger than in the right table, the lsm is not leveled
 				if lsm.sstableArrays[i][j].MaxKey >= lsm.sstableArrays[i][j+1].MinKey {
@@ -265,6 +322,7 @@ func (tree *LSMTree) reopenSSTables() error {
 		for j := 0; j < len(tree.sstableArrays[i]); j++ {
 			var table *sstable.SSTable = tree.sstableArrays[i][j]
 			var name string = table.Name
+			var maxTimestamp uint64 = table.MaxTimestamp
 			var path string = fmt.Sprintf("%s/%s", sstable.PATH, table.Name)
 			closeSSTable(table)
 
@@ -280,6 +338,7 @@ func (tree *LSMTree) reopenSSTables() error {
 				tree.sstableArrays[i][j], err = sstable.LoadSStableSingle(path)
 			}
 			tree.sstableArrays[i][j].Name = name
+			tree.sstableArrays[i][j].MaxTimestamp = maxTimestamp
 			closeSSTable(tree.sstableArrays[i][j])
 
 			if err != nil {
@@ -290,9 +349,15 @@ func (tree *LSMTree) reopenSSTables() error {
 	return err
 }
 
-// Merges the passed sstables into a new sstable
+// Merges the passed sstables into new sstables
+// Records are streamed from the iterators into the sstable writer, so the merge doesn't hold the tables in memory
+// If maxTableRecords is not 0, a new sstable is started whenever the current one reaches that many records
+// Deleted records are written to the new sstables, unless canDropTombstone returns true for their key
+// Writing is slowed down by the limiter, if it isn't nil
+// Bloom filters of the new sstables are made as the filter config says
 func mergeSSTables(sstableArray []*sstable.SSTable, sstableIndexDegree uint32, sstableSummaryDegree uint32,
-	sstableInSameFile bool, sstableCompressionOn bool, compressionMap map[string]uint64) (*sstable.SSTable, error) {
+	sstableInSameFile bool, sstableCompressionOn bool, compressionMap map[string]uint64, maxTableRecords uint32,
+	canDropTombstone func(string) bool, limiter *rateLimiter, filter sstable.FilterConfig) ([]*sstable.SSTable, error) {
 	var sstableCount int = len(sstableArray)
 	var fileIterators []iterators.Iterator = make([]iterators.Iterator, sstableCount)
 
@@ -306,19 +371,29 @@ func mergeSSTables(sstableArray []*sstable.SSTable, sstableIndexDegree uint32, s
 	}
 
 	//Try to create the iterator group
-	iterGroup, err := iterators.NewIteratorGroup(fileIterators)
+	iterGroup, err := iterators.NewIteratorGroup(fileIterators, true)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Error creating iterator group: %s", err.Error()))
 	}
 	//Stop the iterators when the function ends
 	defer iterGroup.Stop()
 
-	//Array containing records going into the new sstable
-	var newRecords []*model.Record = make([]*model.Record, 0)
+	//The new sstables
+	var newSSTables []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	var writer *sstable.Writer = sstable.NewWriter(sstableInSameFile, sstableCompressionOn, int(sstableIndexDegree), int(sstableSummaryDegree), compressionMap, filter)
+
+	//Deletes the sstables written so far if the merge fails
+	var abort func() = func() {
+		writer.Abort()
+		for i := len(newSSTables) - 1; i >= 0; i-- {
+			newSSTables[i].Delete()
+		}
+	}
 
 	for {
 		record_p, err := iterGroup.Next()
 		if err != nil {
+			abort()
 			return nil, err
 		}
 
@@ -327,18 +402,89 @@ func mergeSSTables(sstableArray []*sstable.SSTable, sstableIndexDegree uint32, s
 			break
 		}
 
+		//The older versions of the record were already left out by the iterator group,
+		//so the tombstone itself can be left out if no older sstable could contain the key
+		if record_p.Tombstone == 1 && canDropTombstone(record_p.Key) {
+			continue
+		}
+
 		//If the record isn't nil, add it to the sstable
-		newRecords = append(newRecords, record_p)
-	}
+		var sizeBefore uint64 = writer.DataSize()
+		err = writer.Add(record_p)
+		if err != nil {
+			abort()
+			return nil, err
+		}
+		limiter.wait(writer.DataSize() - sizeBefore)
 
-	//The new sstable
-	newSSTable, err := sstable.CreateSStable(newRecords, sstableInSameFile, sstableCompressionOn, int(sstableIndexDegree), int(sstableSummaryDegree), compressionMap)
+		//If the sstable is full, finish it and start the next one
+		if maxTableRecords != 0 && writer.Count() >= int(maxTableRecords) {
+			newSSTable, err := writer.Finish()
+			if err != nil {
+				abort()
+				return nil, err
+			}
+			newSSTables = append(newSSTables, newSSTable)
+			writer = sstable.NewWriter(sstableInSameFile, sstableCompressionOn, int(sstableIndexDegree), int(sstableSummaryDegree), compressionMap, filter)
+		}
+	}
 
+	//Finish the last sstable, nil is returned if it has no records
+	newSSTable, err := writer.Finish()
 	if err != nil {
+		abort()
 		return nil, err
 	}
+	if newSSTable != nil {
+		newSSTables = append(newSSTables, newSSTable)
+	}
+
+	return newSSTables, nil
+}
+
+// Returns a function which tells if a tombstone can be dropped when the passed sstables are merged
+// Sstables below the level the merged sstables come from (fromLevel is the first level below it), that are not being merged,
+// hold older data than the merged ones, so a tombstone is kept as long as any of them could contain the deleted key -
+// - otherwise the old value would come back
+// When merging into the last non-empty level, all tombstones are dropped
+func (tree *LSMTree) tombstoneFilter(fromLevel uint32, merging []*sstable.SSTable) func(string) bool {
+	var isMerging map[*sstable.SSTable]bool = make(map[*sstable.SSTable]bool)
+	for i := 0; i < len(merging); i++ {
+		isMerging[merging[i]] = true
+	}
+
+	var olderTables []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	for i := fromLevel; i < tree.maxDepth; i++ {
+		for j := 0; j < len(tree.sstableArrays[i]); j++ {
+			if !isMerging[tree.sstableArrays[i][j]] {
+				olderTables = append(olderTables, tree.sstableArrays[i][j])
+			}
+		}
+	}
+
+	return func(key string) bool {
+		for i := 0; i < len(olderTables); i++ {
+			if key >= olderTables[i].MinKey && key <= olderTables[i].MaxKey {
+				return false
+			}
+		}
+		return true
+	}
+}
 
-	return newSSTable, nil
+// Returns how bloom filters of sstables on the passed level are made
+// Upper levels can be given more bits per key, since they are read more often (Monkey)
+// If bits per key aren't set, filters are sized for a fixed false positive rate
+func (tree *LSMTree) FilterConfig(levelIndex uint32) sstable.FilterConfig {
+	var config sstable.FilterConfig = sstable.FilterConfig{Blocked: tree.bloomBlocked, Prefix: tree.prefixExtractor, MerkleHash: tree.merkleHash}
+	if len(tree.bloomBitsPerKey) > 0 {
+		if int(levelIndex) < len(tree.bloomBitsPerKey) {
+			config.BitsPerKey = tree.bloomBitsPerKey[levelIndex]
+		} else {
+			config.BitsPerKey = tree.bloomBitsPerKey[len(tree.bloomBitsPerKey)-1]
+		}
+	}
+	return config
 }
 
 func closeSSTable(table *sstable.SSTable) {
@@ -357,10 +503,24 @@ func (tree *LSMTree) closeAllTables() {
 
 // Deletes the passed sstable from the disk and updates the name attribute of other sstables
 func (tree *LSMTree) deleteTable(table *sstable.SSTable) error {
+	err := tree.renameAfter(table.Name)
+	if err != nil {
+		return err
+	}
+
+	tree.closeAllTables()
+	err = table.Delete()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Updates the name attribute of sstables whose folders are renamed once the folder with the passed name is removed
+func (tree *LSMTree) renameAfter(deletedName string) error {
 	//Key - old name, value - new name
 	//Will be used to update the name attribute of other sstables
 	var nameMapping map[string]string = make(map[string]string)
-	var deletedName = table.Name
 
 	dirContent, err := utils.GetDirContent(sstable.PATH) // dirContent - names of all sstables dirs
 	if err != nil {
@@ -387,29 +547,80 @@ func (tree *LSMTree) deleteTable(table *sstable.SSTable) error {
 			}
 		}
 	}
+	return nil
+}
 
-	tree.closeAllTables()
-	err = table.Delete()
-	if err != nil {
-		return err
+// Removes the sstable with the passed name from the tree and moves its folder to quarantine, returns where it was moved
+// It also works for folders that the tree doesn't know about, they are only moved
+func (tree *LSMTree) QuarantineTable(name string) (string, error) {
+	for i := 0; i < int(tree.maxDepth); i++ {
+		var remaining []*sstable.SSTable = make([]*sstable.SSTable, 0)
+		for _, table := range tree.sstableArrays[i] {
+			if table.Name != name {
+				remaining = append(remaining, table)
+			}
+		}
+		tree.sstableArrays[i] = remaining
 	}
-	return nil
+
+	var destination string = ""
+	_, err := os.Stat(fmt.Sprintf("%s/%s", sstable.PATH, name))
+	if err == nil {
+		err = tree.renameAfter(name)
+		if err != nil {
+			return "", err
+		}
+		tree.closeAllTables()
+		destination, err = sstable.Quarantine(name)
+		if err != nil {
+			return "", err
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	return destination, tree.SaveToFile()
+}
+
+// Removes the passed sstables from the level, without deleting them from the disk
+func (tree *LSMTree) removeTables(levelIndex uint32, tables map[*sstable.SSTable]bool) {
+	var remaining []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	for i := 0; i < len(tree.sstableArrays[levelIndex]); i++ {
+		if !tables[tree.sstableArrays[levelIndex][i]] {
+			remaining = append(remaining, tree.sstableArrays[levelIndex][i])
+		}
+	}
+	tree.sstableArrays[levelIndex] = remaining
 }
 
 func (tree *LSMTree) leveledCompaction(levelIndex uint32) error {
 	//The first table on the passed level will be merged with the appropriate tables of the next level
-	var upperTable *sstable.SSTable = tree.sstableArrays[levelIndex][0]
-	var minKey string = upperTable.MinKey
-	var maxKey string = upperTable.MaxKey
+	return tree.mergeIntoLevel(tree.sstableArrays[levelIndex][:1], levelIndex, levelIndex+1)
+}
+
+// Merges the passed tables from the upper level with the tables of the lower level whose keys overlap with them
+// The lower level stays sorted by key, with no overlapping tables, as leveled compaction needs
+// The upper tables don't need to be sorted or to have non-overlapping keys
+func (tree *LSMTree) mergeIntoLevel(upperTables []*sstable.SSTable, upperLevel uint32, lowerLevel uint32) error {
+	upperTables = append([]*sstable.SSTable{}, upperTables...)
+	var minKey string = upperTables[0].MinKey
+	var maxKey string = upperTables[0].MaxKey
+	for i := 1; i < len(upperTables); i++ {
+		if upperTables[i].MinKey < minKey {
+			minKey = upperTables[i].MinKey
+		}
+		if upperTables[i].MaxKey > maxKey {
+			maxKey = upperTables[i].MaxKey
+		}
+	}
 
 	//Index of the first table from the lower level that needs to be merged
 	//Index of the last table from the lower level that needs to be merged
-	var leftIndex int = len(tree.sstableArrays[levelIndex+1])
+	var leftIndex int = len(tree.sstableArrays[lowerLevel])
 	var rightIndex int = -1
 
 	//Find first table that needs to be merged
-	for i := 0; i < len(tree.sstableArrays[levelIndex+1]); i++ {
-		var tableMaxKey string = tree.sstableArrays[levelIndex+1][i].MaxKey
+	for i := 0; i < len(tree.sstableArrays[lowerLevel]); i++ {
+		var tableMaxKey string = tree.sstableArrays[lowerLevel][i].MaxKey
 		if minKey <= tableMaxKey {
 			leftIndex = i
 			break
@@ -417,93 +628,105 @@ func (tree *LSMTree) leveledCompaction(levelIndex uint32) error {
 	}
 
 	//Find last table that needs to be merged
-	for i := 0; i < len(tree.sstableArrays[levelIndex+1]); i++ {
-		var tableMinKey string = tree.sstableArrays[levelIndex+1][i].MinKey
+	for i := 0; i < len(tree.sstableArrays[lowerLevel]); i++ {
+		var tableMinKey string = tree.sstableArrays[lowerLevel][i].MinKey
 		if maxKey >= tableMinKey {
 			rightIndex = i
 		}
 	}
 
-	if leftIndex == len(tree.sstableArrays[levelIndex+1]) || rightIndex == -1 || (rightIndex < leftIndex) {
-		//If there is no overlap, just move the upper sstable to the lower level
+	var overlaps bool = !(leftIndex == len(tree.sstableArrays[lowerLevel]) || rightIndex == -1 || (rightIndex < leftIndex))
 
-		//Find the index of the first sstable with keys larger than the upper sstable
-		var firstLargerIndex int = -1
-		for i := 0; i < len(tree.sstableArrays[levelIndex+1]); i++ {
-			var tableMinKey string = tree.sstableArrays[levelIndex+1][i].MinKey
+	if !overlaps {
+		//Find the index of the first sstable with keys larger than the upper sstables
+		//The upper sstables will be inserted there, and no lower sstable is merged
+		leftIndex = len(tree.sstableArrays[lowerLevel])
+		for i := 0; i < len(tree.sstableArrays[lowerLevel]); i++ {
+			var tableMinKey string = tree.sstableArrays[lowerLevel][i].MinKey
 			if maxKey < tableMinKey {
-				firstLargerIndex = i
+				leftIndex = i
 				break
 			}
 		}
+		rightIndex = leftIndex - 1
+	}
 
-		if firstLargerIndex == -1 {
-			//If all sstables have smaller keys than the upper table, append the upper table
-			tree.sstableArrays[levelIndex+1] = append(tree.sstableArrays[levelIndex+1], upperTable)
-		} else {
-			//Otherwise, move the larger sstables to the right, and insert the upper table into the level
-			//Expand the array
-			tree.sstableArrays[levelIndex+1] = append(tree.sstableArrays[levelIndex+1], nil)
-			//Shift elements to the right
-			copy(tree.sstableArrays[levelIndex+1][firstLargerIndex+1:], tree.sstableArrays[levelIndex+1][firstLargerIndex:])
-			tree.sstableArrays[levelIndex+1][firstLargerIndex] = upperTable
-		}
+	if !overlaps && len(upperTables) == 1 {
+		//If there is no overlap, just move the upper sstable to the lower level
+		//Expand the array
+		tree.sstableArrays[lowerLevel] = append(tree.sstableArrays[lowerLevel], nil)
+		//Shift elements to the right
+		copy(tree.sstableArrays[lowerLevel][leftIndex+1:], tree.sstableArrays[lowerLevel][leftIndex:])
+		tree.sstableArrays[lowerLevel][leftIndex] = upperTables[0]
 	} else {
-		//Add the first table from the passed level to be merged
+		//Add the tables from the upper level to be merged
 		var toMerge []*sstable.SSTable = make([]*sstable.SSTable, 0)
-		toMerge = append(toMerge, upperTable)
+		toMerge = append(toMerge, upperTables...)
 		//Add the tables from the lower level to be merged
 		for i := leftIndex; i <= rightIndex; i++ {
-			toMerge = append(toMerge, tree.sstableArrays[levelIndex+1][i])
+			toMerge = append(toMerge, tree.sstableArrays[lowerLevel][i])
 		}
 
 		merged, err := mergeSSTables(toMerge, tree.sstableIndexDegree, tree.sstableSummaryDegree,
-			tree.sstableInSameFile, tree.sstableCompressionOn, tree.compressionMap)
-
-		//Insert the merged sstable into the level
-		tree.closeAllTables()
-		tree.sstableArrays[levelIndex+1] = append(tree.sstableArrays[levelIndex+1], nil)
-		copy(tree.sstableArrays[levelIndex+1][leftIndex+1:], tree.sstableArrays[levelIndex+1][leftIndex:])
-		tree.sstableArrays[levelIndex+1][leftIndex] = merged
-		leftIndex += 1
-		rightIndex += 1
+			tree.sstableInSameFile, tree.sstableCompressionOn, tree.compressionMap, tree.maxTableRecords,
+			tree.tombstoneFilter(upperLevel+1, toMerge), newRateLimiter(tree.compactionRateLimit), tree.FilterConfig(lowerLevel))
 
 		if err != nil {
 			return err
 		}
 
+		//Insert the merged sstables into the level, in front of the tables they replace
+		tree.closeAllTables()
+		var level []*sstable.SSTable = tree.sstableArrays[lowerLevel]
+		var newLevel []*sstable.SSTable = make([]*sstable.SSTable, 0, len(level)+len(merged))
+		newLevel = append(newLevel, level[:leftIndex]...)
+		newLevel = append(newLevel, merged...)
+		newLevel = append(newLevel, level[leftIndex:]...)
+		tree.sstableArrays[lowerLevel] = newLevel
+		leftIndex += len(merged)
+		rightIndex += len(merged)
+
 		//Delete sstables that were merged
-		err = tree.deleteTable(upperTable)
-		if err != nil {
-			return err
+		for i := 0; i < len(upperTables); i++ {
+			err = tree.deleteTable(upperTables[i])
+			if err != nil {
+				return err
+			}
 		}
 
 		for i := leftIndex; i <= rightIndex; i++ {
-			err = tree.deleteTable(tree.sstableArrays[levelIndex+1][i])
+			err = tree.deleteTable(tree.sstableArrays[lowerLevel][i])
 			if err != nil {
 				return err
 			}
 		}
 
 		// Remove the deleted sstables from the level
-		copy(tree.sstableArrays[levelIndex+1][leftIndex:], tree.sstableArrays[levelIndex+1][rightIndex+1:])
+		copy(tree.sstableArrays[lowerLevel][leftIndex:], tree.sstableArrays[lowerLevel][rightIndex+1:])
 
 		//How many sstables were removed from the lower level
 		var tablesLost int = rightIndex - leftIndex + 1
 		//Change the extra tables to nil for the garbage collector
-		var lowerLevelLen int = len(tree.sstableArrays[levelIndex+1])
+		var lowerLevelLen int = len(tree.sstableArrays[lowerLevel])
 		for i := 0; i < tablesLost; i++ {
-			tree.sstableArrays[levelIndex+1][lowerLevelLen-1-i] = nil
+			tree.sstableArrays[lowerLevel][lowerLevelLen-1-i] = nil
 		}
-		tree.sstableArrays[levelIndex+1] = tree.sstableArrays[levelIndex+1][:lowerLevelLen-tablesLost]
+		tree.sstableArrays[lowerLevel] = tree.sstableArrays[lowerLevel][:lowerLevelLen-tablesLost]
 
 	}
 
-	//Remove the upper sstable from the upper level
-	copy(tree.sstableArrays[levelIndex][0:], tree.sstableArrays[levelIndex][1:])
-	levelLen := len(tree.sstableArrays[levelIndex])
-	tree.sstableArrays[levelIndex][levelLen-1] = nil
-	tree.sstableArrays[levelIndex] = tree.sstableArrays[levelIndex][:levelLen-1]
+	//Remove the upper sstables from the upper level
+	var isUpper map[*sstable.SSTable]bool = make(map[*sstable.SSTable]bool)
+	for i := 0; i < len(upperTables); i++ {
+		isUpper[upperTables[i]] = true
+	}
+	var remaining []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	for i := 0; i < len(tree.sstableArrays[upperLevel]); i++ {
+		if !isUpper[tree.sstableArrays[upperLevel][i]] {
+			remaining = append(remaining, tree.sstableArrays[upperLevel][i])
+		}
+	}
+	tree.sstableArrays[upperLevel] = remaining
 	tree.reopenSSTables()
 
 	return nil
@@ -518,12 +741,13 @@ func (tree *LSMTree) sizeTieredCompaction(levelIndex uint32) error {
 
 	//Merge all sstables into a single new sstable
 	merged, err := mergeSSTables(toMerge, tree.sstableIndexDegree, tree.sstableSummaryDegree,
-		tree.sstableInSameFile, tree.sstableCompressionOn, tree.compressionMap)
+		tree.sstableInSameFile, tree.sstableCompressionOn, tree.compressionMap, tree.maxTableRecords,
+		tree.tombstoneFilter(levelIndex+1, toMerge), newRateLimiter(tree.compactionRateLimit), tree.FilterConfig(levelIndex+1))
 
 	if err != nil {
 		return err
 	}
-	tree.sstableArrays[levelIndex+1] = append(tree.sstableArrays[levelIndex+1], merged)
+	tree.sstableArrays[levelIndex+1] = append(tree.sstableArrays[levelIndex+1], merged...)
 
 	//Delete old sstables
 	for i := 0; i < len(toMerge); i++ {
@@ -541,12 +765,7 @@ func (tree *LSMTree) sizeTieredCompaction(levelIndex uint32) error {
 }
 
 func (tree *LSMTree) compact(levelIndex uint32) error {
-	var err error
-	if tree.compactionType == "leveled" {
-		err = tree.leveledCompaction(levelIndex)
-	} else {
-		err = tree.sizeTieredCompaction(levelIndex)
-	}
+	err := tree.strategy.Compact(tree, levelIndex)
 	tree.closeAllTables()
 	return err
 }
@@ -560,25 +779,18 @@ func uintPow(x, y uint32) uint32 {
 	return result
 }
 
-func (tree *LSMTree) getCapacityOfLevel(levelIndex uint32) uint32 {
-	if tree.compactionType == "leveled" {
-		if levelIndex == 0 {
-			return 1
-		} else {
-			return tree.firstLevelSize * uintPow(tree.growthFactor, levelIndex-1)
-		}
-	} else {
-		return tree.firstLevelSize
-	}
-}
-
 func (tree *LSMTree) checkLevel(levelIndex uint32) error {
 	//No compaction on last level
 	if levelIndex+1 >= tree.maxDepth {
 		return nil
 	}
 
-	if len(tree.sstableArrays[levelIndex]) > int(tree.getCapacityOfLevel(levelIndex)) {
+	needsCompaction, err := tree.strategy.NeedsCompaction(tree, levelIndex)
+	if err != nil {
+		return err
+	}
+
+	if needsCompaction {
 		err := tree.compact(levelIndex)
 		if err != nil {
 			return err
@@ -594,9 +806,50 @@ func (tree *LSMTree) checkLevel(levelIndex uint32) error {
 	return nil
 }
 
+// Returns the latest version of the record with the passed key, or nil if no sstable contains the key
+// The returned record can be deleted, in which case the key doesn't exist anymore
+func (tree *LSMTree) Get(key string) (*model.Record, error) {
+	return sstable.SearchTables(tree.TableNames(), key, tree.compressionMap)
+}
+
+// Returns the number of sstables on each level
+func (tree *LSMTree) TableCounts() []int {
+	var counts []int = make([]int, tree.maxDepth)
+	for i := 0; i < int(tree.maxDepth); i++ {
+		counts[i] = len(tree.sstableArrays[i])
+	}
+	return counts
+}
+
+// Returns names of all sstables in the order they are searched, from the newest to the oldest -
+// level by level, and from the last added table on each level
+func (tree *LSMTree) TableNames() []string {
+	var names []string = make([]string, 0)
+	for i := 0; i < int(tree.maxDepth); i++ {
+		for j := len(tree.sstableArrays[i]) - 1; j >= 0; j-- {
+			names = append(names, tree.sstableArrays[i][j].Name)
+		}
+	}
+	return names
+}
+
 func (tree *LSMTree) AddSSTable(sstable *sstable.SSTable) error {
 	closeSSTable(sstable)
 	tree.sstableArrays[0] = append(tree.sstableArrays[0], sstable)
-	tree.SaveToFile()
-	return tree.checkLevel(0)
+	if err := tree.SaveToFile(); err != nil {
+		return err
+	}
+	if tree.compactionPaused {
+		return nil
+	}
+	return tree.saveAfter(tree.checkLevel(0))
+}
+
+// Saves the list of sstables after a compaction, even if the compaction failed, so it names the tables that are on disk
+// Returns the error of the compaction, or the error of saving if the compaction succeeded
+func (tree *LSMTree) saveAfter(err error) error {
+	if saveErr := tree.SaveToFile(); err == nil {
+		return saveErr
+	}
+	return err
 }
@@ -0,0 +1,224 @@
+package lsmtree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/sstable"
+)
+
+// Sstables are saved to ../data, so every test runs in its own directory with an empty data directory next to it
+func useTempData(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "src")
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"sstable", "compressionInfo"} {
+		if err := os.MkdirAll(filepath.Join(root, "data", dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+func newTestTree(t *testing.T, strategy CompactionStrategy) *LSMTree {
+	useTempData(t)
+	tree := makeEmptyLSMTree(3, strategy, 1, 2, 5, 5, true, false, map[string]uint64{}, 0, false, nil, sstable.PrefixExtractor{})
+	tree.PauseCompaction()
+	return tree
+}
+
+var timestamp uint64 = 0
+
+func put(key string, value string) *model.Record {
+	timestamp++
+	return model.NewRecordTimestamp(0, key, []byte(value), timestamp)
+}
+
+func del(key string) *model.Record {
+	timestamp++
+	return model.NewRecordTimestamp(1, key, []byte{}, timestamp)
+}
+
+// writes the records, sorted by key, to a new sstable and places it on the level
+func addTable(t *testing.T, tree *LSMTree, level int, records ...*model.Record) {
+	table, err := sstable.CreateSStable(records, true, false, 5, 5, tree.compressionMap, tree.FilterConfig(uint32(level)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeSSTable(table)
+	tree.sstableArrays[level] = append(tree.sstableArrays[level], table)
+}
+
+// returns the newest version of the key from the tables of one level, nil if none of them holds it
+func getFromLevel(t *testing.T, tree *LSMTree, level int, key string) *model.Record {
+	var names []string
+	for i := len(tree.sstableArrays[level]) - 1; i >= 0; i-- {
+		names = append(names, tree.sstableArrays[level][i].Name)
+	}
+	record, err := sstable.SearchTables(names, key, tree.compressionMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func expectDeleted(t *testing.T, tree *LSMTree, key string) {
+	t.Helper()
+	record, err := tree.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record != nil && record.Tombstone == 0 {
+		t.Fatalf("deleted key %q was read with value %q", key, record.Value)
+	}
+}
+
+func expectTombstone(t *testing.T, tree *LSMTree, level int, key string) {
+	t.Helper()
+	record := getFromLevel(t, tree, level, key)
+	if record == nil || record.Tombstone != 1 {
+		t.Fatalf("tombstone of %q should be kept on level %d, found %v", key, level, record)
+	}
+}
+
+func expectGone(t *testing.T, tree *LSMTree, key string) {
+	t.Helper()
+	record, err := tree.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record != nil {
+		t.Fatalf("key %q should be removed from all sstables, found tombstone %d, value %q", key, record.Tombstone, record.Value)
+	}
+}
+
+func expectValue(t *testing.T, tree *LSMTree, key string, value string) {
+	t.Helper()
+	record, err := tree.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil || record.Tombstone == 1 || string(record.Value) != value {
+		t.Fatalf("key %q should have value %q, found %v", key, value, record)
+	}
+}
+
+func TestLeveledCompactionKeepsTombstoneUntilLastLevel(t *testing.T) {
+	tree := newTestTree(t, &LeveledStrategy{})
+	addTable(t, tree, 2, put("a", "old"), put("b", "kept"))
+	addTable(t, tree, 1, put("a", "newer"), put("c", "1"))
+	addTable(t, tree, 0, del("a"))
+
+	if err := tree.compact(0); err != nil {
+		t.Fatal(err)
+	}
+	expectTombstone(t, tree, 1, "a")
+	expectDeleted(t, tree, "a")
+
+	if err := tree.compact(1); err != nil {
+		t.Fatal(err)
+	}
+	expectGone(t, tree, "a")
+	expectValue(t, tree, "b", "kept")
+	expectValue(t, tree, "c", "1")
+}
+
+func TestLeveledCompactionDropsTombstoneWithNoOlderTable(t *testing.T) {
+	tree := newTestTree(t, &LeveledStrategy{})
+	addTable(t, tree, 2, put("a", "old"))
+	addTable(t, tree, 1, put("p", "1"), put("r", "1"))
+	addTable(t, tree, 0, del("q"))
+
+	if err := tree.compact(0); err != nil {
+		t.Fatal(err)
+	}
+	// no table below level 1 can hold "q", so its tombstone isn't needed there
+	expectGone(t, tree, "q")
+	expectValue(t, tree, "a", "old")
+}
+
+func TestSizeTieredCompactionNeverResurrects(t *testing.T) {
+	tree := newTestTree(t, &SizeTieredStrategy{})
+	addTable(t, tree, 2, put("a", "old"), put("b", "kept"))
+	addTable(t, tree, 1, put("a", "newer"))
+	addTable(t, tree, 0, del("a"))
+	addTable(t, tree, 0, put("c", "1"))
+
+	if err := tree.compact(0); err != nil {
+		t.Fatal(err)
+	}
+	expectTombstone(t, tree, 1, "a")
+	expectDeleted(t, tree, "a")
+
+	// size-tiered compaction adds the merged table to the last level next to the old one,
+	// which still holds the old value, so the tombstone must be kept there as well
+	if err := tree.compact(1); err != nil {
+		t.Fatal(err)
+	}
+	expectTombstone(t, tree, 2, "a")
+	expectDeleted(t, tree, "a")
+
+	if err := tree.CompactAll(); err != nil {
+		t.Fatal(err)
+	}
+	expectGone(t, tree, "a")
+	expectValue(t, tree, "b", "kept")
+	expectValue(t, tree, "c", "1")
+}
+
+func TestManualCompactionRemovesDeletedKeys(t *testing.T) {
+	tree := newTestTree(t, &LeveledStrategy{})
+	addTable(t, tree, 2, put("a", "v1"), put("b", "v1"), put("d", "v1"))
+	addTable(t, tree, 1, put("a", "v2"), put("b", "v2"))
+	addTable(t, tree, 0, del("a"), put("b", "v3"))
+
+	if err := tree.CompactRange("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	expectGone(t, tree, "a")
+	expectValue(t, tree, "b", "v3")
+	expectValue(t, tree, "d", "v1")
+	counts := tree.TableCounts()
+	if counts[0] != 0 || counts[1] != 0 {
+		t.Fatalf("range should be moved to the last level, tables per level: %v", counts)
+	}
+}
+
+func TestTimeWindowExpiryRunsWhenTreeIsOpened(t *testing.T) {
+	strategy := &TimeWindowStrategy{TTL: uint64(time.Hour)}
+	tree := newTestTree(t, strategy)
+	addTable(t, tree, 1, model.NewRecordTimestamp(0, "old", []byte("1"), now()-2*uint64(time.Hour)))
+	addTable(t, tree, 0, model.NewRecordTimestamp(0, "new", []byte("1"), now()))
+	if err := tree.SaveToFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := LoadLSMTreeFromFile(3, strategy, 1, 2, 5, 5, true, false, map[string]uint64{}, 0, false, nil, sstable.PrefixExtractor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// timestamps are read with the tables, not from their records
+	for i := 0; i < 2; i++ {
+		if len(opened.sstableArrays[i]) != 1 || opened.sstableArrays[i][0].MaxTimestamp != tree.sstableArrays[i][0].MaxTimestamp {
+			t.Fatalf("newest timestamp of the table on level %d wasn't read", i)
+		}
+	}
+
+	if err := opened.DropExpired(); err != nil {
+		t.Fatal(err)
+	}
+	expectGone(t, opened, "old")
+	expectValue(t, opened, "new", "1")
+}
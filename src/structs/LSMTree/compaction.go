@@ -0,0 +1,196 @@
+package lsmtree
+
+import "time"
+
+const (
+	LEVELED     = "leveled"
+	SIZE_TIERED = "sizetiered"
+	HYBRID      = "hybrid"
+	TIME_WINDOW = "timewindow"
+)
+
+// CompactionStrategy decides when a level of the LSM tree gets compacted and how its data is moved to the lower levels
+type CompactionStrategy interface {
+	NeedsCompaction(tree *LSMTree, levelIndex uint32) (bool, error) //Should return true if the level needs to be compacted
+	Compact(tree *LSMTree, levelIndex uint32) error                 //Should move the data of the level to lower levels
+	IsLevelSorted(tree *LSMTree, levelIndex uint32) bool            //Should return true if the level has no overlapping tables, sorted by key
+}
+
+// ExpiringStrategy is a compaction strategy that deletes sstables once their data is too old
+// Expiry doesn't wait for a flush: the tree also runs it when it is opened and before manual compaction,
+// so old data is dropped from stores that get no writes
+type ExpiringStrategy interface {
+	DropExpired(tree *LSMTree) error //Should delete the sstables whose data has expired
+}
+
+// Returns the compaction strategy with the passed name
+// sizeRatio and maxSpaceAmplification are percentages used by the hybrid strategy
+// windowSeconds and ttlSeconds are used by the time-window strategy
+// If the name is unknown, size-tiered compaction is used
+func NewCompactionStrategy(compactionType string, sizeRatio uint32, maxSpaceAmplification uint32,
+	windowSeconds uint64, ttlSeconds uint64) CompactionStrategy {
+	switch compactionType {
+	case LEVELED:
+		return &LeveledStrategy{}
+	case HYBRID:
+		return &HybridStrategy{SizeRatio: sizeRatio, MaxSpaceAmplification: maxSpaceAmplification}
+	case TIME_WINDOW:
+		return &TimeWindowStrategy{WindowSize: windowSeconds * uint64(time.Second), TTL: ttlSeconds * uint64(time.Second)}
+	default:
+		return &SizeTieredStrategy{}
+	}
+}
+
+// Leveled compaction keeps every level except the first one sorted, with no overlapping tables
+// Each level can hold growthFactor times more tables than the level above it
+// Compaction merges the oldest table of a level with the overlapping tables of the next level
+type LeveledStrategy struct{}
+
+func (strategy *LeveledStrategy) NeedsCompaction(tree *LSMTree, levelIndex uint32) (bool, error) {
+	var capacity uint32
+	if levelIndex == 0 {
+		capacity = 1
+	} else {
+		capacity = tree.firstLevelSize * uintPow(tree.growthFactor, levelIndex-1)
+	}
+	return len(tree.sstableArrays[levelIndex]) > int(capacity), nil
+}
+
+func (strategy *LeveledStrategy) Compact(tree *LSMTree, levelIndex uint32) error {
+	return tree.leveledCompaction(levelIndex)
+}
+
+func (strategy *LeveledStrategy) IsLevelSorted(tree *LSMTree, levelIndex uint32) bool {
+	return levelIndex > 0
+}
+
+// Size-tiered compaction merges all tables of a level into the next level once the level has more than firstLevelSize tables
+type SizeTieredStrategy struct{}
+
+func (strategy *SizeTieredStrategy) NeedsCompaction(tree *LSMTree, levelIndex uint32) (bool, error) {
+	return len(tree.sstableArrays[levelIndex]) > int(tree.firstLevelSize), nil
+}
+
+func (strategy *SizeTieredStrategy) Compact(tree *LSMTree, levelIndex uint32) error {
+	return tree.sizeTieredCompaction(levelIndex)
+}
+
+func (strategy *SizeTieredStrategy) IsLevelSorted(tree *LSMTree, levelIndex uint32) bool {
+	return false
+}
+
+// Hybrid compaction is size-tiered on all levels except the last one, which is leveled
+// Tiered levels are cheap to write to, while the sorted last level, which holds most of the data, keeps space usage low
+// A tiered level is compacted when:
+//   - it has more than firstLevelSize tables, or
+//   - its size reaches SizeRatio percent of the size of the next level (size-ratio trigger)
+//
+// When a tiered level is compacted into the last level, its tables are merged with the overlapping tables of the last level
+// If the size of all tiered levels together exceeds MaxSpaceAmplification percent of the size of the last level,
+// all levels are merged into the last level (space-amplification trigger)
+// Zero turns the corresponding trigger off
+type HybridStrategy struct {
+	SizeRatio             uint32
+	MaxSpaceAmplification uint32
+}
+
+func (strategy *HybridStrategy) NeedsCompaction(tree *LSMTree, levelIndex uint32) (bool, error) {
+	if len(tree.sstableArrays[levelIndex]) == 0 {
+		return false, nil
+	}
+
+	spaceAmplified, err := strategy.isSpaceAmplified(tree)
+	if err != nil || spaceAmplified {
+		return spaceAmplified, err
+	}
+
+	if len(tree.sstableArrays[levelIndex]) > int(tree.firstLevelSize) {
+		return true, nil
+	}
+
+	if strategy.SizeRatio == 0 {
+		return false, nil
+	}
+	levelSize, err := tree.levelSize(levelIndex)
+	if err != nil {
+		return false, err
+	}
+	nextLevelSize, err := tree.levelSize(levelIndex + 1)
+	if err != nil {
+		return false, err
+	}
+	return nextLevelSize > 0 && levelSize*100 >= nextLevelSize*int64(strategy.SizeRatio), nil
+}
+
+func (strategy *HybridStrategy) Compact(tree *LSMTree, levelIndex uint32) error {
+	spaceAmplified, err := strategy.isSpaceAmplified(tree)
+	if err != nil {
+		return err
+	}
+	if spaceAmplified {
+		return tree.compactIntoLastLevel()
+	}
+
+	var lastLevel uint32 = tree.maxDepth - 1
+	if levelIndex+1 == lastLevel {
+		return tree.mergeIntoLevel(tree.sstableArrays[levelIndex], levelIndex, lastLevel)
+	}
+	return tree.sizeTieredCompaction(levelIndex)
+}
+
+func (strategy *HybridStrategy) IsLevelSorted(tree *LSMTree, levelIndex uint32) bool {
+	return levelIndex == tree.maxDepth-1
+}
+
+// Returns true if the tiered levels take more space than allowed compared to the last level
+// Space amplification can't be measured while the last level is empty
+func (strategy *HybridStrategy) isSpaceAmplified(tree *LSMTree) (bool, error) {
+	if strategy.MaxSpaceAmplification == 0 {
+		return false, nil
+	}
+
+	var lastLevel uint32 = tree.maxDepth - 1
+	lastLevelSize, err := tree.levelSize(lastLevel)
+	if err != nil || lastLevelSize == 0 {
+		return false, err
+	}
+
+	var tieredSize int64 = 0
+	for i := uint32(0); i < lastLevel; i++ {
+		size, err := tree.levelSize(i)
+		if err != nil {
+			return false, err
+		}
+		tieredSize += size
+	}
+	return tieredSize*100 > lastLevelSize*int64(strategy.MaxSpaceAmplification), nil
+}
+
+// Returns the number of bytes the tables of the level take on disk
+func (tree *LSMTree) levelSize(levelIndex uint32) (int64, error) {
+	var size int64 = 0
+	for i := 0; i < len(tree.sstableArrays[levelIndex]); i++ {
+		tableSize, err := tree.sstableArrays[levelIndex][i].Size()
+		if err != nil {
+			return 0, err
+		}
+		size += tableSize
+	}
+	return size, nil
+}
+
+// Merges the tables of all levels into the last level, which stays sorted
+// Levels are merged starting from the deepest one, so that the tombstones can be dropped once no older level is left
+func (tree *LSMTree) compactIntoLastLevel() error {
+	var lastLevel uint32 = tree.maxDepth - 1
+	for i := int(lastLevel) - 1; i >= 0; i-- {
+		if len(tree.sstableArrays[i]) == 0 {
+			continue
+		}
+		err := tree.mergeIntoLevel(tree.sstableArrays[i], uint32(i), lastLevel)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
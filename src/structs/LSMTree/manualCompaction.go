@@ -0,0 +1,192 @@
+package lsmtree
+
+import (
+	"errors"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/sstable"
+)
+
+// Stops compaction that is triggered by adding sstables, manual compaction still works
+func (tree *LSMTree) PauseCompaction() {
+	tree.compactionPaused = true
+}
+
+// Turns compaction back on and compacts the levels that filled up while it was paused
+func (tree *LSMTree) ResumeCompaction() error {
+	tree.compactionPaused = false
+	return tree.saveAfter(tree.checkAllLevels())
+}
+
+func (tree *LSMTree) IsCompactionPaused() bool {
+	return tree.compactionPaused
+}
+
+// Sets the max number of bytes per second that compaction writes, 0 removes the limit
+func (tree *LSMTree) SetCompactionRateLimit(bytesPerSecond uint64) {
+	tree.compactionRateLimit = bytesPerSecond
+}
+
+func (tree *LSMTree) CompactionRateLimit() uint64 {
+	return tree.compactionRateLimit
+}
+
+// Sets the hash of merkle trees of sstables made from now on, existing sstables keep theirs
+func (tree *LSMTree) SetMerkleHash(hashType merkletree.HashType) {
+	tree.merkleHash = hashType
+}
+
+// Moves all sstables holding keys from the range [start, end] down to the last level and merges them there
+// Deleted records from the range are removed from the disk, since no older data is left below the last level
+// Records that are still in the memtable are not compacted
+func (tree *LSMTree) CompactRange(start string, end string) error {
+	if start > end {
+		return errors.New("start of the range must not be larger than the end")
+	}
+
+	var lastLevel uint32 = tree.maxDepth - 1
+	for i := uint32(0); i < lastLevel; i++ {
+		var tables []*sstable.SSTable = tree.tablesInRange(i, start, end)
+		if len(tables) == 0 {
+			continue
+		}
+		err := tree.mergeIntoLevel(tables, i, i+1)
+		tree.closeAllTables()
+		if err != nil {
+			return tree.saveAfter(err)
+		}
+	}
+
+	err := tree.rewriteTables(lastLevel, tree.tablesInRange(lastLevel, start, end))
+	tree.closeAllTables()
+	if err != nil {
+		return tree.saveAfter(err)
+	}
+
+	//Levels could have grown over the limits of the compaction strategy
+	if !tree.compactionPaused {
+		err = tree.checkAllLevels()
+	}
+	return tree.saveAfter(err)
+}
+
+// Deletes the sstables whose data has expired, if the compaction strategy expires data
+func (tree *LSMTree) DropExpired() error {
+	strategy, ok := tree.strategy.(ExpiringStrategy)
+	if !ok {
+		return nil
+	}
+	return tree.saveAfter(strategy.DropExpired(tree))
+}
+
+// Compacts the whole key range of the tree, expired sstables are deleted first
+func (tree *LSMTree) CompactAll() error {
+	err := tree.DropExpired()
+	if err != nil {
+		return err
+	}
+
+	var start, end string
+	var empty bool = true
+	for i := 0; i < int(tree.maxDepth); i++ {
+		for j := 0; j < len(tree.sstableArrays[i]); j++ {
+			var table *sstable.SSTable = tree.sstableArrays[i][j]
+			if empty || table.MinKey < start {
+				start = table.MinKey
+			}
+			if empty || table.MaxKey > end {
+				end = table.MaxKey
+			}
+			empty = false
+		}
+	}
+
+	if empty {
+		return nil
+	}
+	return tree.CompactRange(start, end)
+}
+
+func (tree *LSMTree) checkAllLevels() error {
+	for i := uint32(0); i < tree.maxDepth; i++ {
+		err := tree.checkLevel(i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the tables of the level whose keys overlap with the range [start, end]
+// The range is widened by the keys of the returned tables until no other table of the level overlaps with them,
+// so moving the returned tables to a lower level can't leave an older version of their keys above them
+func (tree *LSMTree) tablesInRange(levelIndex uint32, start string, end string) []*sstable.SSTable {
+	var picked map[*sstable.SSTable]bool = make(map[*sstable.SSTable]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, table := range tree.sstableArrays[levelIndex] {
+			if picked[table] || table.MinKey > end || table.MaxKey < start {
+				continue
+			}
+			picked[table] = true
+			changed = true
+			if table.MinKey < start {
+				start = table.MinKey
+			}
+			if table.MaxKey > end {
+				end = table.MaxKey
+			}
+		}
+	}
+
+	var tables []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	for _, table := range tree.sstableArrays[levelIndex] {
+		if picked[table] {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// Merges the passed tables of the level into new tables, which take the place of the first passed table
+// No other table of the level may overlap with the passed ones
+func (tree *LSMTree) rewriteTables(levelIndex uint32, tables []*sstable.SSTable) error {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	merged, err := mergeSSTables(tables, tree.sstableIndexDegree, tree.sstableSummaryDegree,
+		tree.sstableInSameFile, tree.sstableCompressionOn, tree.compressionMap, tree.maxTableRecords,
+		tree.tombstoneFilter(levelIndex+1, tables), newRateLimiter(tree.compactionRateLimit), tree.FilterConfig(levelIndex))
+	if err != nil {
+		return err
+	}
+
+	var isMerged map[*sstable.SSTable]bool = make(map[*sstable.SSTable]bool)
+	for i := 0; i < len(tables); i++ {
+		isMerged[tables[i]] = true
+	}
+
+	//Insert the merged sstables in front of the first table they replace
+	tree.closeAllTables()
+	var newLevel []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	for _, table := range tree.sstableArrays[levelIndex] {
+		if table == tables[0] {
+			newLevel = append(newLevel, merged...)
+		}
+		newLevel = append(newLevel, table)
+	}
+	tree.sstableArrays[levelIndex] = newLevel
+
+	//Delete sstables that were merged
+	//They stay in the level until all are deleted, so their names get updated when other sstables are deleted
+	for i := 0; i < len(tables); i++ {
+		err = tree.deleteTable(tables[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	tree.removeTables(levelIndex, isMerged)
+	return tree.reopenSSTables()
+}
@@ -0,0 +1,32 @@
+package lsmtree
+
+import "time"
+
+// Limits how fast compaction writes new sstables
+// Compaction sleeps whenever it gets ahead of the allowed number of bytes per second
+type rateLimiter struct {
+	bytesPerSecond uint64
+	start          time.Time
+	written        uint64
+}
+
+// Returns nil if there is no limit, waiting on a nil limiter does nothing
+func newRateLimiter(bytesPerSecond uint64) *rateLimiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+	return &rateLimiter{bytesPerSecond: bytesPerSecond, start: time.Now()}
+}
+
+// Records that the passed number of bytes was written and waits until writing them fits into the limit
+func (limiter *rateLimiter) wait(bytes uint64) {
+	if limiter == nil {
+		return
+	}
+	limiter.written += bytes
+	var allowedAfter time.Duration = time.Duration(float64(limiter.written) / float64(limiter.bytesPerSecond) * float64(time.Second))
+	var elapsed time.Duration = time.Since(limiter.start)
+	if allowedAfter > elapsed {
+		time.Sleep(allowedAfter - elapsed)
+	}
+}
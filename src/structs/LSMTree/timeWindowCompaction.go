@@ -0,0 +1,234 @@
+package lsmtree
+
+import (
+	"time"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/iterators"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/sstable"
+)
+
+// Time-window compaction is meant for time series data, which is rarely changed once its time has passed
+// Sstables are grouped into windows of WindowSize nanoseconds by the timestamp of the newest record they hold
+// New sstables stay on the first level until their window is over, then all sstables of the window
+// are merged into the second level, which holds the windows sorted from the oldest to the newest
+// Sstables from different windows are never merged, so old data isn't rewritten again
+// If TTL isn't 0, sstables whose newest record is older than TTL are deleted from all levels without being merged,
+// after flushes, when the tree is opened and before manual compaction
+// If WindowSize is 0, all sstables are in the same window, which is never over
+type TimeWindowStrategy struct {
+	WindowSize uint64
+	TTL        uint64
+}
+
+func (strategy *TimeWindowStrategy) NeedsCompaction(tree *LSMTree, levelIndex uint32) (bool, error) {
+	//Everything is done when the first level is compacted
+	if levelIndex != 0 {
+		return false, nil
+	}
+
+	expired, err := strategy.expiredTables(tree)
+	if err != nil || len(expired) > 0 {
+		return len(expired) > 0, err
+	}
+
+	var currentWindow uint64 = strategy.window(now())
+	for i := 0; i < len(tree.sstableArrays[0]); i++ {
+		maxTimestamp, err := tree.maxTimestamp(tree.sstableArrays[0][i])
+		if err != nil {
+			return false, err
+		}
+		if strategy.window(maxTimestamp) < currentWindow {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (strategy *TimeWindowStrategy) Compact(tree *LSMTree, levelIndex uint32) error {
+	err := strategy.DropExpired(tree)
+	if err != nil {
+		return err
+	}
+
+	//Windows that are over are compacted from the oldest one
+	//The sstables are looked up again after each window, because compaction reloads all of them
+	var currentWindow uint64 = strategy.window(now())
+	for {
+		var oldest uint64 = currentWindow
+		var tables []*sstable.SSTable = make([]*sstable.SSTable, 0)
+		for i := 0; i < len(tree.sstableArrays[0]); i++ {
+			var table *sstable.SSTable = tree.sstableArrays[0][i]
+			window, err := strategy.tableWindow(tree, table)
+			if err != nil {
+				return err
+			}
+			if window < oldest {
+				oldest = window
+				tables = tables[:0]
+			}
+			if window == oldest && window < currentWindow {
+				tables = append(tables, table)
+			}
+		}
+
+		if len(tables) == 0 {
+			return nil
+		}
+		err = strategy.compactWindow(tree, oldest, tables)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (strategy *TimeWindowStrategy) IsLevelSorted(tree *LSMTree, levelIndex uint32) bool {
+	return false
+}
+
+func now() uint64 {
+	return uint64(time.Now().UnixNano())
+}
+
+func (strategy *TimeWindowStrategy) window(timestamp uint64) uint64 {
+	if strategy.WindowSize == 0 {
+		return 0
+	}
+	return timestamp / strategy.WindowSize
+}
+
+func (strategy *TimeWindowStrategy) tableWindow(tree *LSMTree, table *sstable.SSTable) (uint64, error) {
+	maxTimestamp, err := tree.maxTimestamp(table)
+	if err != nil {
+		return 0, err
+	}
+	return strategy.window(maxTimestamp), nil
+}
+
+// Merges the passed sstables from the first level with the sstables of the same window from the second level
+// The merged sstables are put in front of the first sstable from a newer window, so the second level stays sorted by windows
+func (strategy *TimeWindowStrategy) compactWindow(tree *LSMTree, window uint64, upperTables []*sstable.SSTable) error {
+	var toMerge []*sstable.SSTable = append([]*sstable.SSTable{}, upperTables...)
+	var insertIndex int = len(tree.sstableArrays[1])
+	for i := 0; i < len(tree.sstableArrays[1]); i++ {
+		tableWindow, err := strategy.tableWindow(tree, tree.sstableArrays[1][i])
+		if err != nil {
+			return err
+		}
+		if tableWindow == window {
+			toMerge = append(toMerge, tree.sstableArrays[1][i])
+		} else if tableWindow > window && i < insertIndex {
+			insertIndex = i
+		}
+	}
+
+	//A single sstable is just moved to the second level
+	var merged []*sstable.SSTable = toMerge
+	if len(toMerge) > 1 {
+		var err error
+		merged, err = mergeSSTables(toMerge, tree.sstableIndexDegree, tree.sstableSummaryDegree,
+			tree.sstableInSameFile, tree.sstableCompressionOn, tree.compressionMap, tree.maxTableRecords,
+			tree.tombstoneFilter(1, toMerge), newRateLimiter(tree.compactionRateLimit), tree.FilterConfig(1))
+		if err != nil {
+			return err
+		}
+	}
+
+	tree.closeAllTables()
+	var isMerged map[*sstable.SSTable]bool = make(map[*sstable.SSTable]bool)
+	for i := 0; i < len(toMerge); i++ {
+		isMerged[toMerge[i]] = true
+	}
+	var newLevel []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	newLevel = append(newLevel, tree.sstableArrays[1][:insertIndex]...)
+	newLevel = append(newLevel, merged...)
+	newLevel = append(newLevel, tree.sstableArrays[1][insertIndex:]...)
+	tree.sstableArrays[1] = newLevel
+
+	if len(toMerge) > 1 {
+		//Merged sstables stay in the levels until all of them are deleted, so their names get updated
+		for i := 0; i < len(toMerge); i++ {
+			err := tree.deleteTable(toMerge[i])
+			if err != nil {
+				return err
+			}
+		}
+		tree.removeTables(1, isMerged)
+	}
+	tree.removeTables(0, isMerged)
+
+	return tree.reopenSSTables()
+}
+
+// Returns the sstables from all levels whose newest record is older than TTL
+func (strategy *TimeWindowStrategy) expiredTables(tree *LSMTree) ([]*sstable.SSTable, error) {
+	var expired []*sstable.SSTable = make([]*sstable.SSTable, 0)
+	var currentTime uint64 = now()
+	if strategy.TTL == 0 || currentTime < strategy.TTL {
+		return expired, nil
+	}
+
+	for i := 0; i < int(tree.maxDepth); i++ {
+		for j := 0; j < len(tree.sstableArrays[i]); j++ {
+			maxTimestamp, err := tree.maxTimestamp(tree.sstableArrays[i][j])
+			if err != nil {
+				return nil, err
+			}
+			if maxTimestamp < currentTime-strategy.TTL {
+				expired = append(expired, tree.sstableArrays[i][j])
+			}
+		}
+	}
+	return expired, nil
+}
+
+// Deletes expired sstables, whole windows are dropped at once since their sstables are equally old
+func (strategy *TimeWindowStrategy) DropExpired(tree *LSMTree) error {
+	expired, err := strategy.expiredTables(tree)
+	if err != nil || len(expired) == 0 {
+		return err
+	}
+
+	var isExpired map[*sstable.SSTable]bool = make(map[*sstable.SSTable]bool)
+	for i := 0; i < len(expired); i++ {
+		err = tree.deleteTable(expired[i])
+		if err != nil {
+			return err
+		}
+		isExpired[expired[i]] = true
+	}
+	for i := uint32(0); i < tree.maxDepth; i++ {
+		tree.removeTables(i, isExpired)
+	}
+
+	return tree.reopenSSTables()
+}
+
+// Returns the timestamp of the newest record in the sstable
+// It is read with the sstable, only sstables written before it was saved are read in full the first time it's needed
+func (tree *LSMTree) maxTimestamp(table *sstable.SSTable) (uint64, error) {
+	if table.MaxTimestamp != 0 {
+		return table.MaxTimestamp, nil
+	}
+
+	iterator, err := iterators.NewSSTableIterator(table, tree.sstableCompressionOn, tree.compressionMap)
+	if err != nil {
+		return 0, err
+	}
+	defer iterator.Stop()
+
+	var maxTimestamp uint64 = 0
+	for {
+		record, err := iterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		if record == nil {
+			break
+		}
+		if record.Timestamp > maxTimestamp {
+			maxTimestamp = record.Timestamp
+		}
+	}
+	table.MaxTimestamp = maxTimestamp
+	return maxTimestamp, nil
+}
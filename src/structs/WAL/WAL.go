@@ -2,6 +2,7 @@ package WAL
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -161,6 +162,26 @@ func (wal *WAL) Commit(key string, value []byte, tombstone byte) {
 		return
 	}
 }
+
+// returned for records that don't fit in a log segment, even when it is empty
+var ErrRecordTooLarge = errors.New("record is too large for the log")
+
+// CheckSize returns an error if the record can't be appended, a record can continue only into one next segment
+func (wal *WAL) CheckSize(r *model.Record) error {
+	fileLength, err := utils.GetFileLength(wal.currentFile)
+	if err != nil {
+		return err
+	}
+	return wal.checkSize(int64(r.GetRecordLength()), int64(wal.maxBytesPerFile)-fileLength)
+}
+
+func (wal *WAL) checkSize(size int64, bytesLeft int64) error {
+	if size-bytesLeft > int64(wal.maxBytesPerFile) {
+		return fmt.Errorf("%w, it has %d bytes and segments have %d bytes", ErrRecordTooLarge, size, wal.maxBytesPerFile)
+	}
+	return nil
+}
+
 func (wal *WAL) Append(r *model.Record) error {
 	data := r.RecordToBytes()
 	fileLength, err := utils.GetFileLength(wal.currentFile)
@@ -168,7 +189,10 @@ func (wal *WAL) Append(r *model.Record) error {
 		return err
 	}
 	bytesLeft := int64(wal.maxBytesPerFile) - fileLength // number of left bytes
-	if bytesLeft >= int64(len(data)) {                   // if there is enough space for record, just write it
+	if err := wal.checkSize(int64(len(data)), bytesLeft); err != nil {
+		return err
+	}
+	if bytesLeft >= int64(len(data)) { // if there is enough space for record, just write it
 		_, err := wal.currentFile.Seek(0, 2) //Seek to EOF
 		if err != nil {
 			log.Fatal(err)
@@ -349,3 +373,67 @@ func (wal *WAL) UpdateWatermark(didFlush bool) error {
 	}
 	return nil
 }
+
+// WALCheck is the result of checking log segments, records are counted from the low watermark, as they are recovered
+type WALCheck struct {
+	Segments int      `json:"segments"`
+	Records  int      `json:"records"`
+	Problems []string `json:"problems"`
+}
+
+// Check reads the log the way it is read on recovery and checks that segments are numbered one after the other,
+// that the watermark points into them, and that every record after it is whole and has a valid CRC
+func (wal *WAL) Check() WALCheck {
+	check := WALCheck{Segments: len(wal.segmentNames), Problems: make([]string, 0)}
+	for i, fileName := range wal.segmentNames {
+		if fileName != fmt.Sprintf("%s%04d.log", FILE_NAME, i+1) {
+			check.Problems = append(check.Problems, fmt.Sprintf("segment %s is out of order, expected %s%04d.log", fileName, FILE_NAME, i+1))
+		}
+	}
+	if wal.lowWaterMark < 1 || int(wal.lowWaterMark) > len(wal.segmentNames) {
+		check.Problems = append(check.Problems, fmt.Sprintf("low watermark %d doesn't point to a segment", wal.lowWaterMark))
+		return check
+	}
+
+	var data []byte
+	for i := int(wal.lowWaterMark) - 1; i < len(wal.segmentNames); i++ {
+		content, err := os.ReadFile(fmt.Sprintf("../data/log/%s", wal.segmentNames[i]))
+		if err != nil {
+			check.Problems = append(check.Problems, fmt.Sprintf("segment %s can't be read: %v", wal.segmentNames[i], err))
+			return check
+		}
+		if i == int(wal.lowWaterMark)-1 {
+			if wal.bytesFromLastSegment > int64(len(content)) {
+				check.Problems = append(check.Problems, fmt.Sprintf("watermark offset %d is past the end of segment %s", wal.bytesFromLastSegment, wal.segmentNames[i]))
+				return check
+			}
+			content = content[wal.bytesFromLastSegment:]
+		}
+		data = append(data, content...)
+	}
+
+	// records can continue in the next segment, so all segments are read as one
+	for offset := 0; offset < len(data); {
+		bytesLeft := uint64(len(data) - offset)
+		if bytesLeft < KEY_START {
+			check.Problems = append(check.Problems, fmt.Sprintf("last record is incomplete, %d bytes are left", bytesLeft))
+			break
+		}
+		keySize := binary.BigEndian.Uint64(data[offset+KEY_SIZE_START : offset+KEY_SIZE_START+KEY_SIZE_SIZE])
+		valueSize := binary.BigEndian.Uint64(data[offset+VALUE_SIZE_START : offset+VALUE_SIZE_START+VALUE_SIZE_SIZE])
+		if keySize > bytesLeft || valueSize > bytesLeft || KEY_START+keySize+valueSize > bytesLeft {
+			check.Problems = append(check.Problems, fmt.Sprintf("record %d is incomplete or its sizes are damaged", check.Records))
+			break
+		}
+		_, bytesRead, err := model.ReadSingleRecord(data[offset:])
+		if err != nil {
+			check.Problems = append(check.Problems, fmt.Sprintf("record %d has an invalid CRC", check.Records))
+		}
+		offset += int(KEY_START + keySize + valueSize)
+		if err == nil && bytesRead != int(KEY_START+keySize+valueSize) {
+			check.Problems = append(check.Problems, fmt.Sprintf("record %d has a wrong length", check.Records))
+		}
+		check.Records++
+	}
+	return check
+}
@@ -0,0 +1,146 @@
+package accessStats
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/topK"
+)
+
+// error and probability of a bigger error of the count min sketches that count accesses
+const (
+	EPSILON = 0.001
+	DELTA   = 0.01
+)
+
+type KeyCount struct {
+	Key   string
+	Count uint64
+}
+
+type Report struct {
+	SampleRate  uint32
+	Reads       uint64 // all reads, not only the sampled ones
+	Writes      uint64
+	HotReads    []KeyCount // counts of hot keys and prefixes are estimated from the sampled accesses
+	HotWrites   []KeyCount
+	HotPrefixes []KeyCount // prefixes of both read and written keys
+}
+
+// AccessStats counts how often keys are read and written, and keeps the most accessed keys and prefixes
+// Only one of every SampleRate accesses is counted, chosen at random, so counting doesn't slow down every access
+type AccessStats struct {
+	lock         sync.Mutex
+	sampleRate   uint32
+	prefixLength int
+	k            uint32
+	reads        uint64
+	writes       uint64
+	hotReads     *topK.TopK
+	hotWrites    *topK.TopK
+	hotPrefixes  *topK.TopK
+}
+
+// Prefix of a key is its first prefixLength bytes, keys that are shorter are their own prefix
+func NewAccessStats(sampleRate uint32, k uint32, prefixLength int) *AccessStats {
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
+	stats := &AccessStats{sampleRate: sampleRate, prefixLength: prefixLength, k: k}
+	stats.Reset()
+	return stats
+}
+
+func (stats *AccessStats) Reset() {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	stats.reads = 0
+	stats.writes = 0
+	stats.hotReads = topK.NewTopK(stats.k, EPSILON, DELTA)
+	stats.hotWrites = topK.NewTopK(stats.k, EPSILON, DELTA)
+	stats.hotPrefixes = topK.NewTopK(stats.k, EPSILON, DELTA)
+}
+
+func (stats *AccessStats) prefix(key string) string {
+	if len(key) <= stats.prefixLength {
+		return key
+	}
+	return key[:stats.prefixLength]
+}
+
+func (stats *AccessStats) sampled() bool {
+	return stats.sampleRate == 1 || rand.Uint32()%stats.sampleRate == 0
+}
+
+// Returns true if the read made the key one of the most read keys, which is the only way their set changes
+func (stats *AccessStats) RecordRead(key string) bool {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	stats.reads++
+	if !stats.sampled() {
+		return false
+	}
+	wasHot := stats.hotReads.Contains(key)
+	stats.hotReads.Insert(key)
+	stats.hotPrefixes.Insert(stats.prefix(key))
+	return !wasHot && stats.hotReads.Contains(key)
+}
+
+func (stats *AccessStats) RecordWrite(key string) {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	stats.writes++
+	if stats.sampled() {
+		stats.hotWrites.Insert(key)
+		stats.hotPrefixes.Insert(stats.prefix(key))
+	}
+}
+
+// Returns the k most read keys
+func (stats *AccessStats) HotReadKeys() []string {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	items := stats.hotReads.List()
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	return keys
+}
+
+// Returns estimated numbers of reads and writes of the key
+func (stats *AccessStats) KeyCount(key string) (uint64, uint64) {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	rate := uint64(stats.sampleRate)
+	return uint64(stats.hotReads.Query(key)) * rate, uint64(stats.hotWrites.Query(key)) * rate
+}
+
+// Returns the estimated number of reads and writes of keys with the prefix, the prefix must be prefixLength long
+func (stats *AccessStats) PrefixCount(prefix string) uint64 {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	return uint64(stats.hotPrefixes.Query(prefix)) * uint64(stats.sampleRate)
+}
+
+func (stats *AccessStats) Report() Report {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	return Report{
+		SampleRate:  stats.sampleRate,
+		Reads:       stats.reads,
+		Writes:      stats.writes,
+		HotReads:    stats.scale(stats.hotReads.List()),
+		HotWrites:   stats.scale(stats.hotWrites.List()),
+		HotPrefixes: stats.scale(stats.hotPrefixes.List()),
+	}
+}
+
+// returns counts of the items multiplied by the sample rate, so they estimate counts of all accesses
+func (stats *AccessStats) scale(items []topK.Item) []KeyCount {
+	counts := make([]KeyCount, len(items))
+	for i, item := range items {
+		counts[i] = KeyCount{Key: item.Key, Count: uint64(item.Count) * uint64(stats.sampleRate)}
+	}
+	return counts
+}
@@ -2,29 +2,61 @@ package bloomFilter
 
 import (
 	"encoding/binary"
+	"errors"
 	"math"
 
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
 )
 
+// size of a block of the blocked bloom filter in bits, one cache line
+const BLOCK_BITS uint = 512
+
+// written instead of hash.VERSION_MARKER at the start of a serialized blocked bloom filter
+const BLOCKED_MARKER uint32 = hash.VERSION_MARKER - 1
+
+// In a blocked bloom filter, the first hash picks a block of BLOCK_BITS bits and all k bits of an element are set in it,
+// so a lookup reads only one cache line, at the cost of a slightly higher false positive rate
 type BloomFilter struct {
-	bitset   []byte
-	k, m     uint
-	hashFunc []hash.HashWithSeed
+	bitset  []byte
+	k, m    uint
+	hashes  hash.Family
+	blocked bool
 }
 
 func NewBf(n int, p float64) *BloomFilter {
 	m := calculateM(n, p)
-	k := calculateK(n, m)
+	return newBf(m, calculateK(n, m), false)
+}
+
+func NewBlockedBf(n int, p float64) *BloomFilter {
+	m := calculateM(n, p)
+	return newBf(m, calculateK(n, m), true)
+}
+
+// makes a filter which uses bitsPerKey bits for each of n elements
+func NewBfBitsPerKey(n int, bitsPerKey float64, blocked bool) *BloomFilter {
+	m := uint(math.Ceil(float64(n) * bitsPerKey))
+	return newBf(m, calculateK(n, m), blocked)
+}
 
+func newBf(m uint, k uint, blocked bool) *BloomFilter {
+	if blocked {
+		// the bitset is made of whole blocks
+		m = (m + BLOCK_BITS - 1) / BLOCK_BITS * BLOCK_BITS
+	}
 	return &BloomFilter{
-		bitset:   make([]byte, m/8+1),
-		k:        k,
-		m:        m,
-		hashFunc: hash.CreateHashFunctions(k),
+		bitset:  make([]byte, m/8+1),
+		k:       k,
+		m:       m,
+		hashes:  hash.NewFamily(k),
+		blocked: blocked,
 	}
 }
 
+func (b *BloomFilter) IsBlocked() bool {
+	return b.blocked
+}
+
 func calculateM(expectedElements int, falsePositiveRate float64) uint {
 	return uint(math.Ceil(float64(expectedElements) * math.Abs(math.Log(falsePositiveRate)) / math.Pow(math.Log(2), float64(2))))
 }
@@ -34,11 +66,9 @@ func calculateK(expectedElements int, m uint) uint {
 }
 
 func (b *BloomFilter) Find(s string) bool {
-	for _, fn := range b.hashFunc {
-		var hash uint = uint(fn.Hash([]byte(s)))
-		compressed := hash % b.m
-		index := compressed / 8
-		mask := byte(1 << (7 - (compressed % 8)))
+	for _, bit := range b.bits(s) {
+		index := bit / 8
+		mask := byte(1 << (7 - (bit % 8)))
 		if b.bitset[index]&mask == 0 {
 			return false
 		}
@@ -47,47 +77,116 @@ func (b *BloomFilter) Find(s string) bool {
 }
 
 func (b *BloomFilter) Insert(s string) {
-	for _, fn := range b.hashFunc {
-		var hash uint = uint(fn.Hash([]byte(s)))
-		compressed := hash % b.m
-		index := compressed / 8
-		mask := byte(1 << (7 - (compressed % 8)))
+	for _, bit := range b.bits(s) {
+		index := bit / 8
+		mask := byte(1 << (7 - (bit % 8)))
 		b.bitset[index] = b.bitset[index] | mask
 	}
 }
 
+// Adds all elements of the other filter to this one
+// Filters can be merged only if they have the same size, layout and hash functions
+func (b *BloomFilter) Merge(other *BloomFilter) error {
+	if b.m != other.m || b.k != other.k || b.blocked != other.blocked || !hash.SameFamily(b.hashes, other.hashes) {
+		return errors.New("bloom filters have different parameters and can't be merged")
+	}
+	for i := range b.bitset {
+		b.bitset[i] |= other.bitset[i]
+	}
+	return nil
+}
+
+// returns positions of the k bits of the element
+func (b *BloomFilter) bits(s string) []uint {
+	hashes := b.hashes.Hashes([]byte(s))
+	bits := make([]uint, len(hashes))
+	if !b.blocked {
+		for i, hash := range hashes {
+			bits[i] = uint(hash % uint64(b.m))
+		}
+		return bits
+	}
+
+	// the block is picked by the upper bits of the first hash, so it doesn't decide the bits inside the block
+	block := uint((hashes[0] >> 32) % uint64(b.m/BLOCK_BITS))
+	for i, hash := range hashes {
+		bits[i] = block*BLOCK_BITS + uint(hash%uint64(BLOCK_BITS))
+	}
+	return bits
+}
+
+// marker (says if the filter is blocked), version of the hash family, bitset length, number of hash functions, parameters of the hash family, bitset
 func (b *BloomFilter) Serialize() []byte {
+	familyBytes := b.hashes.Serialize()
 
-	var size int = 4 + 4 + int(b.k)*4 + int(b.m)/8 + 1
+	var size int = 4*4 + len(familyBytes) + int(b.m)/8 + 1
 	bytes := make([]byte, size)
 
-	binary.BigEndian.PutUint32(bytes[0:4], uint32(b.m)) //Bitset length
-	binary.BigEndian.PutUint32(bytes[4:8], uint32(b.k)) //Number of hash functions
-
-	i := 0
-	for _, fn := range b.hashFunc {
-		copy(bytes[8+4*i:12+4*i], fn.Seed) //Hash seeds
-		i++
+	if b.blocked {
+		binary.BigEndian.PutUint32(bytes[0:4], BLOCKED_MARKER)
+	} else {
+		binary.BigEndian.PutUint32(bytes[0:4], hash.VERSION_MARKER)
 	}
-
-	copy(bytes[4+4+int(b.k)*4:], b.bitset) //bitset
+	binary.BigEndian.PutUint32(bytes[4:8], b.hashes.Version())
+	binary.BigEndian.PutUint32(bytes[8:12], uint32(b.m))  //Bitset length
+	binary.BigEndian.PutUint32(bytes[12:16], uint32(b.k)) //Number of hash functions
+	copy(bytes[16:], familyBytes)                         //Hash seeds
+	copy(bytes[16+len(familyBytes):], b.bitset)           //bitset
 
 	return bytes
 }
 
+// Reads both the current format and the old one, which starts with the bitset length and always uses md5 hashes
+// Returns nil if the hash family is unknown
 func Deserialize(bytes []byte) *BloomFilter {
+	bf, _ := Read(bytes)
+	return bf
+}
 
-	m := uint(binary.BigEndian.Uint32(bytes[0:4]))
-	k := uint(binary.BigEndian.Uint32(bytes[4:8]))
+// Reads the filter from the start of the bytes, returns the filter and the number of bytes it takes
+// The bitset is copied, so changing the filter doesn't change the bytes it was read from
+// Returns nil if the hash family is unknown, there are not enough bytes, or the bitset length or number of hashes can't be used
+func Read(bytes []byte) (*BloomFilter, int) {
+	var headerSize int = 0
+	version := hash.MD5_SEEDED
+	if len(bytes) < 4 {
+		return nil, 0
+	}
+	marker := binary.BigEndian.Uint32(bytes[0:4])
+	blocked := marker == BLOCKED_MARKER
+	if marker == hash.VERSION_MARKER || blocked {
+		if len(bytes) < 8 {
+			return nil, 0
+		}
+		version = binary.BigEndian.Uint32(bytes[4:8])
+		headerSize = 8
+	}
 
-	hashFunc := make([]hash.HashWithSeed, k)
-	for i := 0; i < int(k); i++ {
-		hashFunc[i] = hash.HashWithSeed{Seed: bytes[8+i*4 : 12+i*4]}
+	if len(bytes) < headerSize+8 {
+		return nil, 0
 	}
-	return &BloomFilter{
-		m:        m,
-		k:        k,
-		hashFunc: hashFunc,
-		bitset:   bytes[8+k*4:],
+	m := uint(binary.BigEndian.Uint32(bytes[headerSize : headerSize+4]))
+	k := uint(binary.BigEndian.Uint32(bytes[headerSize+4 : headerSize+8]))
+	headerSize += 8
+	// bits are picked modulo m, and blocks modulo the number of whole blocks
+	if m == 0 || k == 0 || (blocked && m%BLOCK_BITS != 0) {
+		return nil, 0
 	}
+
+	hashes, read, err := hash.DeserializeFamily(version, k, bytes[headerSize:])
+	if err != nil {
+		return nil, 0
+	}
+	var bitsetStart int = headerSize + read
+	var bitsetEnd int = bitsetStart + int(m)/8 + 1
+	if bitsetEnd > len(bytes) {
+		return nil, 0
+	}
+	return &BloomFilter{
+		m:       m,
+		k:       k,
+		hashes:  hashes,
+		bitset:  append([]byte{}, bytes[bitsetStart:bitsetEnd]...),
+		blocked: blocked,
+	}, bitsetEnd
 }
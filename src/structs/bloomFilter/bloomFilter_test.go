@@ -0,0 +1,102 @@
+package bloomFilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
+)
+
+// Sketches are read from values the engine keeps in the memtable and cache,
+// so merging filters read from them must not change those values
+func TestMergeLeavesSourcesUnchanged(t *testing.T) {
+	a, b := NewBf(100, 0.01), NewBf(100, 0.01)
+	a.Insert("a")
+	b.Insert("b")
+	storedA, storedB := a.Serialize(), b.Serialize()
+	savedA, savedB := append([]byte{}, storedA...), append([]byte{}, storedB...)
+
+	result := Deserialize(storedA)
+	if err := result.Merge(Deserialize(storedB)); err != nil {
+		t.Fatal(err)
+	}
+	result.Insert("c")
+	if !result.Find("a") || !result.Find("b") || !result.Find("c") {
+		t.Fatal("merged filter is missing elements")
+	}
+	if !bytes.Equal(storedA, savedA) || !bytes.Equal(storedB, savedB) {
+		t.Fatal("merging changed the serialized source filters")
+	}
+	if Deserialize(storedA).Find("b") {
+		t.Fatal("source filter holds an element of the other filter")
+	}
+}
+
+// returns a filter with the passed hash family, holding keys key00000, key00002...
+func benchmarkFilter(n int, family uint32) *BloomFilter {
+	bf := NewBf(n, 0.001)
+	if family == hash.MD5_SEEDED {
+		functions := make([]hash.HashWithSeed, bf.k)
+		for i := range functions {
+			functions[i] = hash.HashWithSeed{Seed: []byte{byte(i), 1, 2, 3}}
+		}
+		bf.hashes = &hash.SeededFamily{Functions: functions}
+	}
+	for i := 0; i < n; i++ {
+		bf.Insert(fmt.Sprintf("key%05d", 2*i))
+	}
+	return bf
+}
+
+// Lookups of keys that are in the filter and keys that aren't, with each hash family
+func BenchmarkFind(b *testing.B) {
+	const n = 100000
+	for _, family := range []struct {
+		name    string
+		version uint32
+	}{{"XXHASH_DOUBLE", hash.XXHASH_DOUBLE}, {"MD5_SEEDED", hash.MD5_SEEDED}} {
+		b.Run(family.name, func(b *testing.B) {
+			bf := benchmarkFilter(n, family.version)
+			keys := make([]string, 2*n)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key%05d", i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bf.Find(keys[i%len(keys)])
+			}
+		})
+	}
+}
+
+// Filters are read from sstables and sketch values, so damaged bytes must be rejected, not make reads or lookups panic
+func TestReadRejectsDamagedFilters(t *testing.T) {
+	plain := NewBf(100, 0.01).Serialize()
+	blocked := NewBlockedBf(100, 0.01).Serialize()
+	for _, serialized := range [][]byte{plain, blocked} {
+		for i := 0; i < len(serialized); i++ {
+			if bf, _ := Read(serialized[:i]); bf != nil {
+				t.Fatalf("filter was read from the first %d of %d bytes", i, len(serialized))
+			}
+		}
+	}
+
+	withHeader := func(serialized []byte, m uint32, k uint32) []byte {
+		changed := append([]byte{}, serialized...)
+		binary.BigEndian.PutUint32(changed[8:12], m)
+		binary.BigEndian.PutUint32(changed[12:16], k)
+		return changed
+	}
+	for name, serialized := range map[string][]byte{
+		"no bits":                          withHeader(plain, 0, 3),
+		"no hashes":                        withHeader(plain, 64, 0),
+		"blocked with less than one block": withHeader(blocked, uint32(BLOCK_BITS/2), 3),
+		"blocked with a partial block":     withHeader(blocked, uint32(BLOCK_BITS+8), 3),
+	} {
+		if bf, _ := Read(serialized); bf != nil {
+			t.Fatalf("%s: filter was read", name)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package cuckooFilter
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
+)
+
+// number of fingerprints in one bucket
+const BUCKET_SIZE uint64 = 4
+
+// max number of fingerprints moved to their other bucket while inserting an element
+const MAX_KICKS int = 500
+
+// max share of full slots for which inserts still succeed with a high probability
+const LOAD_FACTOR float64 = 0.95
+
+// Unlike the bloom filter, the cuckoo filter can delete elements
+// Each element is saved as a 16 bit fingerprint in one of its two buckets,
+// the other bucket is found from the bucket and fingerprint alone, so fingerprints can be moved without the element
+type CuckooFilter struct {
+	buckets    [][BUCKET_SIZE]uint16 // 0 marks an empty slot
+	numBuckets uint64                // power of 2, so the other bucket is found with xor
+	count      uint64                // number of saved fingerprints
+}
+
+// makes a filter for n elements
+func NewCf(n int) *CuckooFilter {
+	var numBuckets uint64 = 1
+	var needed uint64 = uint64(math.Ceil(float64(n) / float64(BUCKET_SIZE) / LOAD_FACTOR))
+	for numBuckets < needed {
+		numBuckets <<= 1
+	}
+	return &CuckooFilter{
+		buckets:    make([][BUCKET_SIZE]uint16, numBuckets),
+		numBuckets: numBuckets,
+	}
+}
+
+// returns the fingerprint and the first bucket of the element
+func (cf *CuckooFilter) fingerprintAndIndex(s string) (uint16, uint64) {
+	h := hash.XXHash64([]byte(s), hash.DEFAULT_SEED)
+	fingerprint := uint16(h >> 48)
+	if fingerprint == 0 {
+		fingerprint = 1
+	}
+	return fingerprint, h & (cf.numBuckets - 1)
+}
+
+// returns the other bucket of a fingerprint in the passed bucket
+func (cf *CuckooFilter) altIndex(index uint64, fingerprint uint16) uint64 {
+	var data []byte = make([]byte, 2)
+	binary.BigEndian.PutUint16(data, fingerprint)
+	return (index ^ hash.XXHash64(data, hash.DEFAULT_SEED)) & (cf.numBuckets - 1)
+}
+
+// puts the fingerprint in a free slot of the bucket, returns false if the bucket is full
+func (cf *CuckooFilter) put(index uint64, fingerprint uint16) bool {
+	for i := range cf.buckets[index] {
+		if cf.buckets[index][i] == 0 {
+			cf.buckets[index][i] = fingerprint
+			return true
+		}
+	}
+	return false
+}
+
+// Inserts the element, returns false if the filter is too full to insert it
+// The filter isn't changed if the insert fails
+func (cf *CuckooFilter) Insert(s string) bool {
+	fingerprint, i1 := cf.fingerprintAndIndex(s)
+	i2 := cf.altIndex(i1, fingerprint)
+	if cf.put(i1, fingerprint) || cf.put(i2, fingerprint) {
+		cf.count++
+		return true
+	}
+
+	// fingerprints are kicked to their other bucket until one finds a free slot
+	// the kicks are remembered, so they can be undone if there is no free slot
+	type kick struct {
+		index uint64
+		slot  uint64
+	}
+	var kicks []kick = make([]kick, 0, MAX_KICKS)
+	var index uint64 = i1
+	if rand.Intn(2) == 1 {
+		index = i2
+	}
+	for n := 0; n < MAX_KICKS; n++ {
+		slot := uint64(rand.Intn(int(BUCKET_SIZE)))
+		fingerprint, cf.buckets[index][slot] = cf.buckets[index][slot], fingerprint
+		kicks = append(kicks, kick{index: index, slot: slot})
+		index = cf.altIndex(index, fingerprint)
+		if cf.put(index, fingerprint) {
+			cf.count++
+			return true
+		}
+	}
+
+	for n := len(kicks) - 1; n >= 0; n-- {
+		fingerprint, cf.buckets[kicks[n].index][kicks[n].slot] = cf.buckets[kicks[n].index][kicks[n].slot], fingerprint
+	}
+	return false
+}
+
+// Returns false if the element surely isn't in the filter
+func (cf *CuckooFilter) Lookup(s string) bool {
+	fingerprint, i1 := cf.fingerprintAndIndex(s)
+	i2 := cf.altIndex(i1, fingerprint)
+	for i := 0; i < int(BUCKET_SIZE); i++ {
+		if cf.buckets[i1][i] == fingerprint || cf.buckets[i2][i] == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// Deletes one copy of the element, returns false if it wasn't found
+// Only elements that were inserted should be deleted, otherwise an element with the same fingerprint may be lost
+func (cf *CuckooFilter) Delete(s string) bool {
+	fingerprint, i1 := cf.fingerprintAndIndex(s)
+	i2 := cf.altIndex(i1, fingerprint)
+	for _, index := range []uint64{i1, i2} {
+		for i := 0; i < int(BUCKET_SIZE); i++ {
+			if cf.buckets[index][i] == fingerprint {
+				cf.buckets[index][i] = 0
+				cf.count--
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the number of elements in the filter
+func (cf *CuckooFilter) Count() uint64 {
+	return cf.count
+}
+
+// Returns a byte array representing a serialized cuckoo filter
+// Number of buckets, count, fingerprints of all buckets
+func (cf *CuckooFilter) Serialize() []byte {
+	var data []byte = make([]byte, 16+cf.numBuckets*BUCKET_SIZE*2)
+	binary.BigEndian.PutUint64(data[0:8], cf.numBuckets)
+	binary.BigEndian.PutUint64(data[8:16], cf.count)
+	var offset int = 16
+	for i := uint64(0); i < cf.numBuckets; i++ {
+		for j := 0; j < int(BUCKET_SIZE); j++ {
+			binary.BigEndian.PutUint16(data[offset:offset+2], cf.buckets[i][j])
+			offset += 2
+		}
+	}
+	return data
+}
+
+// Creates a cuckoo filter from serialized data
+// Returns nil if the data isn't a valid cuckoo filter
+func Deserialize(data []byte) *CuckooFilter {
+	if len(data) < 16 {
+		return nil
+	}
+	numBuckets := binary.BigEndian.Uint64(data[0:8])
+	count := binary.BigEndian.Uint64(data[8:16])
+	// checked before the size is multiplied, so a damaged number of buckets can't overflow it
+	if numBuckets > uint64(len(data)-16)/(BUCKET_SIZE*2) {
+		return nil
+	}
+	if numBuckets == 0 || numBuckets&(numBuckets-1) != 0 || uint64(len(data)-16) != numBuckets*BUCKET_SIZE*2 {
+		return nil
+	}
+
+	cf := &CuckooFilter{
+		buckets:    make([][BUCKET_SIZE]uint16, numBuckets),
+		numBuckets: numBuckets,
+		count:      count,
+	}
+	var offset int = 16
+	for i := uint64(0); i < numBuckets; i++ {
+		for j := 0; j < int(BUCKET_SIZE); j++ {
+			cf.buckets[i][j] = binary.BigEndian.Uint16(data[offset : offset+2])
+			offset += 2
+		}
+	}
+	return cf
+}
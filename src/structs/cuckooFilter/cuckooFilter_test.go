@@ -0,0 +1,22 @@
+package cuckooFilter
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestDeserializeRejectsDamagedNumberOfBuckets(t *testing.T) {
+	// 1<<62 buckets times 8 bytes overflows to 0, which is the size of the empty data after the header
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[0:8], 1<<62)
+	if Deserialize(data) != nil {
+		t.Fatal("filter with a damaged number of buckets was read")
+	}
+
+	cf := NewCf(100)
+	cf.Insert("key")
+	read := Deserialize(cf.Serialize())
+	if read == nil || !read.Lookup("key") || read.Count() != 1 {
+		t.Fatal("serialized filter wasn't read back")
+	}
+}
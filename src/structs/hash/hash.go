@@ -1,11 +1,69 @@
 package hash
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/binary"
-	"time"
+	"errors"
+	"math"
 )
 
+// versions of hash families, they are saved with the serialized structures,
+// so the structures are always read with the same hash functions they were made with
+const (
+	MD5_SEEDED    uint32 = 1 // md5 of the data with a different seed for each hash function
+	XXHASH_DOUBLE uint32 = 2 // one xxHash64 of the data, k hashes are made from it with double hashing
+)
+
+// written in place of the first field of serialized structures that save the version of their hash family
+// structures serialized before versions existed don't start with it
+const VERSION_MARKER uint32 = math.MaxUint32
+
+// seed of the xxhash family, it is fixed so hashes are the same in every process
+const DEFAULT_SEED uint64 = 0
+
+// Family is a set of k hash functions used by the bloom filter and count min sketch
+type Family interface {
+	Version() uint32
+	K() uint
+	Hashes(data []byte) []uint64 // returns k hashes of the data
+	Serialize() []byte           // returns parameters of the family, without version and k
+}
+
+// returns the default family with k hash functions
+func NewFamily(k uint) Family {
+	return &DoubleHashFamily{k: k, seed: DEFAULT_SEED}
+}
+
+// returns true if both families give the same hashes for the same data
+func SameFamily(a Family, b Family) bool {
+	return a.Version() == b.Version() && a.K() == b.K() && bytes.Equal(a.Serialize(), b.Serialize())
+}
+
+// reads parameters of the family with the passed version, returns the family and number of bytes read
+func DeserializeFamily(version uint32, k uint, data []byte) (Family, int, error) {
+	switch version {
+	case MD5_SEEDED:
+		if len(data) < int(k)*4 {
+			return nil, 0, errors.New("not enough bytes for hash seeds")
+		}
+		functions := make([]HashWithSeed, k)
+		for i := 0; i < int(k); i++ {
+			seed := make([]byte, 4)
+			copy(seed, data[i*4:i*4+4])
+			functions[i] = HashWithSeed{Seed: seed}
+		}
+		return &SeededFamily{Functions: functions}, int(k) * 4, nil
+	case XXHASH_DOUBLE:
+		if len(data) < 8 {
+			return nil, 0, errors.New("not enough bytes for hash seed")
+		}
+		return &DoubleHashFamily{k: k, seed: binary.BigEndian.Uint64(data[0:8])}, 8, nil
+	default:
+		return nil, 0, errors.New("unknown hash family version")
+	}
+}
+
 type HashWithSeed struct {
 	Seed []byte
 }
@@ -16,14 +74,73 @@ func (h HashWithSeed) Hash(data []byte) uint64 {
 	return binary.BigEndian.Uint64(fn.Sum(nil))
 }
 
-func CreateHashFunctions(k uint) []HashWithSeed {
-	h := make([]HashWithSeed, k)
-	ts := uint(time.Now().Unix())
-	for i := uint(0); i < k; i++ {
-		seed := make([]byte, 4)
-		binary.BigEndian.PutUint32(seed, uint32(ts+i))
-		hfn := HashWithSeed{Seed: seed}
-		h[i] = hfn
+// SeededFamily computes md5 once for each of its functions
+// It is kept to read structures that were made with it, new structures use DoubleHashFamily
+type SeededFamily struct {
+	Functions []HashWithSeed
+}
+
+func (f *SeededFamily) Version() uint32 {
+	return MD5_SEEDED
+}
+
+func (f *SeededFamily) K() uint {
+	return uint(len(f.Functions))
+}
+
+func (f *SeededFamily) Hashes(data []byte) []uint64 {
+	hashes := make([]uint64, len(f.Functions))
+	for i, fn := range f.Functions {
+		hashes[i] = fn.Hash(data)
 	}
-	return h
+	return hashes
+}
+
+func (f *SeededFamily) Serialize() []byte {
+	bytes := make([]byte, 0, 4*len(f.Functions))
+	for _, fn := range f.Functions {
+		bytes = append(bytes, fn.Seed...)
+	}
+	return bytes
+}
+
+// DoubleHashFamily hashes the data only once and makes k hashes from it (Kirsch-Mitzenmacher):
+// hash i = h1 + i*h2, where h1 is xxHash64 of the data and h2 is made by mixing the bits of h1
+type DoubleHashFamily struct {
+	k    uint
+	seed uint64
+}
+
+func (f *DoubleHashFamily) Version() uint32 {
+	return XXHASH_DOUBLE
+}
+
+func (f *DoubleHashFamily) K() uint {
+	return f.k
+}
+
+func (f *DoubleHashFamily) Hashes(data []byte) []uint64 {
+	h1 := XXHash64(data, f.seed)
+	h2 := mix(h1) | 1 // odd, so the hashes don't repeat for a table size that is a power of 2
+	hashes := make([]uint64, f.k)
+	for i := uint(0); i < f.k; i++ {
+		hashes[i] = h1 + uint64(i)*h2
+	}
+	return hashes
+}
+
+func (f *DoubleHashFamily) Serialize() []byte {
+	bytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(bytes, f.seed)
+	return bytes
+}
+
+// finalizer of splitmix64, spreads every bit of x over the whole result
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
 }
@@ -8,23 +8,34 @@ import (
 )
 
 type Iterator interface {
-	Next() (*model.Record, error) //Should return a pointer to the next non-deleted record
+	Next() (*model.Record, error) //Should return a pointer to the next record (sstable and memtable iterators also return deleted records)
 	Stop()                        //Should close files and free resources
 }
 
+// ScanStats counts the sstables a prefix or range iterator had to read, and the ones it could skip
+type ScanStats struct {
+	TablesRead            int
+	TablesSkippedByRange  int //The min and max key of the sstable show it has no keys the iterator needs
+	TablesSkippedByFilter int //The prefix bloom filter of the sstable shows it has no key with the prefix
+}
+
 // Iterator groups allow you to iterate using multiple iterators at the same time
 type IteratorGroup struct {
 	iterators     []Iterator
 	iteratorCount int
 	records       []*model.Record
+	keepDeleted   bool //If true, deleted records are returned as well
 }
 
 // Creates and initializes a new iterator group containing all the passed iterators
-func NewIteratorGroup(iterators []Iterator) (*IteratorGroup, error) {
+// Deleted records are returned by the group only if keepDeleted is true, which is needed by compaction -
+// - a deleted record still has to hide older versions of the record that are not in the group
+func NewIteratorGroup(iterators []Iterator, keepDeleted bool) (*IteratorGroup, error) {
 	var group IteratorGroup = IteratorGroup{
 		iterators:     iterators,
 		iteratorCount: len(iterators),
 		records:       make([]*model.Record, len(iterators)),
+		keepDeleted:   keepDeleted,
 	}
 
 	//Initialize record array to the first record of each iterator
@@ -40,8 +51,8 @@ func NewIteratorGroup(iterators []Iterator) (*IteratorGroup, error) {
 }
 
 // Returns a pointer to the next record in the iterator group
-// The next record is the record containing the smallest key from all the iterators in the group
-// Will never return a deleted record
+// The next record is the latest version of the smallest key from all the iterators in the group
+// Will never return a deleted record, unless the group keeps deleted records
 func (iterGroup *IteratorGroup) Next() (*model.Record, error) {
 	const EMPTY_KEY string = ""
 
@@ -124,7 +135,8 @@ func (iterGroup *IteratorGroup) Next() (*model.Record, error) {
 			return nil, err
 		}
 
-		if recordCopy != nil {
+		//If the latest version of the key is deleted, skip the key
+		if recordCopy != nil && (recordCopy.Tombstone == 0 || iterGroup.keepDeleted) {
 			return recordCopy, nil
 		}
 	}
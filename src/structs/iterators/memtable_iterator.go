@@ -8,42 +8,43 @@ import (
 )
 
 type MemtableIterator struct {
-	data          memtable.DataStructure
-	key_list      []string //Sorted list of keys in the memtable
-	key_count     int      //Number of keys
-	current_index int      //Current position of the iterator
+	records       []model.Record //Copies of the records in the memtable, sorted by key
+	current_index int            //Current position of the iterator
 }
 
 // Returns a pointer to a new memtable iterator as well as an error value
-// The iterator gets invalidated if the memtable data is changed
+// The records are copied when the iterator is made, so it isn't invalidated if the memtable data is changed later
 func NewMemtableIterator(table *memtable.Memtable) (*MemtableIterator, error) {
 	var iter *MemtableIterator = &MemtableIterator{}
-	iter.data = table.Data
-	iter.current_index = 0
-	iter.key_count = len(table.Keys)
-	iter.key_list = make([]string, iter.key_count)
-	copy(iter.key_list, table.Keys)
-	sort.Strings(iter.key_list)
+	var key_list []string = make([]string, len(table.Keys))
+	copy(key_list, table.Keys)
+	sort.Strings(key_list)
+
+	iter.records = make([]model.Record, 0, len(key_list))
+	for i, key := range key_list {
+		//Keys that were put more than once are in the list more than once
+		if i > 0 && key == key_list[i-1] {
+			continue
+		}
+		record, err := table.Data.Find(key)
+		if err != nil {
+			return nil, err
+		}
+		iter.records = append(iter.records, record)
+	}
 
 	return iter, nil
 }
 
-// Returns the next non-deleted record in the memtable
-// Will NEVER return a deleted record
-// If all non-deleted records have been iterated over, returns nil as the record pointer
+// Returns the next record in the memtable
+// Deleted records are returned as well, so they can hide older versions of the record in sstables
+// If all records have been iterated over, returns nil as the record pointer
 func (iter *MemtableIterator) Next() (*model.Record, error) {
-	for iter.current_index < iter.key_count {
-		record, err := iter.data.Find(iter.key_list[iter.current_index])
-		iter.current_index += 1
-		if err != nil {
-			return nil, err
-		}
-
-		if record.Tombstone == 0 {
-			return &record, nil
-		}
+	if iter.current_index == len(iter.records) {
+		return nil, nil
 	}
-	return nil, nil
+	iter.current_index += 1
+	return &iter.records[iter.current_index-1], nil
 }
 
 func (iter *MemtableIterator) Stop() {
@@ -11,6 +11,7 @@ type PrefixIterator struct {
 	iterGroup *IteratorGroup
 	record    *model.Record
 	prefix    string
+	stats     ScanStats
 }
 
 // Return a pointer to a new prefix iterator
@@ -18,6 +19,15 @@ type PrefixIterator struct {
 // The method PrefixIterator.Stop() MUST be called after creating a new iterator in order to free it's resources
 // An error is returned if it occurs during the creation of the iterator
 func NewPrefixIterator(prefix string, isSStableCompressed bool, compressionMap map[string]uint64) (*PrefixIterator, error) {
+	return newPrefixIterator(prefix, false, isSStableCompressed, compressionMap)
+}
+
+// Returns a prefix iterator that returns deleted records as well, so a deleted key can be told apart from a missing one
+func NewPrefixIteratorWithDeleted(prefix string, isSStableCompressed bool, compressionMap map[string]uint64) (*PrefixIterator, error) {
+	return newPrefixIterator(prefix, true, isSStableCompressed, compressionMap)
+}
+
+func newPrefixIterator(prefix string, keepDeleted bool, isSStableCompressed bool, compressionMap map[string]uint64) (*PrefixIterator, error) {
 	var prefixIter *PrefixIterator = &PrefixIterator{prefix: prefix}
 	var iterators []Iterator
 
@@ -29,13 +39,34 @@ func NewPrefixIterator(prefix string, isSStableCompressed bool, compressionMap m
 	//Loop through every sstable
 	//If it could contain records with the given prefix, create an iterator for it
 	for i := 0; i < len(allSStables); i++ {
+		var skip bool = false
 		if allSStables[i].MaxKey < prefix || (allSStables[i].MinKey > prefix && !strings.HasPrefix(allSStables[i].MinKey, prefix)) {
+			skip = true
+			prefixIter.stats.TablesSkippedByRange++
+		} else {
+			//Sstables that have a prefix filter can tell if they have no key with the prefix, without being read
+			singleFile, err := isSSTableInSingleFile(allSStables[i].Name)
+			if err != nil {
+				return nil, err
+			}
+			err = allSStables[i].LoadFilters(!singleFile)
+			if err != nil {
+				return nil, err
+			}
+			if !allSStables[i].MayContainPrefix(prefix) {
+				skip = true
+				prefixIter.stats.TablesSkippedByFilter++
+			}
+		}
+
+		if skip {
 			//If the sstable definitely contains no record with the given prefix, free it's resources
 			allSStables[i].Data.Close()
 			allSStables[i].Index.Close()
 			allSStables[i].Summary.Close()
 			allSStables[i] = nil
 		} else {
+			prefixIter.stats.TablesRead++
 			//Otherwise, create an iterator for the sstable
 			sstableIter, err := NewSSTableIterator(allSStables[i], isSStableCompressed, compressionMap)
 			if err != nil {
@@ -56,7 +87,7 @@ func NewPrefixIterator(prefix string, isSStableCompressed bool, compressionMap m
 	}
 
 	//Group up all the sstable and memtable iterators
-	iterGroup, err := NewIteratorGroup(iterators)
+	iterGroup, err := NewIteratorGroup(iterators, keepDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +110,7 @@ func NewPrefixIterator(prefix string, isSStableCompressed bool, compressionMap m
 }
 
 // Returns a pointer to the next non-deleted record with the given prefix, also returns an error
-// Will NEVER return a deleted record
+// Will NEVER return a deleted record, unless the iterator was made by NewPrefixIteratorWithDeleted
 // If all non-deleted records with the given prefix have been iterated over, returns nil as the record pointer
 // If any errors occur, the returned record is nil and the error is returned
 func (prefixIter *PrefixIterator) Next() (*model.Record, error) {
@@ -106,6 +137,11 @@ func (prefixIter *PrefixIterator) Next() (*model.Record, error) {
 	return retRecord, nil
 }
 
+// Returns how many sstables the iterator reads, and how many it skipped
+func (prefixIter *PrefixIterator) Stats() ScanStats {
+	return prefixIter.stats
+}
+
 // Frees the memory and closes the files used by the prefix iterator
 func (prefixIter *PrefixIterator) Stop() {
 	prefixIter.iterGroup.Stop()
@@ -10,6 +10,7 @@ type RangeIterator struct {
 	record    *model.Record
 	rangeMin  string
 	rangeMax  string
+	stats     ScanStats
 }
 
 // Return a pointer to a new range iterator
@@ -29,11 +30,14 @@ func NewRangeIterator(minKey string, maxKey string, isSStableCompressed bool, co
 	//If it contains records in the given range, create an iterator to it
 	for i := 0; i < len(allSStables); i++ {
 		if allSStables[i].MinKey <= maxKey && allSStables[i].MaxKey >= minKey {
+			rangeIter.stats.TablesRead++
 			sstableIter, err := NewSSTableIterator(allSStables[i], isSStableCompressed, compressionMap)
 			if err != nil {
 				return nil, err
 			}
 			iterators = append(iterators, sstableIter)
+		} else {
+			rangeIter.stats.TablesSkippedByRange++
 		}
 	}
 
@@ -48,7 +52,7 @@ func NewRangeIterator(minKey string, maxKey string, isSStableCompressed bool, co
 	}
 
 	//Group up all the sstable and memtable iterators
-	iterGroup, err := NewIteratorGroup(iterators)
+	iterGroup, err := NewIteratorGroup(iterators, false)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +102,11 @@ func (rangeIter *RangeIterator) Next() (*model.Record, error) {
 	return retRecord, nil
 }
 
+// Returns how many sstables the iterator reads, and how many it skipped
+func (rangeIter *RangeIterator) Stats() ScanStats {
+	return rangeIter.stats
+}
+
 // Frees the memory and closes the files used by the range iterator
 func (rangeIter *RangeIterator) Stop() {
 	rangeIter.iterGroup.Stop()
@@ -123,28 +123,25 @@ func NewSSTableIterator(table *sstable.SSTable, isCompressed bool, compressionMa
 	return iterator, nil
 }
 
-// Returns a pointer to the next non-deleted record, also returns an error
-// Will NEVER return a deleted record
-// If all non-deleted records have been iterated over, returns nil as the record pointer
+// Returns a pointer to the next record, also returns an error
+// Deleted records are returned as well, so they can hide older versions of the record in other sstables
+// If all records have been iterated over, returns nil as the record pointer
 // If any errors occur, the returned record is nil and the error is returned
 func (iter *SSTableIterator) Next() (*model.Record, error) {
+	if iter.current_offset >= iter.end_offset {
+		return nil, nil
+	}
 
-	for iter.current_offset < iter.end_offset {
-		record_p, _, err := model.Deserialize(iter.data, iter.isSSTableCompressed, iter.CompressionMap)
-		if err != nil {
-			return nil, err
-		}
-		iter.current_offset, err = iter.data.Seek(0, io.SeekCurrent)
-		if err != nil {
-			return nil, err
-		}
-
-		if record_p.Tombstone == 0 {
-			return record_p, nil
-		}
+	record_p, _, err := model.Deserialize(iter.data, iter.isSSTableCompressed, iter.CompressionMap)
+	if err != nil {
+		return nil, err
+	}
+	iter.current_offset, err = iter.data.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	return record_p, nil
 }
 
 // Closes the data file that was being iterated over
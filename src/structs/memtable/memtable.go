@@ -74,8 +74,17 @@ func find(key string) (model.Record, error) {
 	return Memtables.Collection[Memtables.current].Data.Find(key)
 }
 
+// Returns memtables from the current one to the oldest one, so newer versions of a key are found first
+func newestFirst() []*Memtable {
+	memtables := make([]*Memtable, Memtables.size)
+	for i := uint(0); i < Memtables.size; i++ {
+		memtables[i] = Memtables.Collection[(Memtables.current+Memtables.size-i)%Memtables.size]
+	}
+	return memtables
+}
+
 func findAndDelete(key string) bool {
-	for _, memtable := range Memtables.Collection {
+	for _, memtable := range newestFirst() {
 		_, err := memtable.Data.Find(key)
 		if err == nil {
 			memtable.delete(key)
@@ -148,7 +157,7 @@ func Put(key string, value []byte, timestamp uint64, tombstone byte) (bool, bool
 }
 
 func Get(key string) (model.Record, error) {
-	for _, memtable := range Memtables.Collection {
+	for _, memtable := range newestFirst() {
 		record, err := memtable.Data.Find(key)
 		if err == nil {
 			return record, nil
@@ -160,7 +169,11 @@ func Get(key string) (model.Record, error) {
 func (memtable *Memtable) getRecordsToFlush() []*model.Record {
 	var records []*model.Record
 	sort.Strings(memtable.Keys)
-	for _, key := range memtable.Keys {
+	for i, key := range memtable.Keys {
+		//Keys that were put more than once are in the list more than once, but are flushed only once
+		if i > 0 && key == memtable.Keys[i-1] {
+			continue
+		}
 		record, err := find(key)
 		if err == nil {
 			records = append(records, &record)
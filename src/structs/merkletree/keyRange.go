@@ -0,0 +1,137 @@
+package merkletree
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	gohash "hash"
+
+	kvhash "github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
+)
+
+const MAX_KEY_RANGE_DEPTH = 20
+
+// KeyRangeTree summarizes all records of an engine, not a single sstable
+// Keys are spread over 1<<depth leaves by their hash, so two engines with the same records have the same trees
+// no matter how the records are split into sstables, and the leaves of a differing subtree hold the keys that differ
+// A leaf is the hash of hashes of its records in key order, nodes are kept in heap order: the root is node 1 and node i has children 2i and 2i+1
+type KeyRangeTree struct {
+	depth   uint8
+	nodes   [][32]byte
+	hashers []gohash.Hash // hashers of leaves, until the tree is finished
+	lastKey string
+	started bool
+}
+
+// Records have to be added in key order, and then the tree has to be finished
+func NewKeyRangeTree(depth uint8) (*KeyRangeTree, error) {
+	if depth > MAX_KEY_RANGE_DEPTH {
+		return nil, errors.New("depth of the key range tree is too big")
+	}
+	tree := &KeyRangeTree{depth: depth, nodes: make([][32]byte, 2<<depth), hashers: make([]gohash.Hash, 1<<depth)}
+	for i := range tree.hashers {
+		tree.hashers[i] = sha256.New()
+	}
+	return tree, nil
+}
+
+func (tree *KeyRangeTree) Depth() uint8 {
+	return tree.depth
+}
+
+// Returns the index of the leaf, from 0, that holds the key
+func (tree *KeyRangeTree) Leaf(key string) int {
+	if tree.depth == 0 {
+		return 0
+	}
+	return int(kvhash.XXHash64([]byte(key), kvhash.DEFAULT_SEED) >> (64 - tree.depth))
+}
+
+// Returns the hash of a record, records with the same key and value have the same hash
+// A deleted record hashes differently from a record with an empty value, so deletes are synced as well,
+// its value isn't hashed, since a deleted record may keep the value it had
+// Timestamps aren't hashed, so the same record written at different times isn't copied again
+func RecordHash(key string, value []byte, deleted bool) [32]byte {
+	content := binary.BigEndian.AppendUint64(nil, uint64(len(key)))
+	content = append(content, key...)
+	if deleted {
+		return sha256.Sum256(append(content, 1))
+	}
+	content = append(content, 0)
+	return sha256.Sum256(append(content, value...))
+}
+
+func (tree *KeyRangeTree) Add(key string, value []byte, deleted bool) error {
+	if tree.hashers == nil {
+		return errors.New("key range tree is already finished")
+	}
+	if tree.started && key <= tree.lastKey {
+		return errors.New("records must be added to the key range tree in key order")
+	}
+	tree.started = true
+	tree.lastKey = key
+	recordHash := RecordHash(key, value, deleted)
+	tree.hashers[tree.Leaf(key)].Write(recordHash[:])
+	return nil
+}
+
+// Computes hashes of all nodes, records can't be added after it
+func (tree *KeyRangeTree) Finish() {
+	if tree.hashers == nil {
+		return
+	}
+	leaves := 1 << tree.depth
+	for i, hasher := range tree.hashers {
+		copy(tree.nodes[leaves+i][:], hasher.Sum(nil))
+	}
+	for i := leaves - 1; i >= 1; i-- {
+		tree.nodes[i] = sha256.Sum256(append(tree.nodes[2*i][:], tree.nodes[2*i+1][:]...))
+	}
+	tree.hashers = nil
+}
+
+func (tree *KeyRangeTree) Root() [32]byte {
+	return tree.nodes[1]
+}
+
+// Returns the hash of the node with the passed heap index, or an error if there is no such node
+func (tree *KeyRangeTree) Node(id int) ([32]byte, error) {
+	if id < 1 || id >= len(tree.nodes) {
+		return [32]byte{}, errors.New("node does not exist in the key range tree")
+	}
+	return tree.nodes[id], nil
+}
+
+// Returns true if the node with the passed heap index is a leaf
+func (tree *KeyRangeTree) IsLeaf(id int) bool {
+	return id >= 1<<tree.depth
+}
+
+// Returns indexes of leaves whose hashes differ from the other tree's, walking only the subtrees whose roots differ
+// nodes returns hashes of the other tree's nodes with the passed heap indexes, it is called once per level
+func (tree *KeyRangeTree) Diff(nodes func(ids []int) ([][32]byte, error)) ([]int, error) {
+	var leaves []int
+	ids := []int{1}
+	for len(ids) > 0 {
+		other, err := nodes(ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(other) != len(ids) {
+			return nil, errors.New("wrong number of nodes of the other key range tree")
+		}
+		var next []int
+		for i, id := range ids {
+			if tree.nodes[id] == other[i] {
+				continue
+			}
+			if tree.IsLeaf(id) {
+				leaves = append(leaves, id-1<<tree.depth)
+			} else {
+				next = append(next, 2*id, 2*id+1)
+			}
+		}
+		ids = next
+	}
+	return leaves, nil
+}
@@ -1,49 +1,178 @@
 package merkletree
 
 import (
+	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
+
+	kvhash "github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
+)
+
+// HashType is the hash a tree is made with, and whether leaves and inner nodes are hashed differently
+type HashType uint8
+
+// Trees are saved with their hash type, so trees made without domain separation are still read and checked as they were made
+const (
+	SHA1   HashType = 1 // sstables written before the hash was configurable have sha1 trees
+	SHA256 HashType = 2
+	// as in RFC 6962, leaves are hashed with the prefix 0x00 and inner nodes with 0x01,
+	// so an inner node can't be passed off as a leaf
+	SHA1_SEPARATED   HashType = 3
+	SHA256_SEPARATED HashType = 4
+)
+
+const DEFAULT_HASH = SHA256_SEPARATED
+
+// prefixes of hashed content of leaves and inner nodes in trees with domain separation
+const (
+	LEAF_PREFIX  byte = 0x00
+	INNER_PREFIX byte = 0x01
 )
 
+// version of the tree format, saved after kvhash.VERSION_MARKER
+// trees saved before it are sha1 node hashes without a header, and are read as legacy trees
+const MERKLE_VERSION uint32 = 1
+
+func ParseHashType(name string) (HashType, error) {
+	switch name {
+	case "sha256", "":
+		return SHA256_SEPARATED, nil
+	case "sha1":
+		return SHA1_SEPARATED, nil
+	default:
+		return 0, errors.New("unknown merkle tree hash")
+	}
+}
+
+func (hashType HashType) isValid() bool {
+	return hashType >= SHA1 && hashType <= SHA256_SEPARATED
+}
+
+func (hashType HashType) isSHA1() bool {
+	return hashType == SHA1 || hashType == SHA1_SEPARATED
+}
+
+func (hashType HashType) isSeparated() bool {
+	return hashType == SHA1_SEPARATED || hashType == SHA256_SEPARATED
+}
+
+func (hashType HashType) String() string {
+	name := "sha256"
+	if hashType.isSHA1() {
+		name = "sha1"
+	}
+	if !hashType.isSeparated() {
+		name += " without domain separation"
+	}
+	return name
+}
+
+func (hashType HashType) Sum(data []byte) []byte {
+	if hashType.isSHA1() {
+		sum := sha1.Sum(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func (hashType HashType) Size() int {
+	if hashType.isSHA1() {
+		return sha1.Size
+	}
+	return sha256.Size
+}
+
+// returns the hash of the inner node with the passed children
+func (hashType HashType) nodeHash(left []byte, right []byte) []byte {
+	var content []byte
+	if hashType.isSeparated() {
+		content = append(content, INNER_PREFIX)
+	}
+	content = append(content, left...)
+	return hashType.Sum(append(content, right...))
+}
+
 type MerkleTree struct {
-	Root   *Node
-	leaves []*Node
+	Root     *Node
+	leaves   []*Node
+	hashType HashType
+	legacy   bool // read from the old format, its leaves are hashes of records as they are saved in the sstable
 }
 
 type Node struct {
-	data   [20]byte
+	data   []byte
 	left   *Node
 	right  *Node
 	parent *Node
 }
 
-func NewTree(content [][]byte) (*MerkleTree, error) {
-	leaves, err := buildLeaves(content)
+func NewTree(hashType HashType, content [][]byte) (*MerkleTree, error) {
+	var hashes [][]byte
+	for _, c := range content {
+		hashes = append(hashes, LeafHash(hashType, c))
+	}
+	return NewTreeFromHashes(hashType, hashes)
+}
+
+// NewTreeFromHashes builds a tree from leaves that were already hashed with LeafHash
+// used when content is streamed, so it doesn't have to be kept in memory until the tree is built
+func NewTreeFromHashes(hashType HashType, hashes [][]byte) (*MerkleTree, error) {
+	leaves, err := leavesFromHashes(hashType, hashes)
 
 	if err != nil {
 		return nil, err
 	}
 
-	root := buildTree(leaves)
+	root := buildTree(hashType, leaves)
 	return &MerkleTree{
-		Root:   root,
-		leaves: leaves,
+		Root:     root,
+		leaves:   leaves,
+		hashType: hashType,
 	}, nil
 }
 
-// helper - builds leaves from bytes given
-// return value of function (leaves) is used for buildTree function to recursively make tree
+// returns hash which a leaf built from given content would have
+func LeafHash(hashType HashType, content []byte) []byte {
+	if !hashType.isSeparated() {
+		return hashType.Sum(content)
+	}
+	return hashType.Sum(append([]byte{LEAF_PREFIX}, content...))
+}
+
+func (merkle *MerkleTree) HashType() HashType {
+	return merkle.hashType
+}
+
+// Returns true if the tree was read from the old format, whose leaves are records as they are saved in the sstable
+// leaves of newer trees are records serialized without compression, so they can be checked without the compression dictionary
+func (merkle *MerkleTree) IsLegacy() bool {
+	return merkle.legacy
+}
+
+func (merkle *MerkleTree) RootHash() []byte {
+	return merkle.Root.data
+}
+
+// Returns the number of leaves, with the one added to make their number even
+func (merkle *MerkleTree) Leaves() int {
+	return len(merkle.leaves)
+}
+
+// helper - makes leaves from already hashed content
 // also returns error if there is no content for tree building
-func buildLeaves(content [][]byte) ([]*Node, error) {
+func leavesFromHashes(hashType HashType, hashes [][]byte) ([]*Node, error) {
 	var leaves []*Node
 
-	if len(content) == 0 {
+	if len(hashes) == 0 {
 		return nil, errors.New("can't build merkle tree if there is no any content")
 	}
 
-	for _, c := range content {
+	for _, h := range hashes {
 		leaves = append(leaves, &Node{
-			data:   hash(c),
+			data:   h,
 			left:   nil,
 			right:  nil,
 			parent: nil,
@@ -51,18 +180,18 @@ func buildLeaves(content [][]byte) ([]*Node, error) {
 	}
 	// tree needs to be binary, so we add one more leaf if needed
 	if len(leaves)%2 != 0 {
-		leaves = addEmptyNode(leaves)
+		leaves = addEmptyNode(hashType, leaves)
 	}
 	return leaves, nil
 }
 
 // makes tree recursively, checks if empty node needs to be added
 // this function returns root of built merkle tree
-func buildTree(nodes []*Node) *Node {
+func buildTree(hashType HashType, nodes []*Node) *Node {
 	length := len(nodes)
 
 	if length%2 == 1 {
-		nodes = addEmptyNode(nodes)
+		nodes = addEmptyNode(hashType, nodes)
 	}
 
 	var nodeList []*Node
@@ -70,7 +199,7 @@ func buildTree(nodes []*Node) *Node {
 		firstNode := nodes[i]
 		secondNode := nodes[i+1]
 		newNode := Node{
-			data:   hash(append(firstNode.data[:], secondNode.data[:]...)),
+			data:   hashType.nodeHash(firstNode.data, secondNode.data),
 			left:   firstNode,
 			right:  secondNode,
 			parent: nil,
@@ -81,7 +210,7 @@ func buildTree(nodes []*Node) *Node {
 	}
 
 	if len(nodeList) > 1 {
-		return buildTree(nodeList)
+		return buildTree(hashType, nodeList)
 	}
 	return nodeList[0]
 }
@@ -89,7 +218,16 @@ func buildTree(nodes []*Node) *Node {
 // VerifyTree function that checks if something changed
 // returns empty list if nothing has changed, if something has changed returns indices of leaves that have changed
 func (merkle *MerkleTree) VerifyTree(data [][]byte) ([]int, error) {
-	otherMerkle, err := NewTree(data)
+	var hashes [][]byte
+	for _, content := range data {
+		hashes = append(hashes, LeafHash(merkle.hashType, content))
+	}
+	return merkle.VerifyLeafHashes(hashes)
+}
+
+// VerifyLeafHashes does the same as VerifyTree, for content that was already hashed with LeafHash
+func (merkle *MerkleTree) VerifyLeafHashes(hashes [][]byte) ([]int, error) {
+	otherMerkle, err := NewTreeFromHashes(merkle.hashType, hashes)
 	var idxNodes []int
 	if err != nil {
 		return nil, errors.New("there is no sense to check if something changed in tree while content is empty")
@@ -99,15 +237,29 @@ func (merkle *MerkleTree) VerifyTree(data [][]byte) ([]int, error) {
 		return nil, errors.New("File is too much damaged")
 	}
 
-	if otherMerkle.Root.data != merkle.Root.data { // in case roots are not same, we are looking for leaves that have changed using dfs algorithm
+	if !bytes.Equal(otherMerkle.Root.data, merkle.Root.data) { // in case roots are not same, we are looking for leaves that have changed using dfs algorithm
 		dfs(merkle.Root, otherMerkle.Root, &idxNodes, 0)
 	}
 	return idxNodes, nil
 }
 
+// Equal returns true if all nodes of the trees are the same, unlike comparing roots it also finds damaged nodes under the root
+func (merkle *MerkleTree) Equal(other *MerkleTree) bool {
+	nodes, otherNodes := merkle.bfs(), other.bfs()
+	if len(nodes) != len(otherNodes) {
+		return false
+	}
+	for i := range nodes {
+		if !bytes.Equal(nodes[i], otherNodes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // dfs algorithm checks if nodes are same, if they are not same then we append their index to idxNodes if they are leaves(left and right children are nil)
 func dfs(node1 *Node, node2 *Node, idxNodes *[]int, idx int) {
-	if node1.data != node2.data {
+	if !bytes.Equal(node1.data, node2.data) {
 		if node1.left == nil && node1.right == nil { // if they are leaves then append their index
 			*idxNodes = append(*idxNodes, idx)
 		} else {
@@ -117,74 +269,171 @@ func dfs(node1 *Node, node2 *Node, idxNodes *[]int, idx int) {
 	}
 }
 
-// function that serializes merkle tree using bfs
-func (merkle *MerkleTree) Serialize() []byte {
-	nodeData := merkle.bfs()
-	var size int = 20 * len(nodeData)
-	bytes := make([]byte, size)
+// Proof holds hashes of siblings of the nodes on the path from a leaf to the root
+// a record belongs to a tree if hashing it with them, in order, gives the root of the tree
+type Proof struct {
+	HashType HashType
+	Index    int
+	Siblings [][]byte
+	Left     []bool // true if the sibling is the left child of its parent
+}
 
-	for i, data := range nodeData {
-		copy(bytes[i*20:(i+1)*20], data[:])
+// Proof returns the proof that the leaf with the passed index, counted from 0, belongs to the tree
+func (merkle *MerkleTree) Proof(index int) (*Proof, error) {
+	if index < 0 || index >= len(merkle.leaves) {
+		return nil, errors.New("leaf does not exist in the merkle tree")
 	}
-
-	return bytes
+	proof := &Proof{HashType: merkle.hashType, Index: index}
+	for node := merkle.leaves[index]; node.parent != nil; node = node.parent {
+		if node.parent.left == node {
+			proof.Siblings = append(proof.Siblings, node.parent.right.data)
+			proof.Left = append(proof.Left, false)
+		} else {
+			proof.Siblings = append(proof.Siblings, node.parent.left.data)
+			proof.Left = append(proof.Left, true)
+		}
+	}
+	return proof, nil
 }
 
-// Deserialize reconstructs a Merkle tree from serialized content
-// uses helper - createNode to emilinate redudancy
-func Deserialize(content []byte) *MerkleTree {
-	var nodes []*Node
-	var emptyHash [20]byte = hash([]byte{})
-	root := &Node{
-		data:   [20]byte(content[0:20]),
-		left:   nil,
-		right:  nil,
-		parent: nil,
+// VerifyProof returns true if the content, as it was hashed into a leaf, belongs to the tree with the passed hash, root and number of leaves
+// The hash is the one of the trusted tree, a proof made with another hash is rejected, so it can't pick a weaker one
+// The proof must have one sibling for every level under the root, on the sides the index of the leaf gives,
+// so it can't end early at an inner node or go on past the root
+func VerifyProof(hashType HashType, root []byte, leaves int, content []byte, proof *Proof) bool {
+	if proof == nil || !hashType.isValid() || proof.HashType != hashType || leaves < 2 || proof.Index < 0 || proof.Index >= leaves {
+		return false
 	}
+	depth := len(levelSizes(leaves)) - 1
+	if len(proof.Siblings) != depth || len(proof.Left) != depth {
+		return false
+	}
+	current := LeafHash(proof.HashType, content)
+	position := proof.Index
+	for i, sibling := range proof.Siblings {
+		if len(sibling) != proof.HashType.Size() || proof.Left[i] != (position%2 == 1) {
+			return false
+		}
+		if proof.Left[i] {
+			current = proof.HashType.nodeHash(sibling, current)
+		} else {
+			current = proof.HashType.nodeHash(current, sibling)
+		}
+		position /= 2
+	}
+	return bytes.Equal(current, root)
+}
 
-	nodes = append(nodes, root)
-	nodesToBuild := len(content)/20 - 1
-	i := 1
-
-	for nodesToBuild > 0 {
-		current := nodes[0]
+// function that serializes merkle tree using bfs
+// legacy trees are saved as they were read, others start with marker, version, hash type and number of leaves
+func (merkle *MerkleTree) Serialize() []byte {
+	var bytes []byte
+	if !merkle.legacy {
+		bytes = binary.BigEndian.AppendUint32(bytes, kvhash.VERSION_MARKER)
+		bytes = binary.BigEndian.AppendUint32(bytes, MERKLE_VERSION)
+		bytes = append(bytes, byte(merkle.hashType))
+		bytes = binary.BigEndian.AppendUint64(bytes, uint64(len(merkle.leaves)))
+	}
+	for _, data := range merkle.bfs() {
+		bytes = append(bytes, data...)
+	}
+	return bytes
+}
 
-		current.left = createNode(content, i, current)
-		i++
-		current.right = createNode(content, i, current)
-		i++
+// returns number of nodes on each level of a tree with the passed number of leaves, from the root
+// nodes added to make a level even are counted, but they have no children
+func levelSizes(leaves int) []int {
+	sizes := []int{leaves}
+	for count := leaves; count > 1; {
+		count = (count + 1) / 2
+		if count > 1 && count%2 == 1 {
+			sizes = append(sizes, count+1)
+		} else {
+			sizes = append(sizes, count)
+		}
+	}
+	for i, j := 0, len(sizes)-1; i < j; i, j = i+1, j-1 {
+		sizes[i], sizes[j] = sizes[j], sizes[i]
+	}
+	return sizes
+}
 
-		nodes = append(nodes, current.left)
+func countNodes(sizes []int) int {
+	var total int = 0
+	for _, size := range sizes {
+		total += size
+	}
+	return total
+}
 
-		// if node in tree was added as empty - then it wont have any children and we wont add it to list except in one case - if it is leaf
-		if (current.right.data == emptyHash && nodesToBuild == 2) || current.right.data != emptyHash {
-			nodes = append(nodes, current.right)
+// Deserialize reconstructs a Merkle tree from serialized content, it reads both formats
+// returns nil if the content isn't a valid tree
+func Deserialize(content []byte) *MerkleTree {
+	if len(content) >= 17 && binary.BigEndian.Uint32(content[0:4]) == kvhash.VERSION_MARKER {
+		hashType := HashType(content[8])
+		leaves := binary.BigEndian.Uint64(content[9:17])
+		if binary.BigEndian.Uint32(content[4:8]) != MERKLE_VERSION || !hashType.isValid() ||
+			leaves < 2 || leaves%2 != 0 || leaves > uint64(len(content)) {
+			return nil
 		}
+		return deserializeNodes(content[17:], hashType, levelSizes(int(leaves)), false)
+	}
 
-		nodes = nodes[1:]
-		nodesToBuild -= 2
+	// legacy trees don't save the number of leaves, it is the one that gives as many sha1 nodes as there are
+	if len(content) == 0 || len(content)%sha1.Size != 0 {
+		return nil
+	}
+	nodes := len(content) / sha1.Size
+	for leaves := 2; leaves <= nodes; leaves += 2 {
+		sizes := levelSizes(leaves)
+		if countNodes(sizes) == nodes {
+			tree := deserializeNodes(content, SHA1, sizes, true)
+			return tree
+		}
 	}
+	return nil
+}
 
+// builds the tree level by level, the first nodes of a level are the parents of the nodes of the next level
+func deserializeNodes(content []byte, hashType HashType, sizes []int, legacy bool) *MerkleTree {
+	size := hashType.Size()
+	if len(content) != countNodes(sizes)*size {
+		return nil
+	}
+	var offset int = 0
+	var previous []*Node
+	for _, count := range sizes {
+		level := make([]*Node, count)
+		for i := range level {
+			level[i] = &Node{data: content[offset : offset+size]}
+			offset += size
+			if i%2 == 1 {
+				parent := previous[i/2]
+				parent.left, parent.right = level[i-1], level[i]
+				level[i-1].parent, level[i].parent = parent, parent
+			}
+		}
+		previous = level
+	}
 	return &MerkleTree{
-		Root:   root,
-		leaves: nodes,
+		Root:     rootOf(previous[0]),
+		leaves:   previous,
+		hashType: hashType,
+		legacy:   legacy,
 	}
 }
 
-// helper - used in deserialization
-func createNode(content []byte, i int, parent *Node) *Node {
-	return &Node{
-		data:   [20]byte(content[20*i : (i+1)*20]),
-		left:   nil,
-		right:  nil,
-		parent: parent,
+func rootOf(node *Node) *Node {
+	for node.parent != nil {
+		node = node.parent
 	}
+	return node
 }
 
 // helper for serialization
-func (merkle *MerkleTree) bfs() [][20]byte {
+func (merkle *MerkleTree) bfs() [][]byte {
 	q := make([]*Node, 0)
-	retVal := make([][20]byte, 0)
+	retVal := make([][]byte, 0)
 	q = append(q, merkle.Root) // add root
 	for len(q) != 0 {
 		node := q[0]
@@ -197,14 +446,10 @@ func (merkle *MerkleTree) bfs() [][20]byte {
 	return retVal
 }
 
-func hash(data []byte) [20]byte {
-	return sha1.Sum(data)
-}
-
-func addEmptyNode(nodes []*Node) []*Node {
+func addEmptyNode(hashType HashType, nodes []*Node) []*Node {
 	empty := []byte{}
 	nodes = append(nodes, &Node{
-		data:   hash(empty),
+		data:   LeafHash(hashType, empty),
 		left:   nil,
 		right:  nil,
 		parent: nil,
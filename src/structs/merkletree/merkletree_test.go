@@ -0,0 +1,147 @@
+package merkletree
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"testing"
+)
+
+func testContent(n int) [][]byte {
+	content := make([][]byte, n)
+	for i := range content {
+		content[i] = []byte(fmt.Sprintf("record%d", i))
+	}
+	return content
+}
+
+func TestProofsOfAllLeavesVerify(t *testing.T) {
+	for _, hashType := range []HashType{SHA1, SHA256, SHA1_SEPARATED, SHA256_SEPARATED} {
+		for _, n := range []int{1, 2, 3, 5, 8, 13} {
+			content := testContent(n)
+			tree, err := NewTree(hashType, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range content {
+				proof, err := tree.Proof(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !VerifyProof(tree.HashType(), tree.RootHash(), tree.Leaves(), content[i], proof) {
+					t.Fatalf("%s, %d leaves: proof of leaf %d doesn't verify", hashType, n, i)
+				}
+				if VerifyProof(tree.HashType(), tree.RootHash(), tree.Leaves(), []byte("other"), proof) {
+					t.Fatalf("%s, %d leaves: proof of leaf %d verifies other content", hashType, n, i)
+				}
+			}
+		}
+	}
+}
+
+// Without domain separation, the children of an inner node, passed as the content of a leaf,
+// with the rest of the path as the proof, give the root
+func TestInnerNodeIsNotALeaf(t *testing.T) {
+	tree, err := NewTree(DEFAULT_HASH, testContent(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.Proof(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := tree.leaves[0].parent
+	children := append(append([]byte{}, parent.left.data...), parent.right.data...)
+	short := &Proof{HashType: proof.HashType, Index: 0, Siblings: proof.Siblings[1:], Left: proof.Left[1:]}
+	if VerifyProof(tree.HashType(), tree.RootHash(), tree.Leaves(), children, short) {
+		t.Fatal("inner node was verified as a leaf")
+	}
+	if VerifyProof(tree.HashType(), tree.RootHash(), tree.Leaves()/2, children, short) {
+		t.Fatal("inner node was verified as a leaf of a smaller tree")
+	}
+}
+
+func TestProofMustMatchDepthAndIndex(t *testing.T) {
+	content := testContent(8)
+	tree, err := NewTree(DEFAULT_HASH, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.Proof(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	longer := &Proof{
+		HashType: proof.HashType,
+		Index:    proof.Index,
+		Siblings: append(append([][]byte{}, proof.Siblings...), tree.RootHash()),
+		Left:     append(append([]bool{}, proof.Left...), false),
+	}
+	if VerifyProof(tree.HashType(), tree.RootHash(), tree.Leaves(), content[5], longer) {
+		t.Fatal("proof with more siblings than levels was verified")
+	}
+	moved := *proof
+	moved.Index = 4
+	if VerifyProof(tree.HashType(), tree.RootHash(), tree.Leaves(), content[5], &moved) {
+		t.Fatal("proof with an index that doesn't match its sides was verified")
+	}
+}
+
+// The hash comes from the trusted tree, so a proof can't choose one without domain separation
+func TestProofMustUseTheHashOfTheTree(t *testing.T) {
+	content := testContent(4)
+	tree, err := NewTree(SHA256_SEPARATED, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hashType := range []HashType{SHA1, SHA256, SHA1_SEPARATED} {
+		other, err := NewTree(hashType, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := other.Proof(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyProof(hashType, other.RootHash(), other.Leaves(), content[1], proof) {
+			t.Fatalf("%s proof doesn't verify against its own tree", hashType)
+		}
+		if VerifyProof(tree.HashType(), other.RootHash(), other.Leaves(), content[1], proof) {
+			t.Fatalf("%s proof was verified for a %s tree", hashType, tree.HashType())
+		}
+	}
+}
+
+// sstables written before domain separation keep their trees, which are checked as they were made
+func TestTreesWithoutDomainSeparationAreRead(t *testing.T) {
+	content := testContent(6)
+	for _, hashType := range []HashType{SHA1, SHA256} {
+		tree, err := NewTree(hashType, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read := Deserialize(tree.Serialize())
+		if read == nil || read.HashType() != hashType || !read.Equal(tree) {
+			t.Fatalf("%s tree wasn't read back", hashType)
+		}
+		if changed, err := read.VerifyTree(content); err != nil || len(changed) != 0 {
+			t.Fatalf("%s tree doesn't match its content: %v, %v", hashType, changed, err)
+		}
+	}
+
+	// legacy trees are sha1 nodes without a header
+	tree, err := NewTree(SHA1, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacy []byte
+	for _, node := range tree.bfs() {
+		legacy = append(legacy, node...)
+	}
+	read := Deserialize(legacy)
+	if read == nil || !read.IsLegacy() || len(read.RootHash()) != sha1.Size {
+		t.Fatal("legacy tree wasn't read")
+	}
+	if changed, err := read.VerifyTree(content); err != nil || len(changed) != 0 {
+		t.Fatalf("legacy tree doesn't match its content: %v, %v", changed, err)
+	}
+}
@@ -9,25 +9,33 @@ import (
 // The array contains at most pageSize records from the page with the passed page number
 // If an invalid page number or page size is passed, an empty array is returned with no error
 func PrefixScan(prefix string, pageNumber int, pageSize int, SSTableCompressionOn bool, compressionMap map[string]uint64) ([]*model.Record, error) {
+	records, _, err := PrefixScanWithStats(prefix, pageNumber, pageSize, SSTableCompressionOn, compressionMap)
+	return records, err
+}
+
+// Same as PrefixScan, but also returns how many sstables were read and skipped
+func PrefixScanWithStats(prefix string, pageNumber int, pageSize int, SSTableCompressionOn bool, compressionMap map[string]uint64) ([]*model.Record, iterators.ScanStats, error) {
 	var records []*model.Record = make([]*model.Record, 0)
+	var stats iterators.ScanStats
 
 	//If the page number is invalid, return an empty array
 	if pageNumber < 1 {
-		return records, nil
+		return records, stats, nil
 	}
 
 	//If the page size is invalid, return an empty array
 	if pageSize < 1 {
-		return records, nil
+		return records, stats, nil
 	}
 
 	//Create a new prefix iterator
 	prefixIter, err := iterators.NewPrefixIterator(prefix, SSTableCompressionOn, compressionMap)
 	if err != nil {
-		return records, err
+		return records, stats, err
 	}
 	//Stop the iterator once we are finished
 	defer prefixIter.Stop()
+	stats = prefixIter.Stats()
 
 	//The number of records we need to skip before we get to the right page
 	var recordsToSkip int = (pageNumber - 1) * pageSize
@@ -36,12 +44,12 @@ func PrefixScan(prefix string, pageNumber int, pageSize int, SSTableCompressionO
 	for i := 0; i < recordsToSkip; i++ {
 		record, err := prefixIter.Next()
 		if err != nil {
-			return records, err
+			return records, stats, err
 		}
 
 		//If we iterated through all the records before getting to the right page return the empty array
 		if record == nil {
-			return records, nil
+			return records, stats, nil
 		}
 	}
 
@@ -49,7 +57,7 @@ func PrefixScan(prefix string, pageNumber int, pageSize int, SSTableCompressionO
 	for i := 0; i < pageSize; i++ {
 		record, err := prefixIter.Next()
 		if err != nil {
-			return records, err
+			return records, stats, err
 		}
 
 		//If there are no more records left, return the non-full array
@@ -59,5 +67,5 @@ func PrefixScan(prefix string, pageNumber int, pageSize int, SSTableCompressionO
 		records = append(records, record)
 	}
 
-	return records, nil
+	return records, stats, nil
 }
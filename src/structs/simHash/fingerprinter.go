@@ -0,0 +1,320 @@
+package simHash
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+
+	countMinSketch "github.com/natasakasikovic/Key-Value-engine/src/structs/CountMinSketch"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/hash"
+)
+
+// Tokenizers split a text into tokens
+const (
+	TOKENIZER_WHITESPACE = "whitespace" // words separated by whitespace, longer than 2 bytes, as they are written
+	TOKENIZER_WORDS      = "words"      // lowercase runs of letters and digits, every character of scripts written without spaces is a word
+	TOKENIZER_NGRAMS     = "ngrams"     // lowercase overlapping character n-grams, whitespace is collapsed to one space
+)
+
+// Weightings give weight to tokens
+const (
+	WEIGHT_TF    = "tf"    // number of times the token is in the text
+	WEIGHT_TFIDF = "tfidf" // tf multiplied by the inverse of the number of learned texts with the token, so common tokens count less
+)
+
+// Hashes give each token its bits
+const (
+	HASH_MD5    = "md5"    // first 8 bytes of md5, kept so old fingerprints can be compared with new ones
+	HASH_XXHASH = "xxhash" // 64 bit xxhash, much faster
+)
+
+// error and probability of a bigger error of the count min sketch of document frequencies
+const (
+	DF_EPSILON = 0.01
+	DF_DELTA   = 0.01
+)
+
+var ENGLISH_STOP_WORDS = []string{
+	"a", "about", "after", "all", "also", "an", "and", "any", "are", "as", "at", "be", "because", "been", "but", "by",
+	"can", "could", "did", "do", "does", "for", "from", "had", "has", "have", "he", "her", "his", "how", "i", "if", "in",
+	"into", "is", "it", "its", "just", "more", "most", "my", "no", "not", "of", "on", "one", "only", "or", "other", "our",
+	"out", "she", "so", "some", "than", "that", "the", "their", "them", "then", "there", "these", "they", "this", "to",
+	"up", "was", "we", "were", "what", "when", "which", "who", "will", "with", "would", "you", "your",
+}
+
+// Options choose how texts are turned into weighted tokens
+type Options struct {
+	Tokenizer   string
+	NgramSize   int // length of n-grams in characters, used by TOKENIZER_NGRAMS
+	ShingleSize int // number of consecutive tokens joined into one, 1 keeps single tokens
+	StopWords   []string
+	Weighting   string
+	Hash        string
+}
+
+// Options GetFingerprint uses, texts fingerprinted with them have the same fingerprints as before options existed
+func DefaultOptions() Options {
+	return Options{Tokenizer: TOKENIZER_WHITESPACE, NgramSize: 3, ShingleSize: 1, Weighting: WEIGHT_TF, Hash: HASH_MD5}
+}
+
+func (options Options) Validate() error {
+	if options.Tokenizer != TOKENIZER_WHITESPACE && options.Tokenizer != TOKENIZER_WORDS && options.Tokenizer != TOKENIZER_NGRAMS {
+		return errors.New("unknown tokenizer")
+	}
+	if options.Tokenizer == TOKENIZER_NGRAMS && options.NgramSize < 1 {
+		return errors.New("n-gram size must be positive")
+	}
+	if options.ShingleSize < 1 {
+		return errors.New("shingle size must be positive")
+	}
+	if options.Weighting != WEIGHT_TF && options.Weighting != WEIGHT_TFIDF {
+		return errors.New("unknown weighting")
+	}
+	if options.Hash != HASH_MD5 && options.Hash != HASH_XXHASH {
+		return errors.New("unknown hash")
+	}
+	return nil
+}
+
+// Fingerprinter makes fingerprints of texts with its options
+// With tf-idf weighting, it counts in how many learned texts each token is, in a count min sketch,
+// so fingerprints of the same text change as more texts are learned
+type Fingerprinter struct {
+	options   Options
+	stopWords map[string]bool
+	documents uint64              // number of learned texts
+	df        *countMinSketch.CMS // number of learned texts with the token, nil without tf-idf weighting
+}
+
+func NewFingerprinter(options Options) (*Fingerprinter, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	fingerprinter := &Fingerprinter{options: options, stopWords: make(map[string]bool)}
+	for _, word := range options.StopWords {
+		fingerprinter.stopWords[strings.ToLower(word)] = true
+	}
+	if options.Weighting == WEIGHT_TFIDF {
+		fingerprinter.df = countMinSketch.CreateCMS(DF_EPSILON, DF_DELTA)
+	}
+	return fingerprinter, nil
+}
+
+func (fingerprinter *Fingerprinter) Options() Options {
+	return fingerprinter.options
+}
+
+// Returns the tokens of the text, with stop words removed and shingles made
+func (fingerprinter *Fingerprinter) Tokens(text string) []string {
+	var tokens []string
+	switch fingerprinter.options.Tokenizer {
+	case TOKENIZER_WHITESPACE:
+		for _, word := range strings.Fields(text) {
+			if len(word) > 2 {
+				tokens = append(tokens, word)
+			}
+		}
+	case TOKENIZER_WORDS:
+		tokens = splitWords(text)
+	case TOKENIZER_NGRAMS:
+		tokens = ngrams(text, fingerprinter.options.NgramSize)
+	}
+
+	if len(fingerprinter.stopWords) > 0 {
+		filtered := tokens[:0]
+		for _, token := range tokens {
+			if !fingerprinter.stopWords[strings.ToLower(token)] {
+				filtered = append(filtered, token)
+			}
+		}
+		tokens = filtered
+	}
+	return shingles(tokens, fingerprinter.options.ShingleSize)
+}
+
+// scripts written without spaces between words
+func isUnspaced(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar)
+}
+
+func splitWords(text string) []string {
+	var words []string
+	var word []rune
+	endWord := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		if isUnspaced(r) {
+			endWord()
+			words = append(words, string(r))
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			word = append(word, r)
+		} else {
+			endWord()
+		}
+	}
+	endWord()
+	return words
+}
+
+// texts shorter than n give themselves as the only n-gram
+func ngrams(text string, n int) []string {
+	runes := []rune(strings.ToLower(strings.Join(strings.Fields(text), " ")))
+	if len(runes) == 0 {
+		return nil
+	}
+	if len(runes) <= n {
+		return []string{string(runes)}
+	}
+	grams := make([]string, 0, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+n]))
+	}
+	return grams
+}
+
+// joins every size consecutive tokens, fewer tokens than size are joined into one shingle
+func shingles(tokens []string, size int) []string {
+	if size <= 1 || len(tokens) == 0 {
+		return tokens
+	}
+	if len(tokens) <= size {
+		return []string{strings.Join(tokens, " ")}
+	}
+	result := make([]string, 0, len(tokens)-size+1)
+	for i := 0; i+size <= len(tokens); i++ {
+		result = append(result, strings.Join(tokens[i:i+size], " "))
+	}
+	return result
+}
+
+func (fingerprinter *Fingerprinter) hash(token string) uint64 {
+	if fingerprinter.options.Hash == HASH_XXHASH {
+		return hash.XXHash64([]byte(token), hash.DEFAULT_SEED)
+	}
+	return getWordHash(token)
+}
+
+// Counts the text into document frequencies of its tokens, it only matters for tf-idf weighting
+func (fingerprinter *Fingerprinter) Learn(text string) {
+	if fingerprinter.df == nil {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, token := range fingerprinter.Tokens(text) {
+		if !seen[token] {
+			seen[token] = true
+			fingerprinter.df.Insert(token)
+		}
+	}
+	fingerprinter.documents++
+}
+
+func (fingerprinter *Fingerprinter) Documents() uint64 {
+	return fingerprinter.documents
+}
+
+func (fingerprinter *Fingerprinter) Fingerprint(text string) uint64 {
+	frequencies := make(map[string]float64)
+	for _, token := range fingerprinter.Tokens(text) {
+		frequencies[token]++
+	}
+	weights := make(map[uint64]float64, len(frequencies))
+	for token, tf := range frequencies {
+		weight := tf
+		if fingerprinter.df != nil {
+			// smoothed, so tokens of texts that weren't learned still count
+			weight *= math.Log(float64(fingerprinter.documents+1)/float64(uint64(fingerprinter.df.Search(token))+1)) + 1
+		}
+		weights[fingerprinter.hash(token)] = weight
+	}
+	return fingerprint(weights)
+}
+
+// options, number of learned texts, then the length of the count min sketch and the sketch with tf-idf weighting
+// strings are saved as length and bytes
+func (fingerprinter *Fingerprinter) Serialize() []byte {
+	options := fingerprinter.options
+	var bytes []byte
+	appendString := func(s string) {
+		bytes = binary.BigEndian.AppendUint32(bytes, uint32(len(s)))
+		bytes = append(bytes, s...)
+	}
+	appendString(options.Tokenizer)
+	bytes = binary.BigEndian.AppendUint32(bytes, uint32(options.NgramSize))
+	bytes = binary.BigEndian.AppendUint32(bytes, uint32(options.ShingleSize))
+	bytes = binary.BigEndian.AppendUint32(bytes, uint32(len(options.StopWords)))
+	for _, word := range options.StopWords {
+		appendString(word)
+	}
+	appendString(options.Weighting)
+	appendString(options.Hash)
+	bytes = binary.BigEndian.AppendUint64(bytes, fingerprinter.documents)
+	var cmsBytes []byte
+	if fingerprinter.df != nil {
+		cmsBytes = fingerprinter.df.Serialize()
+	}
+	bytes = binary.BigEndian.AppendUint32(bytes, uint32(len(cmsBytes)))
+	return append(bytes, cmsBytes...)
+}
+
+// Returns nil if the bytes aren't a valid fingerprinter
+func DeserializeFingerprinter(bytes []byte) *Fingerprinter {
+	var offset int = 0
+	var ok bool = true
+	readUint32 := func() uint32 {
+		if !ok || len(bytes) < offset+4 {
+			ok = false
+			return 0
+		}
+		offset += 4
+		return binary.BigEndian.Uint32(bytes[offset-4 : offset])
+	}
+	readString := func() string {
+		length := int(readUint32())
+		if !ok || len(bytes) < offset+length {
+			ok = false
+			return ""
+		}
+		offset += length
+		return string(bytes[offset-length : offset])
+	}
+
+	var options Options
+	options.Tokenizer = readString()
+	options.NgramSize = int(readUint32())
+	options.ShingleSize = int(readUint32())
+	stopWords := readUint32()
+	for i := 0; ok && i < int(stopWords); i++ {
+		options.StopWords = append(options.StopWords, readString())
+	}
+	options.Weighting = readString()
+	options.Hash = readString()
+	if !ok || len(bytes) < offset+8 {
+		return nil
+	}
+	documents := binary.BigEndian.Uint64(bytes[offset : offset+8])
+	offset += 8
+	cmsLength := int(readUint32())
+	if !ok || len(bytes) != offset+cmsLength {
+		return nil
+	}
+
+	fingerprinter, err := NewFingerprinter(options)
+	if err != nil {
+		return nil
+	}
+	fingerprinter.documents = documents
+	if fingerprinter.df != nil {
+		fingerprinter.df = countMinSketch.Deserialize(bytes[offset:])
+		if fingerprinter.df == nil {
+			return nil
+		}
+	}
+	return fingerprinter
+}
@@ -0,0 +1,67 @@
+package simHash
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// max distance an index can be made for, each band must have at least 2 bits
+const MAX_INDEX_DISTANCE uint8 = 31
+
+// Index finds stored fingerprints within MaxDistance of a fingerprint without comparing it with all of them
+// Fingerprints are split into MaxDistance+1 bands of bits: if two fingerprints differ in at most MaxDistance bits,
+// at least one band is the same in both, so fingerprints are kept in buckets by the value of each of their bands,
+// and only fingerprints from the buckets of the queried fingerprint are compared
+type Index struct {
+	MaxDistance uint8
+	Count       uint64 // number of indexed documents
+	Namespace   string // namespace whose options make fingerprints of documents, empty for the default options
+}
+
+type Match struct {
+	Document string
+	Distance uint8
+}
+
+func NewIndex(maxDistance uint8, namespace string) (*Index, error) {
+	if maxDistance > MAX_INDEX_DISTANCE {
+		return nil, errors.New("max distance of the index is too big")
+	}
+	return &Index{MaxDistance: maxDistance, Namespace: namespace}, nil
+}
+
+func (index *Index) Bands() int {
+	return int(index.MaxDistance) + 1
+}
+
+// Returns the value of each band of the fingerprint, the first bands get one more bit if 64 isn't divisible by the number of bands
+func (index *Index) BandValues(fingerprint uint64) []uint64 {
+	bands := index.Bands()
+	values := make([]uint64, bands)
+	var start int = 0
+	for i := 0; i < bands; i++ {
+		width := 64 / bands
+		if i < 64%bands {
+			width++
+		}
+		values[i] = (fingerprint >> start) & (1<<width - 1)
+		start += width
+	}
+	return values
+}
+
+// max distance, count, namespace
+func (index *Index) Serialize() []byte {
+	bytes := make([]byte, 9)
+	bytes[0] = index.MaxDistance
+	binary.BigEndian.PutUint64(bytes[1:9], index.Count)
+	return append(bytes, index.Namespace...)
+}
+
+// Returns nil if the bytes aren't a valid index
+func DeserializeIndex(bytes []byte) *Index {
+	if len(bytes) < 9 || bytes[0] > MAX_INDEX_DISTANCE {
+		return nil
+	}
+	return &Index{MaxDistance: bytes[0], Count: binary.BigEndian.Uint64(bytes[1:9]), Namespace: string(bytes[9:])}
+}
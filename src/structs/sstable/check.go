@@ -0,0 +1,288 @@
+package sstable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
+	"github.com/natasakasikovic/Key-Value-engine/src/utils"
+)
+
+// TableCheck is the result of checking one sstable, the sstable is damaged if there are any problems
+type TableCheck struct {
+	Name     string   `json:"name"`
+	Records  int      `json:"records"`
+	Problems []string `json:"problems"`
+}
+
+// entry of the index or the summary: key and offset of the item it points to
+type indexEntry struct {
+	key    string
+	offset uint64
+}
+
+func (check *TableCheck) problem(format string, args ...any) {
+	check.Problems = append(check.Problems, fmt.Sprintf(format, args...))
+}
+
+// CheckTable reads the whole sstable with the passed name and checks that its parts agree:
+// offsets in the header, records (CRC and key order), index entries against records, summary entries against the index,
+// the bloom filter and the prefix filter against keys, and the merkle tree against records
+// Sizes and offsets read from the sstable are checked against the files before they are used
+func CheckTable(dirName string, compressionOn bool, compressionMap map[string]uint64) TableCheck {
+	check := TableCheck{Name: dirName, Problems: make([]string, 0)}
+	path := fmt.Sprintf("%s/%s", PATH, dirName)
+	content, err := utils.GetDirContent(path)
+	if err != nil {
+		check.problem("sstable can't be read: %v", err)
+		return check
+	}
+	separate := len(content) != 1
+
+	var table *SSTable
+	if separate {
+		table, err = LoadSSTableSeparate(path)
+	} else {
+		table, err = LoadSStableSingle(path)
+	}
+	if err != nil {
+		check.problem("header can't be read: %v", err)
+		return check
+	}
+	defer table.Close()
+	table.Name = dirName
+	table.CompressionOn = compressionOn
+
+	dataStart, dataEnd, indexStart, indexEnd, summaryEnd, ok := table.checkOffsets(&check, separate, path)
+	if !ok {
+		return check
+	}
+
+	if err := table.loadBF(separate, dirName); err != nil {
+		check.problem("bloom filter can't be read: %v", err)
+	}
+	if err := table.LoadMerkle(separate, path); err != nil || table.Merkle == nil {
+		check.problem("merkle tree can't be read")
+	}
+
+	// compressed index entries hold numbers of keys, not keys
+	var keys map[uint64]string
+	if compressionOn {
+		keys = make(map[uint64]string, len(compressionMap))
+		for key, value := range compressionMap {
+			keys[value] = key
+		}
+	}
+	index, err := readEntries(table.Index, indexStart, indexEnd, compressionOn, keys)
+	if err != nil {
+		check.problem("index can't be read: %v", err)
+	}
+	summary, err := readEntries(table.Summary, table.SummaryOffset, summaryEnd, compressionOn, keys)
+	if err != nil {
+		check.problem("summary can't be read: %v", err)
+	}
+	table.checkSummary(&check, summary, index, indexStart)
+	table.checkData(&check, dataStart, dataEnd, index, compressionMap)
+	return check
+}
+
+// checks that the parts of the sstable are where the header says, and returns the offsets between which they are
+func (sstable *SSTable) checkOffsets(check *TableCheck, separate bool, path string) (int64, int64, int64, int64, int64, bool) {
+	if separate {
+		for _, name := range []string{"Data", "Index", "Summary", "Filter", "Metadata"} {
+			if _, err := os.Stat(fmt.Sprintf("%s/%s%s.db", path, FILE_NAME, name)); err != nil {
+				check.problem("%s file is missing", name)
+			}
+		}
+		dataEnd, err := utils.GetFileLength(sstable.Data)
+		if err != nil {
+			check.problem("data file can't be read: %v", err)
+			return 0, 0, 0, 0, 0, false
+		}
+		indexEnd, err := utils.GetFileLength(sstable.Index)
+		if err != nil {
+			check.problem("index file can't be read: %v", err)
+			return 0, 0, 0, 0, 0, false
+		}
+		summaryEnd, err := utils.GetFileLength(sstable.Summary)
+		if err != nil {
+			check.problem("summary file can't be read: %v", err)
+			return 0, 0, 0, 0, 0, false
+		}
+		return 0, dataEnd, 0, indexEnd, summaryEnd, true
+	}
+
+	length, err := utils.GetFileLength(sstable.Data)
+	if err != nil {
+		check.problem("file can't be read: %v", err)
+		return 0, 0, 0, 0, 0, false
+	}
+	var headerSize int64 = 7*8 + int64(len(sstable.MinKey)) + int64(len(sstable.MaxKey))
+	// the other offsets can only be checked against the end of the header and the file if this one is right
+	if sstable.BfOffset != headerSize {
+		check.problem("header: filter offset is %d, header ends at %d", sstable.BfOffset, headerSize)
+		return 0, 0, 0, 0, 0, false
+	}
+	offsets := []int64{sstable.BfOffset, sstable.DataOffset, sstable.IndexOffset, sstable.SummaryOffset, sstable.MerkleOffset, length}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] < offsets[i-1] {
+			check.problem("header: offsets of filter, data, index, summary and merkle are not in order or are past the end of the file")
+			return 0, 0, 0, 0, 0, false
+		}
+	}
+	return sstable.DataOffset, sstable.IndexOffset, sstable.IndexOffset, sstable.SummaryOffset, sstable.MerkleOffset, true
+}
+
+// reads index or summary entries between the passed offsets, offsets saved in entries are relative to the part they point to
+func readEntries(file *os.File, start int64, end int64, compressionOn bool, keys map[uint64]string) ([]indexEntry, error) {
+	reader := bufio.NewReader(io.NewSectionReader(file, start, end-start))
+	var entries []indexEntry
+	buffer := make([]byte, 8)
+	for offset := start; offset < end; {
+		if _, err := io.ReadFull(reader, buffer); err != nil {
+			return entries, err
+		}
+		var key string
+		if compressionOn {
+			key = keys[binary.BigEndian.Uint64(buffer)]
+			offset += 8
+		} else {
+			size := binary.BigEndian.Uint64(buffer)
+			if size > uint64(end-offset) {
+				return entries, fmt.Errorf("entry at %d is longer than the part it is in", offset)
+			}
+			keyBytes := make([]byte, size)
+			if _, err := io.ReadFull(reader, keyBytes); err != nil {
+				return entries, err
+			}
+			key = string(keyBytes)
+			offset += 8 + int64(size)
+		}
+		if _, err := io.ReadFull(reader, buffer); err != nil {
+			return entries, err
+		}
+		entries = append(entries, indexEntry{key: key, offset: binary.BigEndian.Uint64(buffer)})
+		offset += 8
+	}
+	return entries, nil
+}
+
+// every summary entry has to point to the start of an index entry with the same key
+func (sstable *SSTable) checkSummary(check *TableCheck, summary []indexEntry, index []indexEntry, indexStart int64) {
+	if len(index) > 0 && len(summary) == 0 {
+		check.problem("summary is empty")
+		return
+	}
+	starts := make(map[uint64]string, len(index))
+	var offset uint64 = 0
+	for _, entry := range index {
+		starts[offset] = entry.key
+		if sstable.CompressionOn {
+			offset += 16
+		} else {
+			offset += 16 + uint64(len(entry.key))
+		}
+	}
+	for i, entry := range summary {
+		key, ok := starts[entry.offset]
+		if !ok {
+			check.problem("summary entry %d points to %d, which is not the start of an index entry", i, entry.offset+uint64(indexStart))
+		} else if key != entry.key {
+			check.problem("summary entry %d has key %q, but the index entry it points to has key %q", i, entry.key, key)
+		}
+		if i > 0 && entry.key <= summary[i-1].key {
+			check.problem("summary entry %d is not sorted by key", i)
+		}
+	}
+}
+
+// reads all records, checking their CRC, key order, that index entries point to them, that filters have their keys
+// and that the merkle tree is made from them
+func (sstable *SSTable) checkData(check *TableCheck, start int64, end int64, index []indexEntry, compressionMap map[string]uint64) {
+	_, err := sstable.Data.Seek(start, 0)
+	if err != nil {
+		check.problem("data can't be read: %v", err)
+		return
+	}
+	var leafHashes [][]byte
+	var lastKey string
+	var next int = 0 // next index entry that a record should match
+	offset := start
+	for offset < end {
+		record, bytesRead, err := model.Deserialize(sstable.Data, sstable.CompressionOn, compressionMap)
+		if err != nil {
+			// the rest of the data can't be read reliably, so the check stops here
+			check.problem("record %d at %d can't be read: %v", check.Records, offset, err)
+			return
+		}
+		relative := uint64(offset - start)
+		for next < len(index) && index[next].offset < relative {
+			check.problem("index entry %d points to %d, which is not the start of a record", next, index[next].offset+uint64(start))
+			next++
+		}
+		if next < len(index) && index[next].offset == relative {
+			if index[next].key != record.Key {
+				check.problem("index entry %d has key %q, but the record it points to has key %q", next, index[next].key, record.Key)
+			}
+			next++
+		}
+
+		if check.Records == 0 && record.Key != sstable.MinKey {
+			check.problem("first key is %q, but the header says %q", record.Key, sstable.MinKey)
+		}
+		if check.Records > 0 && record.Key <= lastKey {
+			check.problem("record %d with key %q is not sorted by key", check.Records, record.Key)
+		}
+		if sstable.Bf != nil && !sstable.Bf.Find(record.Key) {
+			check.problem("bloom filter doesn't contain key %q", record.Key)
+		}
+		if sstable.PrefixBf != nil {
+			if prefix, ok := sstable.PrefixExtractor.Extract(record.Key); ok && !sstable.PrefixBf.Find(prefix) {
+				check.problem("prefix filter doesn't contain prefix %q", prefix)
+			}
+		}
+		if sstable.Merkle != nil {
+			leaf, err := MerkleLeaf(sstable.Merkle, record, sstable.CompressionOn, compressionMap)
+			if err != nil {
+				check.problem("record %d can't be serialized: %v", check.Records, err)
+			}
+			leafHashes = append(leafHashes, merkletree.LeafHash(sstable.Merkle.HashType(), leaf))
+		}
+
+		lastKey = record.Key
+		offset += int64(bytesRead)
+		check.Records++
+	}
+	for ; next < len(index); next++ {
+		check.problem("index entry %d points to %d, which is past the data", next, index[next].offset+uint64(start))
+	}
+	if check.Records == 0 {
+		check.problem("sstable has no records")
+		return
+	}
+	if lastKey != sstable.MaxKey {
+		check.problem("last key is %q, but the header says %q", lastKey, sstable.MaxKey)
+	}
+
+	if sstable.Merkle == nil {
+		return
+	}
+	changed, err := sstable.Merkle.VerifyLeafHashes(leafHashes)
+	if err != nil {
+		check.problem("merkle tree: %v", err)
+		return
+	}
+	if len(changed) > 0 {
+		check.problem("merkle tree: records %v changed", changed)
+		return
+	}
+	// changed leaves are found only if roots differ, so damaged nodes under an intact root are found by comparing all nodes
+	other, err := merkletree.NewTreeFromHashes(sstable.Merkle.HashType(), leafHashes)
+	if err == nil && !sstable.Merkle.Equal(other) {
+		check.problem("merkle tree: saved nodes don't match the records")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/natasakasikovic/Key-Value-engine/src/model"
 	hashmap "github.com/natasakasikovic/Key-Value-engine/src/structs/hashMap"
 )
 
@@ -47,19 +46,10 @@ func keyExists(hashMap map[string]uint64, key string) bool {
 }
 
 // if compression is turned on, use dictionary encoding
-// maps keys on numbers
-func dictionaryEncodingOn(records []*model.Record, compressionMap map[string]uint64) {
-	var nextKey uint64
-	if len(compressionMap) == 0 {
-		nextKey = 0
-	} else {
-		nextKey = uint64(len(compressionMap))
-	}
-	for i := 0; i < len(records); i++ {
-		if keyExists(compressionMap, records[i].Key) { // if key already exists, don't map it again
-			continue
-		}
-		compressionMap[records[i].Key] = uint64(nextKey)
-		nextKey++
+// maps key on a number, if it is not already mapped
+func dictionaryEncodeKey(key string, compressionMap map[string]uint64) {
+	if keyExists(compressionMap, key) { // if key already exists, don't map it again
+		return
 	}
+	compressionMap[key] = uint64(len(compressionMap))
 }
@@ -7,7 +7,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/natasakasikovic/Key-Value-engine/src/model"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/bloomFilter"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
 	"github.com/natasakasikovic/Key-Value-engine/src/utils"
@@ -61,35 +60,17 @@ func LoadSStableSingle(p string) (*SSTable, error) {
 
 	sstable.Index, sstable.Data, sstable.Summary = file, file, file
 
-	// set min key
-	var minKeyLength uint64
-	err = binary.Read(file, binary.BigEndian, &minKeyLength)
+	// set min key and max key
+	sstable.MinKey, err = readHeaderKey(file)
 	if err != nil {
 		file.Close()
 		return nil, err
 	}
-	minKeyBytes := make([]byte, minKeyLength)
-	_, err = file.Read(minKeyBytes)
+	sstable.MaxKey, err = readHeaderKey(file)
 	if err != nil {
 		file.Close()
 		return nil, err
 	}
-	sstable.MinKey = string(minKeyBytes)
-
-	// set max key
-	var maxKeyLength uint64
-	err = binary.Read(file, binary.BigEndian, &maxKeyLength)
-	if err != nil {
-		file.Close()
-		return nil, err
-	}
-	maxKeyBytes := make([]byte, maxKeyLength)
-	_, err = file.Read(maxKeyBytes)
-	if err != nil {
-		file.Close()
-		return nil, err
-	}
-	sstable.MaxKey = string(maxKeyBytes)
 
 	// set offsets
 	offsets := make([]uint64, 5)
@@ -120,40 +101,18 @@ func LoadSSTableSeparate(path string) (*SSTable, error) {
 	}
 	sstable.Summary = summaryFile
 
-	// set min key
-	var minKeyLength int64
-	err = binary.Read(summaryFile, binary.BigEndian, &minKeyLength)
-	if err != nil {
-		summaryFile.Close()
-		return nil, err
-	}
-
-	minKeyBytes := make([]byte, minKeyLength)
-	_, err = summaryFile.Read(minKeyBytes)
+	// set min key and max key
+	sstable.MinKey, err = readHeaderKey(summaryFile)
 	if err != nil {
 		summaryFile.Close()
 		return nil, err
 	}
-
-	sstable.MinKey = string(minKeyBytes)
-
-	//set max key
-	var maxKeyLength int64
-	err = binary.Read(summaryFile, binary.BigEndian, &maxKeyLength)
+	sstable.MaxKey, err = readHeaderKey(summaryFile)
 	if err != nil {
 		summaryFile.Close()
 		return nil, err
 	}
 
-	maxKeyBytes := make([]byte, maxKeyLength)
-	_, err = summaryFile.Read(maxKeyBytes)
-	if err != nil {
-		summaryFile.Close()
-		return nil, err
-	}
-
-	sstable.MaxKey = string(maxKeyBytes)
-
 	// set index file
 	indexFile, err := os.Open(fmt.Sprintf("%s%s", path, "Index.db"))
 	if err != nil {
@@ -172,13 +131,37 @@ func LoadSSTableSeparate(path string) (*SSTable, error) {
 	sstable.Data = dataFile
 
 	sstable.BfOffset, sstable.DataOffset, sstable.IndexOffset, sstable.MerkleOffset = 0, 0, 0, 0
-	sstable.SummaryOffset = 2*8 + minKeyLength + maxKeyLength
+	sstable.SummaryOffset = int64(2*8 + len(sstable.MinKey) + len(sstable.MaxKey))
 
 	return sstable, nil
 }
 
+// reads the length of a key and the key from the start of the sstable
+// the length is checked against the size of the file, so a damaged one can't make the key larger than the file
+func readHeaderKey(file *os.File) (string, error) {
+	var length uint64
+	err := binary.Read(file, binary.BigEndian, &length)
+	if err != nil {
+		return "", err
+	}
+	fileSize, err := utils.GetFileLength(file)
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(fileSize) {
+		return "", errors.New("key in the header of the sstable is longer than the file")
+	}
+	key := make([]byte, length)
+	_, err = io.ReadFull(file, key)
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
 // params: bool singleFile - if we load from single file first read first 8 bytes to check size of bf
 // if it is not single file then read all bytes from file
+// the prefix filter, if the sstable has it, is saved right after the bloom filter and is loaded as well
 func (sstable *SSTable) loadBF(separateFile bool, path string) error {
 	var file *os.File
 	var err error
@@ -196,136 +179,77 @@ func (sstable *SSTable) loadBF(separateFile bool, path string) error {
 			return err
 		}
 	} else {
-		file = sstable.Data
+		// the file is opened again, so the filter can be loaded after the sstable files are closed
+		path = fmt.Sprintf("%s/%s/%s%s", PATH, path, FILE_NAME, "DataIndexSummary.db")
+		file, err = os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fileSize, err := utils.GetFileLength(file)
+		if err != nil {
+			return err
+		}
+		if sstable.BfOffset < 0 || sstable.DataOffset < sstable.BfOffset || sstable.DataOffset > fileSize {
+			return errors.New("offsets of the bloom filter are past the end of the file")
+		}
 		toRead = make([]byte, int(sstable.DataOffset-sstable.BfOffset))
 		file.Seek(sstable.BfOffset, 0)
-		_, err = io.ReadAtLeast(sstable.Data, toRead, len(toRead))
+		_, err = io.ReadAtLeast(file, toRead, len(toRead))
 		if err != nil {
 			return err
 		}
 	}
 
-	sstable.Bf = bloomFilter.Deserialize(toRead)
-	return nil
+	maxTimestamp, toRead := splitMaxTimestamp(toRead)
+	if maxTimestamp != 0 {
+		sstable.MaxTimestamp = maxTimestamp
+	}
+	bf, read := bloomFilter.Read(toRead)
+	if bf == nil {
+		return errors.New("bloom filter is not valid or uses an unknown hash family")
+	}
+	sstable.Bf = bf
+	return sstable.deserializePrefixFilter(toRead[read:])
 }
 
-// param path: path to the folder where files will be saved;
-// param records: array of pointers to records from memtable;
-// param n: index degree, param m: summary degree;
-// returns error: if it occured during actions connected to files;
-func (sstable *SSTable) makeSeparateFiles(path string, records []*model.Record, n int, m int, compressionMap map[string]uint64) error {
-
-	data, _ := MakeFile(path, "Data")
-	summary, _ := MakeFile(path, "Summary")
-	index, _ := MakeFile(path, "Index")
-	filter, _ := MakeFile(path, "Filter")
-	merkle, _ := MakeFile(path, "Metadata")
-	if data != nil && index != nil && summary != nil {
-		sstable.Data = data
-		sstable.Index = index
-		sstable.Summary = summary
-
-		minKeyBytes := []byte(sstable.MinKey)
-		maxKeyBytes := []byte(sstable.MaxKey)
-
-		var minKeyLength uint64 = uint64(len(minKeyBytes))
-		var maxKeyLength uint64 = uint64(len(maxKeyBytes))
-
-		minKeyInfoSerialized := append(uint64ToBytes(minKeyLength), minKeyBytes...)
-		maxKeyInfoSerialized := append(uint64ToBytes(maxKeyLength), maxKeyBytes...)
-
-		var contentSummary [][]byte = [][]byte{minKeyInfoSerialized, maxKeyInfoSerialized}
-
-		contentData, err := sstable.serializeData(records, compressionMap)
+// reads the newest record timestamp from the end of the filter part, without reading the filters
+// the sstable files have to be open, MaxTimestamp stays 0 if the sstable was written before the timestamp was saved
+func (sstable *SSTable) LoadMaxTimestamp(separateFile bool, path string) error {
+	var file *os.File = sstable.Data
+	var end int64 = sstable.DataOffset
+	if separateFile {
+		var err error
+		file, err = os.Open(fmt.Sprintf("%s/%s%s", path, FILE_NAME, "Filter.db"))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		end, err = utils.GetFileLength(file)
 		if err != nil {
 			return err
 		}
-		var contentIndex [][]byte = sstable.serializeIndexSummary(contentData, n, sstable.CompressionOn)
-		contentSummary = append(contentSummary, sstable.serializeIndexSummary(contentIndex, m, sstable.CompressionOn)...)
-
-		var contentBf [][]byte = [][]byte{sstable.Bf.Serialize()}
-
-		var contentMerkle [][]byte = [][]byte{sstable.Merkle.Serialize()}
-
-		sstable.writeToFile(data, contentData)
-		sstable.writeToFile(index, contentIndex)
-		sstable.writeToFile(summary, contentSummary)
-		sstable.writeToFile(filter, contentBf)
-		sstable.writeToFile(merkle, contentMerkle)
-		return nil
 	}
-	return errors.New("error occured")
-}
-
-// function that calls every serialization
-// saves header one after the other -> length of min key, so we know how we need to read to get min key
-// same things is done for max key, then we saved offsets for data, index and summary
-func (sstable *SSTable) writeToSingleFile(records []*model.Record, n int, m int, compressionMap map[string]uint64) error {
-	var content [][]byte
-
-	if sstable.CompressionOn { // then w
-
+	if end-sstable.BfOffset < MAX_TIMESTAMP_SIZE {
+		return nil
 	}
 
-	minKeyBytes := []byte(sstable.MinKey)
-	maxKeyBytes := []byte(sstable.MaxKey)
-
-	var minKeyLength uint64 = uint64(len(minKeyBytes))
-	var maxKeyLength uint64 = uint64(len(maxKeyBytes))
-	var bfOffset uint64 = 7*8 + minKeyLength + maxKeyLength
-	var contentBf [][]byte = [][]byte{sstable.Bf.Serialize()}
-	var dataOffset uint64 = calculateOffset(contentBf, bfOffset)
-	contentData, err := sstable.serializeData(records, compressionMap)
+	toRead := make([]byte, MAX_TIMESTAMP_SIZE)
+	_, err := file.ReadAt(toRead, end-MAX_TIMESTAMP_SIZE)
 	if err != nil {
 		return err
 	}
-	var indexOffset uint64 = calculateOffset(contentData, dataOffset)
-	var contentIndex [][]byte = sstable.serializeIndexSummary(contentData, n, sstable.CompressionOn)
-	var summaryOffset uint64 = calculateOffset(contentIndex, indexOffset)
-	var contentSummary [][]byte = sstable.serializeIndexSummary(contentIndex, m, sstable.CompressionOn)
-	var merkleOffset uint64 = calculateOffset(contentSummary, summaryOffset)
-	var contentMerkle [][]byte = [][]byte{sstable.Merkle.Serialize()}
-
-	content = append(content, uint64ToBytes(minKeyLength))
-	content = append(content, minKeyBytes)
-	content = append(content, uint64ToBytes(maxKeyLength))
-	content = append(content, maxKeyBytes)
-	content = append(content, uint64ToBytes(bfOffset))
-	content = append(content, uint64ToBytes(dataOffset))
-	content = append(content, uint64ToBytes(indexOffset))
-	content = append(content, uint64ToBytes(summaryOffset))
-	content = append(content, uint64ToBytes(merkleOffset))
-	content = append(content, contentBf...)
-	content = append(content, contentData...)
-	content = append(content, contentIndex...)
-	content = append(content, contentSummary...)
-	content = append(content, contentMerkle...)
-
-	sstable.writeToFile(sstable.Data, content)
-	sstable.DataOffset = int64(dataOffset)
-	sstable.SummaryOffset = int64(summaryOffset)
-	sstable.BfOffset = int64(bfOffset)
-	sstable.MerkleOffset = int64(merkleOffset)
-	sstable.IndexOffset = int64(indexOffset)
-
+	sstable.MaxTimestamp, _ = splitMaxTimestamp(toRead)
 	return nil
 }
 
-// params: n - index degree, m - summary degree
-// makes sstable which is in single file, all *os.File in struct refer to same file
-// this function also returns error if it occured during actions connected to files
-func (sstable *SSTable) makeSingleFile(path string, records []*model.Record, n int, m int, compressionMap map[string]uint64) error {
-	file, err := MakeFile(path, "DataIndexSummary")
-	if err != nil {
-		return err
-	}
-	sstable.Index, sstable.Data, sstable.Summary = file, file, file
-	sstable.writeToSingleFile(records, n, m, compressionMap)
-	return nil
+// loads the bloom filter and the prefix filter of the sstable
+func (sstable *SSTable) LoadFilters(separateFile bool) error {
+	return sstable.loadBF(separateFile, sstable.Name)
 }
 
 // helper - makes files
-// used in function makeSeparateFiles and makeSingleFile
+// used by the sstable writer
 func MakeFile(path string, s string) (*os.File, error) {
 	file, err := os.OpenFile(fmt.Sprintf("%s/%s%s.db", path, FILE_NAME, s), os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
@@ -334,16 +258,6 @@ func MakeFile(path string, s string) (*os.File, error) {
 	return file, nil
 }
 
-// writes serialized content to file
-func (sstable *SSTable) writeToFile(file *os.File, arr [][]byte) {
-	var content []byte
-	for i := 0; i < len(arr); i++ {
-		content = append(content, arr[i]...)
-	}
-	file.Write(content)
-	defer file.Close()
-}
-
 func (sstable *SSTable) LoadMerkle(separateFile bool, path string) error {
 	var file *os.File
 	var err error
@@ -362,6 +276,9 @@ func (sstable *SSTable) LoadMerkle(separateFile bool, path string) error {
 		if err != nil {
 			return err
 		}
+		if sstable.MerkleOffset < 0 || sstable.MerkleOffset > fileSize {
+			return errors.New("offset of the merkle tree is past the end of the file")
+		}
 		toRead = make([]byte, fileSize-sstable.MerkleOffset)
 		file.Seek(sstable.MerkleOffset, 0)
 		_, err = io.ReadAtLeast(sstable.Data, toRead, len(toRead))
@@ -8,14 +8,6 @@ import (
 	"github.com/natasakasikovic/Key-Value-engine/src/utils"
 )
 
-// this functions calculates lenght of bytes for forwarded content and returns it
-func calculateOffset(content [][]byte, offset uint64) uint64 {
-	for i := 0; i < len(content); i++ {
-		offset += uint64(len(content[i]))
-	}
-	return uint64(offset)
-}
-
 // gets key - made to read key from records, but also from index block
 func getKey(item []byte, compressionOn bool) (uint64, []byte) {
 	if compressionOn {
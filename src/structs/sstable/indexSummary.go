@@ -91,22 +91,15 @@ func (sstable *SSTable) searchIndexCompressed(file *os.File, offset1 int, offset
 	return targetOffset1, targetOffset2, nil
 }
 
-func (sstable *SSTable) serializeIndexSummary(content [][]byte, n int, compressed bool) [][]byte {
-	var retVal [][]byte
-	var offset uint64 = 0
-	for i := 0; i < len(content); i++ {
-		if i%n == 0 {
-			keySize, key := getKey(content[i], compressed)
-			var buffer bytes.Buffer
-			if !compressed {
-				binary.Write(&buffer, binary.BigEndian, keySize) // write key size if not compressed
-			}
-			binary.Write(&buffer, binary.BigEndian, key)
-			binary.Write(&buffer, binary.BigEndian, offset)
-			retVal = append(retVal, buffer.Bytes())
-		}
-		offset += uint64(len(content[i]))
-
+// serializes one index/summary entry - key of the passed item and offset on which the item begins
+// item can be a serialized record (for index) or a serialized index entry (for summary)
+func serializeIndexEntry(item []byte, offset uint64, compressed bool) []byte {
+	keySize, key := getKey(item, compressed)
+	var buffer bytes.Buffer
+	if !compressed {
+		binary.Write(&buffer, binary.BigEndian, keySize) // write key size if not compressed
 	}
-	return retVal
+	binary.Write(&buffer, binary.BigEndian, key)
+	binary.Write(&buffer, binary.BigEndian, offset)
+	return buffer.Bytes()
 }
@@ -0,0 +1,138 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/bloomFilter"
+)
+
+// kinds of prefix extractors
+const (
+	PREFIX_NONE      = "none"
+	PREFIX_FIXED     = "fixed"     // prefix is the first Length bytes of the key
+	PREFIX_DELIMITER = "delimiter" // prefix is the key up to and including the first Delimiter
+)
+
+// PrefixExtractor takes the part of a key that is saved in the prefix bloom filter of an sstable
+// Keys that are shorter than Length, or don't contain Delimiter, have no prefix
+type PrefixExtractor struct {
+	Type      string
+	Length    int
+	Delimiter string
+}
+
+func (extractor PrefixExtractor) IsOn() bool {
+	return (extractor.Type == PREFIX_FIXED && extractor.Length > 0) ||
+		(extractor.Type == PREFIX_DELIMITER && extractor.Delimiter != "")
+}
+
+// returns the prefix of the key and true, or false if the key has no prefix
+// it works for prefixes that are scanned for as well: if it returns a prefix for the scanned prefix,
+// every key that begins with the scanned prefix has that same prefix
+func (extractor PrefixExtractor) Extract(key string) (string, bool) {
+	switch extractor.Type {
+	case PREFIX_FIXED:
+		if extractor.Length <= 0 || len(key) < extractor.Length {
+			return "", false
+		}
+		return key[:extractor.Length], true
+	case PREFIX_DELIMITER:
+		if extractor.Delimiter == "" {
+			return "", false
+		}
+		index := strings.Index(key, extractor.Delimiter)
+		if index == -1 {
+			return "", false
+		}
+		return key[:index+len(extractor.Delimiter)], true
+	default:
+		return "", false
+	}
+}
+
+// type length, type, length, delimiter length, delimiter
+func (extractor PrefixExtractor) serialize() []byte {
+	var content []byte
+	content = append(content, uint64ToBytes(uint64(len(extractor.Type)))...)
+	content = append(content, extractor.Type...)
+	content = append(content, uint64ToBytes(uint64(extractor.Length))...)
+	content = append(content, uint64ToBytes(uint64(len(extractor.Delimiter)))...)
+	content = append(content, extractor.Delimiter...)
+	return content
+}
+
+// returns the extractor and number of bytes read
+func deserializePrefixExtractor(bytes []byte) (PrefixExtractor, int, error) {
+	var extractor PrefixExtractor
+	var offset int = 0
+	readString := func() (string, error) {
+		if len(bytes) < offset+8 {
+			return "", errors.New("prefix filter is not valid")
+		}
+		size := binary.BigEndian.Uint64(bytes[offset : offset+8])
+		offset += 8
+		if size > uint64(len(bytes)-offset) {
+			return "", errors.New("prefix filter is not valid")
+		}
+		offset += int(size)
+		return string(bytes[offset-int(size) : offset]), nil
+	}
+
+	var err error
+	extractor.Type, err = readString()
+	if err != nil {
+		return extractor, 0, err
+	}
+	if len(bytes) < offset+8 {
+		return extractor, 0, errors.New("prefix filter is not valid")
+	}
+	extractor.Length = int(binary.BigEndian.Uint64(bytes[offset : offset+8]))
+	offset += 8
+	extractor.Delimiter, err = readString()
+	if err != nil {
+		return extractor, 0, err
+	}
+	return extractor, offset, nil
+}
+
+// returns the bytes saved after the bloom filter: the prefix extractor and the prefix bloom filter
+// nothing is saved if the sstable has no prefix filter, so sstables without it are read the same as the old ones
+func (sstable *SSTable) serializePrefixFilter() []byte {
+	if sstable.PrefixBf == nil {
+		return []byte{}
+	}
+	return append(sstable.PrefixExtractor.serialize(), sstable.PrefixBf.Serialize()...)
+}
+
+// reads the prefix filter written after the bloom filter, if there are no bytes the sstable has no prefix filter
+func (sstable *SSTable) deserializePrefixFilter(bytes []byte) error {
+	if len(bytes) == 0 {
+		return nil
+	}
+	extractor, read, err := deserializePrefixExtractor(bytes)
+	if err != nil {
+		return err
+	}
+	prefixBf := bloomFilter.Deserialize(bytes[read:])
+	if prefixBf == nil {
+		return errors.New("prefix filter is not valid")
+	}
+	sstable.PrefixExtractor = extractor
+	sstable.PrefixBf = prefixBf
+	return nil
+}
+
+// returns false if the sstable surely has no key beginning with the prefix
+// sstables without a prefix filter, and prefixes the extractor can't shorten, always return true
+func (sstable *SSTable) MayContainPrefix(prefix string) bool {
+	if sstable.PrefixBf == nil {
+		return true
+	}
+	extracted, ok := sstable.PrefixExtractor.Extract(prefix)
+	if !ok {
+		return true
+	}
+	return sstable.PrefixBf.Find(extracted)
+}
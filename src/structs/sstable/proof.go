@@ -0,0 +1,141 @@
+package sstable
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
+	"github.com/natasakasikovic/Key-Value-engine/src/utils"
+)
+
+// RecordProof proves that the record is saved in the sstable whose merkle tree has the root
+type RecordProof struct {
+	Table    string
+	Record   *model.Record
+	HashType merkletree.HashType // hash of the tree
+	Root     []byte
+	Leaves   int // number of leaves of the tree
+	Proof    *merkletree.Proof
+}
+
+// returns the content of the record's leaf in merkle trees that aren't legacy: the record serialized without compression
+// sizes are set from the key and value, since records read from compressed sstables don't have them
+func merkleLeafContent(record *model.Record) ([]byte, error) {
+	leaf := *record
+	leaf.KeySize = uint64(len(record.Key))
+	leaf.ValueSize = uint64(len(record.Value))
+	return leaf.Serialize(false, nil)
+}
+
+// MerkleLeaf returns the content the leaf of the record was made from in the tree
+// leaves of legacy trees are records as they are saved in the sstable, so they depend on compression
+func MerkleLeaf(tree *merkletree.MerkleTree, record *model.Record, compressionOn bool, compressionMap map[string]uint64) ([]byte, error) {
+	if tree.IsLegacy() {
+		return record.Serialize(compressionOn, compressionMap)
+	}
+	return merkleLeafContent(record)
+}
+
+// Verify returns true if the record belongs to the tree with the passed hash, root and number of leaves,
+// which should be ones the client trusts
+func (proof *RecordProof) Verify(hashType merkletree.HashType, root []byte, leaves int) bool {
+	if proof.Record == nil {
+		return false
+	}
+	content, err := merkleLeafContent(proof.Record)
+	if err != nil {
+		return false
+	}
+	return merkletree.VerifyProof(hashType, root, leaves, content, proof.Proof)
+}
+
+// LoadTable loads the sstable with the passed name from the sstable directory, with its merkle tree
+// files of the returned sstable are open
+func LoadTable(dirName string) (*SSTable, error) {
+	path := fmt.Sprintf("%s/%s", PATH, dirName)
+	content, err := utils.GetDirContent(path)
+	if err != nil {
+		return nil, err
+	}
+	var table *SSTable
+	if len(content) == 1 {
+		table, err = LoadSStableSingle(path)
+	} else {
+		table, err = LoadSSTableSeparate(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	table.Name = dirName
+	err = table.LoadMerkle(len(content) != 1, path)
+	if err == nil && table.Merkle == nil {
+		err = errors.New("merkle tree of the sstable is not valid")
+	}
+	if err != nil {
+		table.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+// closes files of the sstable
+func (sstable *SSTable) Close() {
+	sstable.Data.Close()
+	sstable.Index.Close()
+	sstable.Summary.Close()
+}
+
+// Returns the offsets between which records of the sstable are
+func (sstable *SSTable) DataRange() (int64, int64, error) {
+	if sstable.Data == sstable.Index {
+		return sstable.DataOffset, sstable.IndexOffset, nil
+	}
+	end, err := utils.GetFileLength(sstable.Data)
+	return sstable.DataOffset, end, err
+}
+
+// ProveRecord returns the proof that the record with the key is saved in the sstable with the passed name
+// returns nil if the key is not in the sstable, records are read one by one, since a leaf is found by the record's position
+func ProveRecord(dirName string, key string, compressionOn bool, compressionMap map[string]uint64) (*RecordProof, error) {
+	table, err := LoadTable(dirName)
+	if err != nil {
+		return nil, err
+	}
+	defer table.Close()
+	// leaves of legacy trees aren't made the way proofs are verified, the sstable gets a new tree when it is compacted
+	if table.Merkle.IsLegacy() {
+		return nil, errors.New("sstable was written before proofs were supported")
+	}
+	if key < table.MinKey || key > table.MaxKey {
+		return nil, nil
+	}
+
+	offset, end, err := table.DataRange()
+	if err != nil {
+		return nil, err
+	}
+	_, err = table.Data.Seek(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	for index := 0; offset < end; index++ {
+		record, bytesRead, err := model.Deserialize(table.Data, compressionOn, compressionMap)
+		if err != nil {
+			return nil, err
+		}
+		offset += int64(bytesRead)
+		if record.Key > key {
+			return nil, nil
+		}
+		if record.Key == key {
+			proof, err := table.Merkle.Proof(index)
+			if err != nil {
+				return nil, err
+			}
+			return &RecordProof{Table: dirName, Record: record, HashType: table.Merkle.HashType(), Root: table.Merkle.RootHash(),
+				Leaves: table.Merkle.Leaves(), Proof: proof}, nil
+		}
+	}
+	return nil, nil
+}
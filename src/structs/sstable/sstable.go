@@ -1,8 +1,10 @@
 package sstable
 
 import (
+	"encoding/binary"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/natasakasikovic/Key-Value-engine/src/model"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/bloomFilter"
@@ -16,6 +18,7 @@ const (
 	PATH             = "../data/sstable"
 	START_COUNTER    = "0001"
 	COMPRESSION_PATH = "../data/compressionInfo"
+	QUARANTINE_PATH  = "../data/quarantine" // damaged sstables are moved here by verify
 )
 
 type SSTable struct {
@@ -25,174 +28,202 @@ type SSTable struct {
 	MinKey, MaxKey, Name                                           string
 	DataOffset, IndexOffset, SummaryOffset, MerkleOffset, BfOffset int64
 	CompressionOn                                                  bool
+	PrefixBf                                                       *bloomFilter.BloomFilter // nil if the sstable has no prefix filter
+	PrefixExtractor                                                PrefixExtractor
+	MaxTimestamp                                                   uint64 // newest record timestamp, 0 if not known yet
 }
 
-// function that creates a new sstable
-// returns pointer to sstable if it is successfully created, otherwise returns an error
-func CreateSStable(records []*model.Record, singleFile, compressionOn bool, indexDegree, summaryDegree int, compressionMap map[string]uint64) (*SSTable, error) {
+// The newest record timestamp is saved at the end of the filter part, followed by MAX_TIMESTAMP_MAGIC
+// Sstables written before it was saved don't end with the magic number, their newest timestamp is found by reading the records
+const (
+	MAX_TIMESTAMP_MAGIC uint64 = 0x6d617874696d6531 // "maxtime1"
+	MAX_TIMESTAMP_SIZE         = 2 * 8
+)
 
-	sstable := SSTable{}
-	dirNames, err := utils.GetDirContent(PATH)
+// returns the newest record timestamp and the magic number, written after the filters
+func (sstable *SSTable) serializeMaxTimestamp() []byte {
+	return append(uint64ToBytes(sstable.MaxTimestamp), uint64ToBytes(MAX_TIMESTAMP_MAGIC)...)
+}
 
-	if err != nil {
-		return nil, err
+// returns the newest record timestamp saved at the end of the filter part and the filters before it
+// if the timestamp isn't saved, returns 0 and all bytes
+func splitMaxTimestamp(bytes []byte) (uint64, []byte) {
+	if len(bytes) < MAX_TIMESTAMP_SIZE || binary.BigEndian.Uint64(bytes[len(bytes)-8:]) != MAX_TIMESTAMP_MAGIC {
+		return 0, bytes
 	}
+	end := len(bytes) - MAX_TIMESTAMP_SIZE
+	return binary.BigEndian.Uint64(bytes[end : end+8]), bytes[:end]
+}
 
-	var path string
-	if len(dirNames) == 0 {
-		path = fmt.Sprintf("%s/%s%s", PATH, DIR_NAME, START_COUNTER)
-		dirNames = append(dirNames, fmt.Sprintf("%s%s", DIR_NAME, START_COUNTER))
-	} else {
-		dirNames, path, err = utils.GetNextContentName(dirNames, PATH, DIR_NAME)
+// false positive rate of the bloom filter, used if bits per key are not set
+const FALSE_POSITIVE_RATE = 0.001
+
+// FilterConfig says how the bloom filter and the merkle tree of an sstable are made
+// The kind of filter and the hash of the tree are saved with them, so sstables are read correctly whatever the config is
+type FilterConfig struct {
+	Blocked    bool    // use the cache-line blocked bloom filter
+	BitsPerKey float64 // 0 means the filter is sized for FALSE_POSITIVE_RATE
+	Prefix     PrefixExtractor
+	MerkleHash merkletree.HashType // 0 means merkletree.DEFAULT_HASH
+}
+
+// returns an empty bloom filter for n elements, made as the config says
+func (filter FilterConfig) newBF(n int) *bloomFilter.BloomFilter {
+	if filter.BitsPerKey > 0 {
+		return bloomFilter.NewBfBitsPerKey(n, filter.BitsPerKey, filter.Blocked)
+	} else if filter.Blocked {
+		return bloomFilter.NewBlockedBf(n, FALSE_POSITIVE_RATE)
+	}
+	return bloomFilter.NewBf(n, FALSE_POSITIVE_RATE)
+}
+
+// function that creates a new sstable from records sorted by key
+// returns pointer to sstable if it is successfully created, otherwise returns an error
+func CreateSStable(records []*model.Record, singleFile, compressionOn bool, indexDegree, summaryDegree int, compressionMap map[string]uint64, filter FilterConfig) (*SSTable, error) {
+	writer := NewWriter(singleFile, compressionOn, indexDegree, summaryDegree, compressionMap, filter)
+	for _, record := range records {
+		err := writer.Add(record)
 		if err != nil {
+			writer.Abort()
 			return nil, err
 		}
 	}
+	return writer.Finish()
+}
 
-	err = os.Mkdir(path, os.ModeDir)
+// returns nil as first param if record is not found
+// second param returns error if it occured during actions connected to files, otherwise returns nil
+func Search(key string, compressionMap map[string]uint64) (*model.Record, error) {
+	dirContent, err := utils.GetDirContent(PATH) // dirContent - names of all sstables dirs
 	if err != nil {
 		return nil, err
 	}
-	sstable.Name = dirNames[len(dirNames)-1]
 
-	if compressionOn {
-		sstable.CompressionOn = true
-		dictionaryEncodingOn(records, compressionMap)
+	var names []string
+	for i := len(dirContent) - 1; i >= 0; i-- { // search through all sstables, started from newest one
+		names = append(names, dirContent[i])
 	}
+	return SearchTables(names, key, compressionMap)
+}
 
-	sstable.Bf = bloomFilter.NewBf(len(records), 0.001)
-	for _, record := range records {
-		sstable.Bf.Insert(record.Key)
+// searches the sstables with passed names in the given order, and returns the first record found
+// returns nil as first param if record is not found
+// the returned record can be deleted - it hides older versions of the record, so the search stops on it
+func SearchTables(names []string, key string, compressionMap map[string]uint64) (*model.Record, error) {
+	for _, dirName := range names {
+		record, err := searchTable(dirName, key, compressionMap)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			return record, nil
+		}
 	}
-	sstable.MinKey = records[0].Key
-	sstable.MaxKey = records[len(records)-1].Key
+	return nil, nil
+}
 
-	content, err := sstable.serializeData(records, compressionMap)
+// searches one sstable, returns nil as first param if record is not in the sstable
+func searchTable(dirName string, key string, compressionMap map[string]uint64) (*model.Record, error) {
+	path := fmt.Sprintf("%s/%s", PATH, dirName)
+	content, err := utils.GetDirContent(path) // get content of sstable, so we can check if sstable is in single file or in seperate files
 	if err != nil {
 		return nil, err
 	}
-	sstable.Merkle, _ = merkletree.NewTree(content)
 
-	if singleFile {
-		err = sstable.makeSingleFile(path, records, indexDegree, summaryDegree, compressionMap)
+	var sstable *SSTable
+
+	if len(content) == 1 {
+		sstable, err = LoadSStableSingle(path)
 	} else {
-		err = sstable.makeSeparateFiles(path, records, indexDegree, summaryDegree, compressionMap)
+		sstable, err = LoadSSTableSeparate(path)
 	}
-
 	if err != nil {
 		return nil, err
 	}
-	return &sstable, nil
-}
+	defer sstable.Data.Close()
+	defer sstable.Index.Close()
+	defer sstable.Summary.Close()
 
-// returns nil as first param if record is not found
-// second param returns error if it occured during actions connected to files, otherwise returns nil
-func Search(key string, compressionMap map[string]uint64) (*model.Record, error) {
-	dirContent, err := utils.GetDirContent(PATH) // dirContent - names of all sstables dirs
+	// check if compression is on (if there is a file CompressionInfo.db in folder, then compression is on
+	retVal, err := utils.EmptyDir(COMPRESSION_PATH)
 	if err != nil {
 		return nil, err
 	}
+	sstable.CompressionOn = !retVal
 
-	for i := len(dirContent) - 1; i >= 0; i-- { // search through all sstables, started from newest one
-		var dirName string = dirContent[i] // one sstable
-		path := fmt.Sprintf("%s/%s", PATH, dirName)
-		content, err := utils.GetDirContent(path) // get content of sstable, so we can check if sstable is in single file or in seperate files
-		if err != nil {
-			return nil, err
-		}
-
-		var sstable *SSTable
-
-		if len(content) == 1 {
-			sstable, err = LoadSStableSingle(path)
-		} else {
-			sstable, err = LoadSSTableSeparate(path)
-		}
-		if err != nil {
-			if sstable.Data != nil {
-				sstable.Data.Close()
-			}
-			if sstable.Summary != nil {
-				sstable.Summary.Close()
-			}
-			if sstable.Index != nil {
-				sstable.Index.Close()
-			}
-
-			return nil, err
-		}
-		defer sstable.Data.Close()
-		defer sstable.Index.Close()
-		defer sstable.Summary.Close()
-
-		// check if compression is on (if there is a file CompressionInfo.db in folder, then compression is on
-		retVal, err := utils.EmptyDir(COMPRESSION_PATH)
-		if err != nil {
-			return nil, err
-		}
-		sstable.CompressionOn = !retVal
-
-		sstable.Name = dirName
+	sstable.Name = dirName
 
-		sstable.loadBF(len(content) != 1, dirName) // first ask bloomfilter
-		if !sstable.Bf.Find(key) {                 // then record is not in this sstable, go to next sstable
-			continue
-		}
-		if key < sstable.MinKey || key > sstable.MaxKey { // if key is not in range of sstable, go to next sstable
-			continue
-		}
+	err = sstable.loadBF(len(content) != 1, dirName) // first ask bloomfilter
+	if err != nil {
+		return nil, err
+	}
+	if !sstable.Bf.Find(key) { // then record is not in this sstable, go to next sstable
+		return nil, nil
+	}
+	if key < sstable.MinKey || key > sstable.MaxKey { // if key is not in range of sstable, go to next sstable
+		return nil, nil
+	}
 
-		var endingOffset int = sstable.getEndingOffsetSummary(len(content) == 1)
+	var endingOffset int = sstable.getEndingOffsetSummary(len(content) == 1)
 
-		// offset1 and offset2 are offsets between which we should search index
-		offset1, offset2, err := sstable.searchIndex(sstable.Summary, int(sstable.SummaryOffset), endingOffset, key, compressionMap)
+	// offset1 and offset2 are offsets between which we should search index
+	offset1, offset2, err := sstable.searchIndex(sstable.Summary, int(sstable.SummaryOffset), endingOffset, key, compressionMap)
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		if offset2 == 0 { // this means that we need to search until the end of index
-			offset2 = uint64(sstable.SummaryOffset) - uint64(sstable.IndexOffset) // this is the size of index
-		} else { // in other case we need to read next value
-			sstable.Index.Seek(int64(offset2+uint64(sstable.IndexOffset)), 0)
-			var bytesRead int
-			if sstable.CompressionOn {
-				_, _, bytesRead, err = readBlockCompressed(sstable.Index)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				_, _, bytesRead, err = readBlock(sstable.Index)
-				if err != nil {
-					return nil, err
-				}
+	if offset2 == 0 { // this means that we need to search until the end of index
+		offset2 = uint64(sstable.SummaryOffset) - uint64(sstable.IndexOffset) // this is the size of index
+	} else { // in other case we need to read next value
+		sstable.Index.Seek(int64(offset2+uint64(sstable.IndexOffset)), 0)
+		var bytesRead int
+		if sstable.CompressionOn {
+			_, _, bytesRead, err = readBlockCompressed(sstable.Index)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			_, _, bytesRead, err = readBlock(sstable.Index)
+			if err != nil {
+				return nil, err
 			}
-			offset2 += uint64(bytesRead) // we need to increase offset2, so we can read one more value while searching in index
 		}
+		offset2 += uint64(bytesRead) // we need to increase offset2, so we can read one more value while searching in index
+	}
 
-		offset1 += uint64(sstable.IndexOffset) // if it is single file starting index offset is ok
-		offset2 = getEndingOffset(len(content) == 1, sstable.Index, sstable.IndexOffset, sstable.SummaryOffset, int64(offset2))
+	offset1 += uint64(sstable.IndexOffset) // if it is single file starting index offset is ok
+	offset2 = getEndingOffset(len(content) == 1, sstable.Index, sstable.IndexOffset, sstable.SummaryOffset, int64(offset2))
 
-		// offset1 and offset2 are offsets between which we should search data
-		offset1, offset2, err = sstable.searchIndex(sstable.Index, int(offset1), int(offset2), key, compressionMap)
+	// offset1 and offset2 are offsets between which we should search data
+	offset1, offset2, err = sstable.searchIndex(sstable.Index, int(offset1), int(offset2), key, compressionMap)
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		offset1 += uint64(sstable.DataOffset) // if it is single file starting index offste is okay
-		offset2 = getEndingOffset(len(content) == 1, sstable.Data, sstable.DataOffset, sstable.IndexOffset, int64(offset2))
+	offset1 += uint64(sstable.DataOffset) // if it is single file starting index offste is okay
+	offset2 = getEndingOffset(len(content) == 1, sstable.Data, sstable.DataOffset, sstable.IndexOffset, int64(offset2))
 
-		record, err := sstable.searchData(len(content) == 1, int(offset1), int(offset2), key, compressionMap)
+	return sstable.searchData(len(content) == 1, int(offset1), int(offset2), key, compressionMap)
+}
 
+// returns number of bytes that all files of the sstable take on disk
+func (sstable *SSTable) Size() (int64, error) {
+	path := fmt.Sprintf("%s/%s", PATH, sstable.Name)
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return 0, err
+	}
+	var size int64 = 0
+	for _, file := range files {
+		info, err := file.Info()
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
-
-		return record, nil
+		size += info.Size()
 	}
-
-	return nil, nil
+	return size, nil
 }
 
 // deletes sstable folder, returns error if it occured during deletion
@@ -229,14 +260,46 @@ func (sstable *SSTable) Delete() error {
 	if err != nil {
 		return err
 	}
-	// rename all folders after deleted one
+	return shiftFolders(dirContent, i)
+}
+
+// rename all folders after the removed one, so names of sstables stay one after the other
+func shiftFolders(dirContent []string, i int) error {
 	for j := i; j < len(dirContent)-1; j++ {
 		new_name := fmt.Sprintf("%s/%s", PATH, dirContent[j])
 		old_name := fmt.Sprintf("%s/%s", PATH, dirContent[j+1])
-		err = os.Rename(old_name, new_name)
+		err := os.Rename(old_name, new_name)
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// moves the sstable folder with the passed name to the quarantine folder, where it is kept for inspection
+// folders after it are renamed the same way as when an sstable is deleted
+// returns the path the folder was moved to
+func Quarantine(dirName string) (string, error) {
+	dirContent, err := utils.GetDirContent(PATH)
+	if err != nil {
+		return "", err
+	}
+	i := 0
+	for i < len(dirContent) && dirContent[i] != dirName {
+		i++
+	}
+	if i == len(dirContent) {
+		return "", fmt.Errorf("sstable %s does not exist", dirName)
+	}
+
+	err = os.MkdirAll(QUARANTINE_PATH, 0755)
+	if err != nil {
+		return "", err
+	}
+	destination := fmt.Sprintf("%s/%s_%d", QUARANTINE_PATH, dirName, time.Now().UnixNano())
+	err = os.Rename(fmt.Sprintf("%s/%s", PATH, dirName), destination)
+	if err != nil {
+		return "", err
+	}
+	return destination, shiftFolders(dirContent, i)
+}
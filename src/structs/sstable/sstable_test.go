@@ -0,0 +1,158 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/bloomFilter"
+)
+
+const BENCHMARK_RECORDS = 10000
+
+// Sstables are saved to ../data, so every test and benchmark runs in its own directory with an empty data directory next to it
+func useTempData(b testing.TB) {
+	root := b.TempDir()
+	for _, dir := range []string{"src", "data/sstable", "data/compressionInfo"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			b.Fatal(err)
+		}
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(root, "src")); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { os.Chdir(previous) })
+}
+
+// writes an sstable with keys key00000, key00002... in separate files, so its filter can be replaced
+func writeBenchmarkTable(b *testing.B) *SSTable {
+	records := make([]*model.Record, BENCHMARK_RECORDS)
+	for i := range records {
+		records[i] = model.NewRecordTimestamp(0, fmt.Sprintf("key%05d", 2*i), []byte("value"), uint64(i+1))
+	}
+	table, err := CreateSStable(records, false, false, 5, 5, map[string]uint64{}, FilterConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return table
+}
+
+// replaces the filter of the table with one made with the md5 family, which filters written before xxhash use
+func useSeededFilter(b *testing.B, table *SSTable) {
+	path := fmt.Sprintf("%s/%s/%s%s", PATH, table.Name, FILE_NAME, "Filter.db")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	current, read := bloomFilter.Read(content)
+	if current == nil {
+		b.Fatal("filter of the sstable can't be read")
+	}
+
+	// the old format is the bitset length, number of hash functions, their md5 seeds and the bitset
+	m, k := binary.BigEndian.Uint32(content[8:12]), binary.BigEndian.Uint32(content[12:16])
+	old := binary.BigEndian.AppendUint32(nil, m)
+	old = binary.BigEndian.AppendUint32(old, k)
+	for i := uint32(0); i < k; i++ {
+		old = binary.BigEndian.AppendUint32(old, 0x9e3779b9*(i+1))
+	}
+	old = append(old, make([]byte, m/8+1)...)
+	seeded := bloomFilter.Deserialize(old)
+	for i := 0; i < BENCHMARK_RECORDS; i++ {
+		seeded.Insert(fmt.Sprintf("key%05d", 2*i))
+	}
+
+	// the prefix filter is saved after the bloom filter
+	err = os.WriteFile(path, append(seeded.Serialize(), content[read:]...), 0644)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+// Searches for keys that are in the table and keys that aren't, most reads of an sstable are the second kind,
+// which only the filter answers
+func BenchmarkSearch(b *testing.B) {
+	for _, family := range []string{"XXHASH_DOUBLE", "MD5_SEEDED"} {
+		b.Run(family, func(b *testing.B) {
+			useTempData(b)
+			table := writeBenchmarkTable(b)
+			if family == "MD5_SEEDED" {
+				useSeededFilter(b, table)
+			}
+			for _, key := range []string{"key00000", "key00001"} {
+				record, err := Search(key, map[string]uint64{})
+				if err != nil || (record != nil) != (key == "key00000") {
+					b.Fatalf("search of %s returned %v, %v", key, record, err)
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Search(fmt.Sprintf("key%05d", i%(2*BENCHMARK_RECORDS)), map[string]uint64{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Damaged sizes and offsets are reported as problems of the table, reading them mustn't panic
+func TestCheckTableReportsDamage(t *testing.T) {
+	useTempData(t)
+	records := []*model.Record{
+		model.NewRecordTimestamp(0, "a", []byte("1"), 1),
+		model.NewRecordTimestamp(0, "b", []byte("2"), 2),
+		model.NewRecordTimestamp(1, "c", []byte{}, 3),
+	}
+	table, err := CreateSStable(records, true, false, 2, 2, map[string]uint64{}, FilterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check := CheckTable(table.Name, false, map[string]uint64{}); len(check.Problems) != 0 || check.Records != 3 {
+		t.Fatalf("table has problems before it was damaged: %v", check)
+	}
+
+	path := fmt.Sprintf("%s/%s/%s%s", PATH, table.Name, FILE_NAME, "DataIndexSummary.db")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerSize := 7*8 + 2
+	bfOffset, dataOffset := int(table.BfOffset), int(table.DataOffset)
+	setUint64 := func(at int, value uint64) func([]byte) []byte {
+		return func(content []byte) []byte {
+			binary.BigEndian.PutUint64(content[at:at+8], value)
+			return content
+		}
+	}
+	for name, damage := range map[string]func([]byte) []byte{
+		"min key longer than the file":         setUint64(0, 1<<62),
+		"max key longer than the file":         setUint64(9, 1<<40),
+		"filter offset past the file":          setUint64(headerSize-5*8, 1<<63),
+		"data offset before the filter":        setUint64(headerSize-4*8, 1),
+		"merkle offset past the file":          setUint64(headerSize-8, uint64(len(original))+1),
+		"filter with no bits":                  setUint64(bfOffset+8, 0),
+		"key of a record longer than the file": setUint64(dataOffset, 1<<62),
+		"value of a record longer than the file": func(content []byte) []byte {
+			// key size, key, crc, timestamp and tombstone come before the value size
+			return setUint64(dataOffset+8+1+4+8+1, math.MaxUint64)(content)
+		},
+		"truncated": func(content []byte) []byte { return content[:dataOffset+10] },
+	} {
+		damaged := damage(append([]byte{}, original...))
+		if err := os.WriteFile(path, damaged, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if check := CheckTable(table.Name, false, map[string]uint64{}); len(check.Problems) == 0 {
+			t.Fatalf("%s: no problems were found", name)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package sstable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/bloomFilter"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
+	"github.com/natasakasikovic/Key-Value-engine/src/utils"
+)
+
+// names of the temporary files used while the sstable is being written
+// they are deleted when the writer finishes
+const (
+	TEMP_DATA     = "TempData"
+	TEMP_INDEX    = "TempIndex"
+	TEMP_KEYS     = "TempKeys"
+	TEMP_PREFIXES = "TempPrefixes"
+)
+
+// Writer builds a new sstable record by record
+// Records are written to disk as soon as they are added, so the caller doesn't need to hold all of them in memory
+// Only the summary and the hashes of merkle leaves are kept in memory until the sstable is finished
+// Records MUST be added sorted by key
+type Writer struct {
+	sstable        *SSTable
+	path           string
+	singleFile     bool
+	indexDegree    int
+	summaryDegree  int
+	compressionMap map[string]uint64
+	filter         FilterConfig
+
+	// if the sstable is in a single file, data and index are written to temporary files
+	// and copied into the final file when the writer finishes, because the header has to be written first
+	dataFile, indexFile, keysFile, prefixesFile *os.File
+	data, index, keys, prefixes                 *bufio.Writer
+
+	dataSize    uint64 // number of bytes written to data
+	indexSize   uint64 // number of bytes written to index
+	recordCount int
+	indexCount  int
+	prefixCount int    // number of different prefixes written to the prefixes file
+	lastPrefix  string // prefixes of sorted keys come in order, so only the last one is needed to skip repeated ones
+	summary     [][]byte
+	leafHashes  [][]byte
+}
+
+// returns a new writer, sstable folder is created when the first record is added
+func NewWriter(singleFile, compressionOn bool, indexDegree, summaryDegree int, compressionMap map[string]uint64, filter FilterConfig) *Writer {
+	return &Writer{
+		sstable:        &SSTable{CompressionOn: compressionOn},
+		singleFile:     singleFile,
+		indexDegree:    indexDegree,
+		summaryDegree:  summaryDegree,
+		compressionMap: compressionMap,
+		filter:         filter,
+	}
+}
+
+// returns number of records added to the writer
+func (w *Writer) Count() int {
+	return w.recordCount
+}
+
+// returns number of bytes of records written to the data so far
+func (w *Writer) DataSize() uint64 {
+	return w.dataSize
+}
+
+// makes a folder for the new sstable and opens files that records are written to
+func (w *Writer) open() error {
+	dirNames, err := utils.GetDirContent(PATH)
+	if err != nil {
+		return err
+	}
+
+	var path string
+	if len(dirNames) == 0 {
+		path = fmt.Sprintf("%s/%s%s", PATH, DIR_NAME, START_COUNTER)
+		dirNames = append(dirNames, fmt.Sprintf("%s%s", DIR_NAME, START_COUNTER))
+	} else {
+		dirNames, path, err = utils.GetNextContentName(dirNames, PATH, DIR_NAME)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Mkdir(path, os.ModeDir)
+	if err != nil {
+		return err
+	}
+	w.path = path
+	w.sstable.Name = dirNames[len(dirNames)-1]
+
+	dataName, indexName := "Data", "Index"
+	if w.singleFile {
+		dataName, indexName = TEMP_DATA, TEMP_INDEX
+	}
+
+	w.dataFile, err = MakeFile(path, dataName)
+	if err != nil {
+		return err
+	}
+	w.indexFile, err = MakeFile(path, indexName)
+	if err != nil {
+		return err
+	}
+	w.keysFile, err = MakeFile(path, TEMP_KEYS)
+	if err != nil {
+		return err
+	}
+	if w.filter.Prefix.IsOn() {
+		w.prefixesFile, err = MakeFile(path, TEMP_PREFIXES)
+		if err != nil {
+			return err
+		}
+		w.prefixes = bufio.NewWriter(w.prefixesFile)
+	}
+
+	w.data = bufio.NewWriter(w.dataFile)
+	w.index = bufio.NewWriter(w.indexFile)
+	w.keys = bufio.NewWriter(w.keysFile)
+	return nil
+}
+
+// writes the record to the sstable
+// every indexDegree-th record gets an index entry, and every summaryDegree-th index entry gets a summary entry
+func (w *Writer) Add(record *model.Record) error {
+	if w.dataFile == nil {
+		err := w.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.recordCount > 0 && record.Key <= w.sstable.MaxKey {
+		return errors.New("records must be added to the sstable sorted by key")
+	}
+
+	if w.sstable.CompressionOn {
+		dictionaryEncodeKey(record.Key, w.compressionMap)
+	}
+
+	recordBytes, err := record.Serialize(w.sstable.CompressionOn, w.compressionMap)
+	if err != nil {
+		return err
+	}
+
+	if w.recordCount == 0 {
+		w.sstable.MinKey = record.Key
+	}
+	w.sstable.MaxKey = record.Key
+	if record.Timestamp > w.sstable.MaxTimestamp {
+		w.sstable.MaxTimestamp = record.Timestamp
+	}
+
+	if w.recordCount%w.indexDegree == 0 {
+		indexEntry := serializeIndexEntry(recordBytes, w.dataSize, w.sstable.CompressionOn)
+		if w.indexCount%w.summaryDegree == 0 {
+			w.summary = append(w.summary, serializeIndexEntry(indexEntry, w.indexSize, w.sstable.CompressionOn))
+		}
+		_, err = w.index.Write(indexEntry)
+		if err != nil {
+			return err
+		}
+		w.indexSize += uint64(len(indexEntry))
+		w.indexCount++
+	}
+
+	_, err = w.data.Write(recordBytes)
+	if err != nil {
+		return err
+	}
+	w.dataSize += uint64(len(recordBytes))
+
+	// keys are saved so the bloom filter can be made once we know how many records there are
+	_, err = w.keys.Write(append(uint64ToBytes(uint64(len(record.Key))), record.Key...))
+	if err != nil {
+		return err
+	}
+
+	if w.prefixes != nil {
+		prefix, ok := w.filter.Prefix.Extract(record.Key)
+		if ok && (w.prefixCount == 0 || prefix != w.lastPrefix) {
+			_, err = w.prefixes.Write(append(uint64ToBytes(uint64(len(prefix))), prefix...))
+			if err != nil {
+				return err
+			}
+			w.lastPrefix = prefix
+			w.prefixCount++
+		}
+	}
+
+	// leaves are records without compression, so they can be checked without the compression dictionary
+	leafContent, err := merkleLeafContent(record)
+	if err != nil {
+		return err
+	}
+	w.leafHashes = append(w.leafHashes, merkletree.LeafHash(w.merkleHash(), leafContent))
+	w.recordCount++
+	return nil
+}
+
+// writes the rest of the sstable (filter, summary, merkle) and removes temporary files
+// returns nil as the sstable if no records were added, in that case nothing is written to disk
+// files of the returned sstable are closed
+func (w *Writer) Finish() (*SSTable, error) {
+	if w.recordCount == 0 {
+		return nil, nil
+	}
+
+	for _, buffer := range []*bufio.Writer{w.data, w.index, w.keys, w.prefixes} {
+		if buffer == nil {
+			continue
+		}
+		err := buffer.Flush()
+		if err != nil {
+			w.Abort()
+			return nil, err
+		}
+	}
+
+	bf, err := w.makeBF()
+	if err != nil {
+		w.Abort()
+		return nil, err
+	}
+	w.sstable.Bf = bf
+
+	if w.prefixesFile != nil {
+		err = w.makePrefixBF()
+		if err != nil {
+			w.Abort()
+			return nil, err
+		}
+	}
+
+	w.sstable.Merkle, err = merkletree.NewTreeFromHashes(w.merkleHash(), w.leafHashes)
+	if err != nil {
+		w.Abort()
+		return nil, err
+	}
+
+	if w.singleFile {
+		err = w.finishSingleFile()
+	} else {
+		err = w.finishSeparateFiles()
+	}
+	if err != nil {
+		w.Abort()
+		return nil, err
+	}
+
+	w.keysFile.Close()
+	err = os.Remove(w.keysFile.Name())
+	if err != nil {
+		return nil, err
+	}
+	if w.prefixesFile != nil {
+		w.prefixesFile.Close()
+		err = os.Remove(w.prefixesFile.Name())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return w.sstable, nil
+}
+
+// closes the files and deletes everything that was written, used if writing fails
+func (w *Writer) Abort() {
+	if w.dataFile == nil {
+		return
+	}
+	w.dataFile.Close()
+	w.indexFile.Close()
+	w.keysFile.Close()
+	if w.prefixesFile != nil {
+		w.prefixesFile.Close()
+	}
+	os.RemoveAll(w.path)
+}
+
+// returns the hash merkle trees are made with
+func (w *Writer) merkleHash() merkletree.HashType {
+	if w.filter.MerkleHash == 0 {
+		return merkletree.DEFAULT_HASH
+	}
+	return w.filter.MerkleHash
+}
+
+// makes bloom filter sized for the number of records that were added, reading the keys back from the temporary file
+func (w *Writer) makeBF() (*bloomFilter.BloomFilter, error) {
+	bf := w.filter.newBF(w.recordCount)
+
+	err := insertFromFile(bf, w.keysFile, w.recordCount)
+	if err != nil {
+		return nil, err
+	}
+	return bf, nil
+}
+
+// makes the prefix bloom filter from the prefixes saved in the temporary file
+func (w *Writer) makePrefixBF() error {
+	// a filter needs room for at least one element, even if no key had a prefix
+	var count int = w.prefixCount
+	if count == 0 {
+		count = 1
+	}
+
+	bf := w.filter.newBF(count)
+
+	err := insertFromFile(bf, w.prefixesFile, w.prefixCount)
+	if err != nil {
+		return err
+	}
+	w.sstable.PrefixBf = bf
+	w.sstable.PrefixExtractor = w.filter.Prefix
+	return nil
+}
+
+// reads count strings (size, then bytes) from the start of the file and inserts them into the filter
+func insertFromFile(bf *bloomFilter.BloomFilter, file *os.File, count int) error {
+	_, err := file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+	sizeBytes := make([]byte, 8)
+	for i := 0; i < count; i++ {
+		_, err = io.ReadFull(reader, sizeBytes)
+		if err != nil {
+			return err
+		}
+		content := make([]byte, binary.BigEndian.Uint64(sizeBytes))
+		_, err = io.ReadFull(reader, content)
+		if err != nil {
+			return err
+		}
+		bf.Insert(string(content))
+	}
+	return nil
+}
+
+// returns summary entries with the min and max key written in front of them
+func (w *Writer) serializeSummary() []byte {
+	var content []byte
+	content = append(content, uint64ToBytes(uint64(len(w.sstable.MinKey)))...)
+	content = append(content, w.sstable.MinKey...)
+	content = append(content, uint64ToBytes(uint64(len(w.sstable.MaxKey)))...)
+	content = append(content, w.sstable.MaxKey...)
+	for _, entry := range w.summary {
+		content = append(content, entry...)
+	}
+	return content
+}
+
+// data and index are already in their files, so only summary, filter and merkle are left to write
+func (w *Writer) finishSeparateFiles() error {
+	w.dataFile.Close()
+	w.indexFile.Close()
+
+	summary, err := writeFile(w.path, "Summary", w.serializeSummary())
+	if err != nil {
+		return err
+	}
+	_, err = writeFile(w.path, "Filter", w.serializeFilters())
+	if err != nil {
+		return err
+	}
+	_, err = writeFile(w.path, "Metadata", w.sstable.Merkle.Serialize())
+	if err != nil {
+		return err
+	}
+
+	w.sstable.Data, w.sstable.Index, w.sstable.Summary = w.dataFile, w.indexFile, summary
+	w.sstable.BfOffset, w.sstable.DataOffset, w.sstable.IndexOffset, w.sstable.MerkleOffset = 0, 0, 0, 0
+	w.sstable.SummaryOffset = int64(2*8 + len(w.sstable.MinKey) + len(w.sstable.MaxKey))
+	return nil
+}
+
+// returns the filter part of the sstable: the bloom filter, the prefix filter and the newest record timestamp
+func (w *Writer) serializeFilters() []byte {
+	content := append(w.sstable.Bf.Serialize(), w.sstable.serializePrefixFilter()...)
+	return append(content, w.sstable.serializeMaxTimestamp()...)
+}
+
+// makes file and writes the whole content to it, returned file is closed
+func writeFile(path string, s string, content []byte) (*os.File, error) {
+	file, err := MakeFile(path, s)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	_, err = file.Write(content)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// saves header one after the other -> length of min key, so we know how we need to read to get min key
+// same things is done for max key, then offsets of filter, data, index, summary and merkle
+// after the header come filter, data and index (copied from temporary files), summary and merkle
+func (w *Writer) finishSingleFile() error {
+	file, err := MakeFile(w.path, "DataIndexSummary")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	minKeyBytes := []byte(w.sstable.MinKey)
+	maxKeyBytes := []byte(w.sstable.MaxKey)
+	contentBf := w.serializeFilters()
+	var contentSummary []byte
+	for _, entry := range w.summary {
+		contentSummary = append(contentSummary, entry...)
+	}
+	contentMerkle := w.sstable.Merkle.Serialize()
+
+	var bfOffset uint64 = 7*8 + uint64(len(minKeyBytes)) + uint64(len(maxKeyBytes))
+	var dataOffset uint64 = bfOffset + uint64(len(contentBf))
+	var indexOffset uint64 = dataOffset + w.dataSize
+	var summaryOffset uint64 = indexOffset + w.indexSize
+	var merkleOffset uint64 = summaryOffset + uint64(len(contentSummary))
+
+	writer := bufio.NewWriter(file)
+	for _, content := range [][]byte{
+		uint64ToBytes(uint64(len(minKeyBytes))), minKeyBytes,
+		uint64ToBytes(uint64(len(maxKeyBytes))), maxKeyBytes,
+		uint64ToBytes(bfOffset), uint64ToBytes(dataOffset), uint64ToBytes(indexOffset),
+		uint64ToBytes(summaryOffset), uint64ToBytes(merkleOffset),
+		contentBf,
+	} {
+		_, err = writer.Write(content)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, temp := range []*os.File{w.dataFile, w.indexFile} {
+		_, err = temp.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, temp)
+		if err != nil {
+			return err
+		}
+		temp.Close()
+		err = os.Remove(temp.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = writer.Write(contentSummary)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(contentMerkle)
+	if err != nil {
+		return err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	w.sstable.Index, w.sstable.Data, w.sstable.Summary = file, file, file
+	w.sstable.BfOffset = int64(bfOffset)
+	w.sstable.DataOffset = int64(dataOffset)
+	w.sstable.IndexOffset = int64(indexOffset)
+	w.sstable.SummaryOffset = int64(summaryOffset)
+	w.sstable.MerkleOffset = int64(merkleOffset)
+	return nil
+}
@@ -0,0 +1,171 @@
+package topK
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"sort"
+
+	countMinSketch "github.com/natasakasikovic/Key-Value-engine/src/structs/CountMinSketch"
+)
+
+type Item struct {
+	Key   string
+	Count uint32 // estimated by the count min sketch, never lower than the real count
+}
+
+// TopK keeps the k most frequent items: counts of all items are kept in a count min sketch,
+// and the k items with the biggest counts are kept in a min heap, so the least frequent of them can be replaced
+type TopK struct {
+	k     uint32
+	cms   *countMinSketch.CMS
+	items itemHeap
+}
+
+func NewTopK(k uint32, epsilon float64, delta float64) *TopK {
+	return &TopK{
+		k:     k,
+		cms:   countMinSketch.CreateCMS(epsilon, delta),
+		items: itemHeap{index: make(map[string]int)},
+	}
+}
+
+func (topK *TopK) Insert(key string) {
+	topK.cms.Insert(key)
+	count := topK.cms.Search(key)
+
+	if position, ok := topK.items.index[key]; ok {
+		topK.items.list[position].Count = count
+		heap.Fix(&topK.items, position)
+	} else if uint32(len(topK.items.list)) < topK.k {
+		heap.Push(&topK.items, Item{Key: key, Count: count})
+	} else if len(topK.items.list) > 0 && count > topK.items.list[0].Count {
+		delete(topK.items.index, topK.items.list[0].Key)
+		topK.items.list[0] = Item{Key: key, Count: count}
+		topK.items.index[key] = 0
+		heap.Fix(&topK.items, 0)
+	}
+}
+
+// Returns the k most frequent items, from the most frequent one
+func (topK *TopK) List() []Item {
+	list := append([]Item{}, topK.items.list...)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Returns true if the key is one of the k most frequent items
+func (topK *TopK) Contains(key string) bool {
+	_, ok := topK.items.index[key]
+	return ok
+}
+
+// Returns the estimated count of the key, it works for keys that aren't in the top k too
+func (topK *TopK) Query(key string) uint32 {
+	return topK.cms.Search(key)
+}
+
+func (topK *TopK) K() uint32 {
+	return topK.k
+}
+
+// k, length of the count min sketch, count min sketch, number of items, then key length, key and count of each item
+func (topK *TopK) Serialize() []byte {
+	cmsBytes := topK.cms.Serialize()
+	bytes := make([]byte, 0, 8+len(cmsBytes)+4)
+	bytes = binary.BigEndian.AppendUint32(bytes, topK.k)
+	bytes = binary.BigEndian.AppendUint32(bytes, uint32(len(cmsBytes)))
+	bytes = append(bytes, cmsBytes...)
+	bytes = binary.BigEndian.AppendUint32(bytes, uint32(len(topK.items.list)))
+	for _, item := range topK.items.list {
+		bytes = binary.BigEndian.AppendUint32(bytes, uint32(len(item.Key)))
+		bytes = append(bytes, item.Key...)
+		bytes = binary.BigEndian.AppendUint32(bytes, item.Count)
+	}
+	return bytes
+}
+
+// Returns nil if the bytes aren't a valid top k
+func Deserialize(bytes []byte) *TopK {
+	var offset int = 0
+	readUint32 := func() (uint32, bool) {
+		if len(bytes) < offset+4 {
+			return 0, false
+		}
+		offset += 4
+		return binary.BigEndian.Uint32(bytes[offset-4 : offset]), true
+	}
+
+	k, ok := readUint32()
+	if !ok {
+		return nil
+	}
+	cmsLength, ok := readUint32()
+	if !ok || len(bytes) < offset+int(cmsLength) {
+		return nil
+	}
+	cms := countMinSketch.Deserialize(bytes[offset : offset+int(cmsLength)])
+	if cms == nil {
+		return nil
+	}
+	offset += int(cmsLength)
+
+	count, ok := readUint32()
+	if !ok || count > k {
+		return nil
+	}
+	topK := &TopK{k: k, cms: cms, items: itemHeap{list: make([]Item, count), index: make(map[string]int)}}
+	for i := 0; i < int(count); i++ {
+		keyLength, ok := readUint32()
+		if !ok || len(bytes) < offset+int(keyLength) {
+			return nil
+		}
+		key := string(bytes[offset : offset+int(keyLength)])
+		offset += int(keyLength)
+		itemCount, ok := readUint32()
+		if !ok {
+			return nil
+		}
+		// items are saved in heap order, so the heap doesn't have to be rebuilt
+		topK.items.list[i] = Item{Key: key, Count: itemCount}
+		topK.items.index[key] = i
+	}
+	return topK
+}
+
+// itemHeap is a min heap of items by count, it keeps positions of the items in the index
+type itemHeap struct {
+	list  []Item
+	index map[string]int // key -> position of the item in the list
+}
+
+func (h *itemHeap) Len() int {
+	return len(h.list)
+}
+
+func (h *itemHeap) Less(i, j int) bool {
+	return h.list[i].Count < h.list[j].Count
+}
+
+func (h *itemHeap) Swap(i, j int) {
+	h.list[i], h.list[j] = h.list[j], h.list[i]
+	h.index[h.list[i].Key] = i
+	h.index[h.list[j].Key] = j
+}
+
+func (h *itemHeap) Push(x any) {
+	item := x.(Item)
+	h.index[item.Key] = len(h.list)
+	h.list = append(h.list, item)
+}
+
+func (h *itemHeap) Pop() any {
+	item := h.list[len(h.list)-1]
+	h.list = h.list[:len(h.list)-1]
+	delete(h.index, item.Key)
+	return item
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	config2 "github.com/natasakasikovic/Key-Value-engine/src/config"
@@ -13,8 +15,10 @@ import (
 	lsmtree "github.com/natasakasikovic/Key-Value-engine/src/structs/LSMTree"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/TokenBucket"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/WAL"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/accessStats"
 	hashmap "github.com/natasakasikovic/Key-Value-engine/src/structs/hashMap"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/memtable"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
 	"github.com/natasakasikovic/Key-Value-engine/src/structs/sstable"
 	"github.com/natasakasikovic/Key-Value-engine/src/utils"
 )
@@ -25,6 +29,10 @@ const (
 	HLL_KEY = "hyperLogLog"
 	SH_KEY  = "simhash"
 	TB_KEY  = "tokenBucket"
+	CF_KEY  = "cuckooFilter"
+	TK_KEY  = "topK"
+	SHI_KEY = "simhashIndex"
+	SHN_KEY = "simhashNamespace"
 )
 
 type Engine struct {
@@ -34,8 +42,28 @@ type Engine struct {
 	Config         *config2.Config
 	LSMTree        *lsmtree.LSMTree
 	CompressionMap map[string]uint64
+	Stats          *accessStats.AccessStats //Nil if access statistics are off
+
+	sketchLock  sync.Mutex //Sketches are read, changed and saved again while holding it
+	requestLock sync.Mutex //Held by servers while they run a request, see Locked
+	watchLock   sync.Mutex //Guards watchers, they are added and closed outside of the request lock
+	watchers    map[*Watcher]struct{}
+}
+
+// Locked runs fn while no other Locked call runs
+// Engine methods aren't safe for concurrent use, so servers run every request of every client through it
+func (engine *Engine) Locked(fn func()) {
+	engine.requestLock.Lock()
+	defer engine.requestLock.Unlock()
+	fn()
 }
 
+// returned when the token bucket has no tokens left for the request
+var ErrRateLimited = errors.New("wait until sending new request")
+
+// returned when a probabilistic structure with the passed name doesn't exist
+var ErrSketchNotFound = errors.New("does not exist")
+
 func NewEngine() (*Engine, error) {
 	filePath := "config/config.json"
 	config, err := config2.LoadConfig(filePath)
@@ -43,9 +71,62 @@ func NewEngine() (*Engine, error) {
 		return nil, err
 	}
 
-	// -------------------------------------------------------------------
+	dict, err := loadCompressionMap(config.CompressionOn)
+	if err != nil {
+		return nil, err
+	}
+
+	wal, err := WAL.NewWAL(config.WalSize, int32(config.MemTableMaxInstances))
+	if err != nil {
+		return nil, err
+	}
+	cache := LRUCache.NewLRUCache(config.LRUCacheMaxSize)
+
+	memtable.InitMemtables(uint64(config.MemtableSize), config.MemtableStructure, uint64(config.MemTableMaxInstances), config.BTreeOrder, config.SkipListMaxHeight)
+	//Ucitavanje bf, cms, hll, simhash iz fajlova
+	err = wal.ReadRecords()
+	if err != nil {
+		return nil, err
+	}
+	tokenBucket := TokenBucket.NewTokenBucket(config.NumberOfTokens, int64(config.TokenResetInterval))
+
+	strategy := lsmtree.NewCompactionStrategy(config.LSMCompactionType, config.LSMSizeRatio, config.LSMMaxSpaceAmp,
+		config.LSMWindowSeconds, config.LSMWindowTTLSeconds)
+	prefixExtractor := sstable.PrefixExtractor{Type: config.PrefixExtractor, Length: config.PrefixLength, Delimiter: config.PrefixDelimiter}
+	tree, _ := lsmtree.LoadLSMTreeFromFile(config.LSMTreeMaxDepth, strategy, config.LSMFirstLevelSize, config.LSMGrowthFactor, config.IndexDegree, config.SummaryDegree, config.SSTableInSameFile, config.CompressionOn, dict, config.LSMMaxTableRecords, config.BloomFilterType == "blocked", config.BloomBitsPerKey, prefixExtractor)
+
+	if tree == nil {
+		tree, err = lsmtree.NewLSMTree(config.LSMTreeMaxDepth, strategy, config.LSMFirstLevelSize, config.LSMGrowthFactor, config.IndexDegree, config.SummaryDegree, config.SSTableInSameFile, config.CompressionOn, dict, config.LSMMaxTableRecords, config.BloomFilterType == "blocked", config.BloomBitsPerKey, prefixExtractor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// data that expired while the engine was off is dropped now, not after the next flush
+	err = tree.DropExpired()
+	if err != nil {
+		return nil, err
+	}
+	tree.SetCompactionRateLimit(config.LSMCompactionRate)
+	merkleHash, err := merkletree.ParseHashType(config.MerkleHash)
+	if err != nil {
+		return nil, err
+	}
+	tree.SetMerkleHash(merkleHash)
+
+	var stats *accessStats.AccessStats
+	if config.AccessStatsOn {
+		stats = accessStats.NewAccessStats(config.AccessSampleRate, config.AccessTopK, config.AccessPrefixLength)
+	}
+
+	return &Engine{Wal: wal, Cache: cache, TokenBucket: tokenBucket, Config: config, LSMTree: tree, CompressionMap: dict, Stats: stats}, nil
+}
+
+// Loads the compression dictionary, an empty one is made if there is none yet
+// returns nil if compression is off
+func loadCompressionMap(compressionOn bool) (map[string]uint64, error) {
 	var dict map[string]uint64
-	if config.CompressionOn {
+	if compressionOn {
 		empty, err := utils.EmptyDir(sstable.COMPRESSION_PATH)
 		if err != nil {
 			return nil, err
@@ -71,42 +152,43 @@ func NewEngine() (*Engine, error) {
 
 		}
 	}
-	//-------------------------------------------------------------------
-
-	wal, err := WAL.NewWAL(config.WalSize, int32(config.MemTableMaxInstances))
-	if err != nil {
-		return nil, err
-	}
-	cache := LRUCache.NewLRUCache(config.LRUCacheMaxSize)
-
-	memtable.InitMemtables(uint64(config.MemtableSize), config.MemtableStructure, uint64(config.MemTableMaxInstances), config.BTreeOrder, config.SkipListMaxHeight)
-	//Ucitavanje bf, cms, hll, simhash iz fajlova
-	err = wal.ReadRecords()
-	if err != nil {
-		return nil, err
-	}
-	tokenBucket := TokenBucket.NewTokenBucket(config.NumberOfTokens, int64(config.TokenResetInterval))
-
-	tree, _ := lsmtree.LoadLSMTreeFromFile(config.LSMTreeMaxDepth, config.LSMCompactionType, config.LSMFirstLevelSize, config.LSMGrowthFactor, config.IndexDegree, config.SummaryDegree, config.SSTableInSameFile, config.CompressionOn, dict)
+	return dict, nil
+}
 
-	if tree == nil {
-		tree, err = lsmtree.NewLSMTree(config.LSMTreeMaxDepth, config.LSMCompactionType, config.LSMFirstLevelSize, config.LSMGrowthFactor, config.IndexDegree, config.SummaryDegree, config.SSTableInSameFile, config.CompressionOn, dict)
-		if err != nil {
-			return nil, err
+// Returns true if the key begins with a prefix reserved for keys of probabilistic structures
+// Their keys are the prefix, "_" and the name, so other keys that begin with the same word, e.g. topKitten, can be used
+func IsReservedKey(key string) bool {
+	for _, prefix := range []string{BF_KEY, CMS_KEY, HLL_KEY, SHI_KEY, SHN_KEY, SH_KEY, TB_KEY, CF_KEY, TK_KEY} {
+		if strings.HasPrefix(key, prefix+"_") {
+			return true
 		}
 	}
-
-	return &Engine{Wal: wal, Cache: cache, TokenBucket: tokenBucket, Config: config, LSMTree: tree, CompressionMap: dict}, nil
+	return false
 }
 
 // Get Checks Memtable, Cache, BloomFilter and SSTable for given key
+// Keys of probabilistic structures can't be read, they are used through their own methods
 func (engine *Engine) Get(key string) ([]byte, error) {
+	if IsReservedKey(key) {
+		return nil, errors.New("key must not begin with system prefix")
+	}
+	// pinned keys are replaced only when the most read keys change, not on every read
+	if engine.Stats != nil && engine.Stats.RecordRead(key) && engine.Config.PinHotKeys {
+		engine.Cache.SetPinned(engine.Stats.HotReadKeys())
+	}
+	return engine.get(key)
+}
+
+func (engine *Engine) get(key string) ([]byte, error) {
 
 	if !engine.TokenBucket.IsRequestAvailable() {
-		return nil, errors.New("wait until sending new request")
+		return nil, ErrRateLimited
 	}
 	memtableRecord, err := memtable.Get(key)
 	if err == nil {
+		if memtableRecord.Tombstone == 1 {
+			return nil, nil
+		}
 		return memtableRecord.Value, nil
 	}
 	value := engine.Cache.Get(key)
@@ -114,8 +196,12 @@ func (engine *Engine) Get(key string) ([]byte, error) {
 		return value, nil
 	}
 
-	record, err := sstable.Search(key, engine.CompressionMap)
-	if err == nil && record != nil {
+	// a deleted record hides older versions of the key, so the search stops on it
+	record, err := engine.LSMTree.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil && record.Tombstone == 0 {
 		value = record.Value
 		engine.Cache.Add(key, value)
 		return value, nil
@@ -125,9 +211,19 @@ func (engine *Engine) Get(key string) ([]byte, error) {
 
 // Put Adds record to WAL and to Memtable with tombstone 0
 func (engine *Engine) Put(key string, value []byte) error {
+	if IsReservedKey(key) {
+		return errors.New("key must not begin with system prefix")
+	}
+	if engine.Stats != nil {
+		engine.Stats.RecordWrite(key)
+	}
+	return engine.put(key, value)
+}
+
+func (engine *Engine) put(key string, value []byte) error {
 
 	if !engine.TokenBucket.IsRequestAvailable() {
-		return errors.New("wait until sending new request")
+		return ErrRateLimited
 	}
 	err := engine.Commit(key, value, 0)
 	if err != nil {
@@ -138,8 +234,18 @@ func (engine *Engine) Put(key string, value []byte) error {
 
 // Delete Adds record to WAL and to Memtable with tombstone 1
 func (engine *Engine) Delete(key string) error {
+	if IsReservedKey(key) {
+		return errors.New("key must not begin with system prefix")
+	}
+	if engine.Stats != nil {
+		engine.Stats.RecordWrite(key)
+	}
+	return engine.delete(key)
+}
+
+func (engine *Engine) delete(key string) error {
 	if !engine.TokenBucket.IsRequestAvailable() {
-		return errors.New("wait until sending new request")
+		return ErrRateLimited
 	}
 	err := engine.Commit(key, make([]byte, 0), 1)
 	if err != nil {
@@ -149,10 +255,17 @@ func (engine *Engine) Delete(key string) error {
 }
 
 func (engine *Engine) Commit(key string, value []byte, tombstone byte) error {
-	timestamp := uint64(time.Now().UnixNano())
-	r := model.NewRecordTimestamp(tombstone, key, value, timestamp)
+	return engine.commitRecord(model.NewRecordTimestamp(tombstone, key, value, uint64(time.Now().UnixNano())))
+}
+
+// Saves the record with its own timestamp, which records copied from another engine keep
+func (engine *Engine) commitRecord(r *model.Record) error {
+	// checked before the memtable is changed, so a record that can't be logged isn't saved
+	if err := engine.Wal.CheckSize(r); err != nil {
+		return err
+	}
 
-	didSwap, didFlush, records := memtable.Put(key, value, timestamp, tombstone)
+	didSwap, didFlush, records := memtable.Put(r.Key, r.Value, r.Timestamp, r.Tombstone)
 	if didSwap {
 		err := engine.Wal.UpdateWatermark(didFlush)
 		if err != nil {
@@ -163,14 +276,104 @@ func (engine *Engine) Commit(key string, value []byte, tombstone byte) error {
 	if err != nil {
 		return err
 	}
+	engine.notifyWatchers(r)
 	if didFlush {
-		sstable, err := sstable.CreateSStable(records, engine.Config.SSTableInSameFile, engine.Config.CompressionOn, int(engine.Config.IndexDegree), int(engine.Config.SummaryDegree), engine.CompressionMap)
+		sstable, err := sstable.CreateSStable(records, engine.Config.SSTableInSameFile, engine.Config.CompressionOn, int(engine.Config.IndexDegree), int(engine.Config.SummaryDegree), engine.CompressionMap, engine.LSMTree.FilterConfig(0))
 		if err != nil {
 			return err
 		}
-		engine.LSMTree.AddSSTable(sstable)
+		err = engine.LSMTree.AddSSTable(sstable)
 		engine.Cache.UpdateKeys(records)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompactRange merges all sstables holding keys from [start, end] into the last level of the LSM tree
+func (engine *Engine) CompactRange(start, end string) error {
+	return engine.LSMTree.CompactRange(start, end)
+}
+
+// CompactAll merges all sstables into the last level of the LSM tree
+func (engine *Engine) CompactAll() error {
+	return engine.LSMTree.CompactAll()
+}
+
+// PauseCompaction stops compaction after memtable flushes until ResumeCompaction is called
+func (engine *Engine) PauseCompaction() {
+	engine.LSMTree.PauseCompaction()
+}
+
+// ResumeCompaction turns compaction back on and compacts levels that filled up in the meantime
+func (engine *Engine) ResumeCompaction() error {
+	return engine.LSMTree.ResumeCompaction()
+}
+
+func (engine *Engine) IsCompactionPaused() bool {
+	return engine.LSMTree.IsCompactionPaused()
+}
+
+// SetCompactionRateLimit caps the number of bytes per second written by compaction, 0 means no limit
+func (engine *Engine) SetCompactionRateLimit(bytesPerSecond uint64) {
+	engine.LSMTree.SetCompactionRateLimit(bytesPerSecond)
+}
+
+// EngineStatus describes the state of the LSM tree and compactions, with access statistics if they are on
+type EngineStatus struct {
+	Levels              []int               `json:"levels"` // number of sstables on each level
+	CompactionPaused    bool                `json:"compaction_paused"`
+	CompactionRateLimit uint64              `json:"compaction_rate_limit"` // bytes per second, 0 means no limit
+	Access              *accessStats.Report `json:"access,omitempty"`
+}
+
+func (engine *Engine) Status() EngineStatus {
+	status := EngineStatus{
+		Levels:              engine.LSMTree.TableCounts(),
+		CompactionPaused:    engine.LSMTree.IsCompactionPaused(),
+		CompactionRateLimit: engine.LSMTree.CompactionRateLimit(),
+	}
+	if engine.Stats != nil {
+		report := engine.Stats.Report()
+		status.Access = &report
+	}
+	return status
+}
+
+// AccessStats returns total numbers of reads and writes, and the most accessed keys and key prefixes
+func (engine *Engine) AccessStats() (accessStats.Report, error) {
+	if engine.Stats == nil {
+		return accessStats.Report{}, errors.New("access statistics are off")
+	}
+	return engine.Stats.Report(), nil
+}
+
+// KeyAccessCount returns estimated numbers of reads and writes of the key
+func (engine *Engine) KeyAccessCount(key string) (uint64, uint64, error) {
+	if engine.Stats == nil {
+		return 0, 0, errors.New("access statistics are off")
+	}
+	reads, writes := engine.Stats.KeyCount(key)
+	return reads, writes, nil
+}
+
+// PrefixAccessCount returns the estimated number of accesses to keys with the prefix
+// The prefix must be as long as access_stats_prefix_length in the config
+func (engine *Engine) PrefixAccessCount(prefix string) (uint64, error) {
+	if engine.Stats == nil {
+		return 0, errors.New("access statistics are off")
+	}
+	return engine.Stats.PrefixCount(prefix), nil
+}
+
+// ResetAccessStats starts counting accesses from zero, pinned keys are unpinned
+func (engine *Engine) ResetAccessStats() error {
+	if engine.Stats == nil {
+		return errors.New("access statistics are off")
 	}
+	engine.Stats.Reset()
+	engine.Cache.SetPinned(nil)
 	return nil
 }
 
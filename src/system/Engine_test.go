@@ -0,0 +1,96 @@
+package system
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/TokenBucket"
+)
+
+// The engine reads its config and log state from paths relative to src and saves data to ../data,
+// so every test runs in its own copy of them
+func useTempEngine(t *testing.T) *Engine {
+	root := t.TempDir()
+	for _, dir := range []string{"src/config", "src/structs/WAL", "data/sstable", "data/compressionInfo", "data/log"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"config/config.json", "structs/WAL/bytesFromLastSegment.log"} {
+		content, err := os.ReadFile(filepath.Join("..", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "src", file), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(root, "src")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+
+	engine, err := NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+// Only keys of structures, the prefix of their type followed by "_", are reserved
+func TestKeysThatOnlyBeginWithAStructureNameCanBeUsed(t *testing.T) {
+	engine := useTempEngine(t)
+	if err := engine.BloomCreate("filter", 10, 0.01); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"topKitten", "simhashes", "bloomFilters", "tokenBuckets/1", "simhashIndexing"} {
+		if err := engine.Put(key, []byte(key)); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		value, err := engine.Get(key)
+		if err != nil || string(value) != key {
+			t.Fatalf("%s: read %q, %v", key, value, err)
+		}
+		if err := engine.Delete(key); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+
+	for _, key := range []string{sketchKey(BF_KEY, "filter"), sketchKey(TK_KEY, "kitten"), sketchKey(SHI_KEY, "index") + "/doc/a"} {
+		if _, err := engine.Get(key); err == nil {
+			t.Fatalf("%s: key of a structure was read", key)
+		}
+		if err := engine.Put(key, []byte("value")); err == nil {
+			t.Fatalf("%s: key of a structure was written", key)
+		}
+	}
+	if exists, err := engine.BloomExists("filter", "a"); err != nil || exists {
+		t.Fatalf("bloom filter was changed: %v, %v", exists, err)
+	}
+}
+
+// A page of a scan reads far more than a get, so it takes a request from the token bucket as well
+func TestScansTakeRequestsFromTheTokenBucket(t *testing.T) {
+	engine := useTempEngine(t)
+	engine.TokenBucket = TokenBucket.NewTokenBucket(2, 3600)
+	if _, _, err := engine.ScanPage("key", "", "", 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.ScanPrefix("key", func(record *model.Record) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := engine.ScanPage("key", "", "", 2, 10); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("scan with an empty token bucket returned %v", err)
+	}
+	if err := engine.ScanRange("a", "z", func(record *model.Record) bool { return true }); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("scan with an empty token bucket returned %v", err)
+	}
+}
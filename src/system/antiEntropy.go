@@ -0,0 +1,352 @@
+package system
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"strings"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/iterators"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/memtable"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/merkletree"
+)
+
+// Two engines are synced by comparing key range Merkle trees of all their records:
+// only subtrees whose roots differ are walked, and only keys of differing leaves are compared and copied
+// Each engine runs in its own process, from its own directory, one waits with ServeSync and the other calls SyncWith
+// Deleted records are compared and copied like other records, and copied records keep their timestamps,
+// so a delete isn't undone by an older version of the key on the other engine
+
+// Sync modes
+const (
+	SYNC_MIRROR  = "mirror" // the other engine is made equal to this one, keys it has and this one doesn't are deleted
+	SYNC_TWO_WAY = "twoway" // keys missing on one side are copied to it, and newer versions of keys replace older ones
+)
+
+// number of leaves of the key range tree is 1<<DEFAULT_SYNC_DEPTH
+const DEFAULT_SYNC_DEPTH uint8 = 10
+
+// records are sent in batches, so a big difference isn't sent as one message
+const SYNC_BATCH_SIZE = 1000
+
+type SyncEntry struct {
+	Key       string
+	Hash      [32]byte
+	Timestamp uint64
+	Deleted   bool
+}
+
+type SyncRecord struct {
+	Key       string
+	Value     []byte
+	Delete    bool
+	Timestamp uint64
+}
+
+type SyncReport struct {
+	NodesCompared   int
+	DifferentLeaves int
+	Pushed          int // keys written to the other engine
+	Pulled          int // keys written to this engine
+	Deleted         int // keys deleted from the other engine
+}
+
+// Calls fn for the newest version of every key, deleted ones too, in key order
+func (engine *Engine) forEachRecord(fn func(record *model.Record) error) error {
+	iterator, err := iterators.NewPrefixIteratorWithDeleted("", engine.Config.CompressionOn, engine.CompressionMap)
+	if err != nil {
+		return err
+	}
+	defer iterator.Stop()
+	for {
+		record, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return nil
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// KeyRangeSummary returns the key range Merkle tree of all records of the engine, with 1<<depth leaves
+func (engine *Engine) KeyRangeSummary(depth uint8) (*merkletree.KeyRangeTree, error) {
+	tree, err := merkletree.NewKeyRangeTree(depth)
+	if err != nil {
+		return nil, err
+	}
+	err = engine.forEachRecord(func(record *model.Record) error {
+		return tree.Add(record.Key, record.Value, record.Tombstone == 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	tree.Finish()
+	return tree, nil
+}
+
+// Returns entries of all records that belong to the passed leaves of the tree
+func (engine *Engine) leafEntries(tree *merkletree.KeyRangeTree, leaves []int) ([]SyncEntry, error) {
+	wanted := make(map[int]bool)
+	for _, leaf := range leaves {
+		wanted[leaf] = true
+	}
+	var entries []SyncEntry
+	err := engine.forEachRecord(func(record *model.Record) error {
+		if wanted[tree.Leaf(record.Key)] {
+			deleted := record.Tombstone == 1
+			entries = append(entries, SyncEntry{
+				Key:       record.Key,
+				Hash:      merkletree.RecordHash(record.Key, record.Value, deleted),
+				Timestamp: record.Timestamp,
+				Deleted:   deleted,
+			})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Returns the newest version of the key, deleted or not, nil if the engine has no record of it
+// The cache isn't read, since it doesn't keep timestamps
+func (engine *Engine) getRecord(key string) (*model.Record, error) {
+	if !engine.TokenBucket.IsRequestAvailable() {
+		return nil, ErrRateLimited
+	}
+	record, err := memtable.Get(key)
+	if err == nil {
+		return &record, nil
+	}
+	return engine.LSMTree.Get(key)
+}
+
+func (engine *Engine) getRecords(keys []string) ([]SyncRecord, error) {
+	records := make([]SyncRecord, 0, len(keys))
+	for _, key := range keys {
+		record, err := engine.getRecord(key)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil || record.Tombstone == 1 {
+			var timestamp uint64 = 0
+			if record != nil {
+				timestamp = record.Timestamp
+			}
+			records = append(records, SyncRecord{Key: key, Delete: true, Timestamp: timestamp})
+		} else {
+			records = append(records, SyncRecord{Key: key, Value: record.Value, Timestamp: record.Timestamp})
+		}
+	}
+	return records, nil
+}
+
+// Saves the records with their own timestamps
+// A record that replaces a version of the key that isn't older, which only a mirror sync does,
+// gets the timestamp right after that version, so it is the newest one for compactions and scans too
+func (engine *Engine) applyRecords(records []SyncRecord) error {
+	for _, record := range records {
+		local, err := engine.getRecord(record.Key)
+		if err != nil {
+			return err
+		}
+		timestamp := record.Timestamp
+		if local != nil && local.Timestamp >= timestamp {
+			timestamp = local.Timestamp + 1
+		}
+		var tombstone byte = 0
+		if record.Delete {
+			tombstone = 1
+		}
+		if err := engine.commitRecord(model.NewRecordTimestamp(tombstone, record.Key, record.Value, timestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncService answers requests of the engine that syncs with this one
+type SyncService struct {
+	engine *Engine
+	tree   *merkletree.KeyRangeTree // made by Summary, nil after records are changed
+}
+
+func (service *SyncService) Summary(depth uint8, root *[32]byte) error {
+	tree, err := service.engine.KeyRangeSummary(depth)
+	if err != nil {
+		return err
+	}
+	service.tree = tree
+	*root = tree.Root()
+	return nil
+}
+
+func (service *SyncService) Nodes(ids []int, hashes *[][32]byte) error {
+	if service.tree == nil {
+		return errors.New("summary has to be made first")
+	}
+	*hashes = make([][32]byte, len(ids))
+	for i, id := range ids {
+		node, err := service.tree.Node(id)
+		if err != nil {
+			return err
+		}
+		(*hashes)[i] = node
+	}
+	return nil
+}
+
+func (service *SyncService) Entries(leaves []int, entries *[]SyncEntry) error {
+	if service.tree == nil {
+		return errors.New("summary has to be made first")
+	}
+	result, err := service.engine.leafEntries(service.tree, leaves)
+	*entries = result
+	return err
+}
+
+func (service *SyncService) Get(keys []string, records *[]SyncRecord) error {
+	result, err := service.engine.getRecords(keys)
+	*records = result
+	return err
+}
+
+func (service *SyncService) Apply(records []SyncRecord, applied *int) error {
+	service.tree = nil
+	*applied = len(records)
+	return service.engine.applyRecords(records)
+}
+
+// Returns "unix" for paths and "tcp" for addresses with a port
+func syncNetwork(address string) string {
+	if strings.Contains(address, ":") && !strings.Contains(address, "/") {
+		return "tcp"
+	}
+	return "unix"
+}
+
+// ServeSync waits for one engine to connect to the address and answers its requests until it disconnects
+// The address is a path of a unix socket, or host:port
+func (engine *Engine) ServeSync(address string) error {
+	listener, err := net.Listen(syncNetwork(address), address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Sync", &SyncService{engine: engine}); err != nil {
+		conn.Close()
+		return err
+	}
+	server.ServeConn(conn)
+	return nil
+}
+
+// SyncWith connects to the engine waiting at the address and syncs the two engines in the passed mode
+func (engine *Engine) SyncWith(address string, mode string, depth uint8) (SyncReport, error) {
+	var report SyncReport
+	if mode != SYNC_MIRROR && mode != SYNC_TWO_WAY {
+		return report, errors.New("unknown sync mode")
+	}
+	tree, err := engine.KeyRangeSummary(depth)
+	if err != nil {
+		return report, err
+	}
+	client, err := rpc.Dial(syncNetwork(address), address)
+	if err != nil {
+		return report, err
+	}
+	defer client.Close()
+
+	var root [32]byte
+	if err := client.Call("Sync.Summary", depth, &root); err != nil {
+		return report, err
+	}
+	leaves, err := tree.Diff(func(ids []int) ([][32]byte, error) {
+		report.NodesCompared += len(ids)
+		var hashes [][32]byte
+		err := client.Call("Sync.Nodes", ids, &hashes)
+		return hashes, err
+	})
+	if err != nil || len(leaves) == 0 {
+		return report, err
+	}
+	report.DifferentLeaves = len(leaves)
+
+	local, err := engine.leafEntries(tree, leaves)
+	if err != nil {
+		return report, err
+	}
+	var remote []SyncEntry
+	if err := client.Call("Sync.Entries", leaves, &remote); err != nil {
+		return report, err
+	}
+
+	remoteEntries := make(map[string]SyncEntry, len(remote))
+	for _, entry := range remote {
+		remoteEntries[entry.Key] = entry
+	}
+	var push, pull, remove []string
+	for _, entry := range local {
+		other, ok := remoteEntries[entry.Key]
+		delete(remoteEntries, entry.Key)
+		if ok && other.Hash == entry.Hash {
+			continue
+		}
+		if !ok || mode == SYNC_MIRROR || entry.Timestamp >= other.Timestamp {
+			push = append(push, entry.Key)
+		} else {
+			pull = append(pull, entry.Key)
+		}
+	}
+	for _, entry := range remote {
+		if _, ok := remoteEntries[entry.Key]; !ok {
+			continue
+		}
+		if mode == SYNC_MIRROR {
+			// a key this engine has no record of and the other one deleted already is left as it is,
+			// so its leaf differs until a compaction drops the deleted record
+			if !entry.Deleted {
+				remove = append(remove, entry.Key)
+			}
+		} else {
+			pull = append(pull, entry.Key)
+		}
+	}
+
+	// pulled records are read before anything is pushed, so they aren't changed by this sync
+	for start := 0; start < len(pull); start += SYNC_BATCH_SIZE {
+		var records []SyncRecord
+		if err := client.Call("Sync.Get", pull[start:min(start+SYNC_BATCH_SIZE, len(pull))], &records); err != nil {
+			return report, err
+		}
+		if err := engine.applyRecords(records); err != nil {
+			return report, err
+		}
+		report.Pulled += len(records)
+	}
+
+	outgoing, err := engine.getRecords(push)
+	if err != nil {
+		return report, err
+	}
+	for _, key := range remove {
+		outgoing = append(outgoing, SyncRecord{Key: key, Delete: true})
+	}
+	for start := 0; start < len(outgoing); start += SYNC_BATCH_SIZE {
+		var applied int
+		if err := client.Call("Sync.Apply", outgoing[start:min(start+SYNC_BATCH_SIZE, len(outgoing))], &applied); err != nil {
+			return report, err
+		}
+	}
+	report.Pushed = len(push)
+	report.Deleted = len(remove)
+	return report, nil
+}
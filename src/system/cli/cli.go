@@ -0,0 +1,515 @@
+package cli
+
+import (
+	gocontext "context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+	consoleinterface "github.com/natasakasikovic/Key-Value-engine/src/system/consoleInterface"
+	"github.com/natasakasikovic/Key-Value-engine/src/system/grpcapi"
+	"github.com/natasakasikovic/Key-Value-engine/src/system/repl"
+	"github.com/natasakasikovic/Key-Value-engine/src/system/resp"
+	"github.com/natasakasikovic/Key-Value-engine/src/system/rest"
+)
+
+// Commands are run as "kv [--json] <command> [flags] [args]", flags can be written before or after args,
+// and args that begin with '-' are written after "--"
+// With no command, the numbered menu is started, as it is with "interactive"
+
+// Exit codes
+const (
+	EXIT_OK           = 0
+	EXIT_NOT_FOUND    = 1 // key or element doesn't exist
+	EXIT_USAGE        = 2
+	EXIT_ERROR        = 3
+	EXIT_RATE_LIMITED = 4 // the token bucket rejected the request
+	EXIT_DAMAGED      = 5 // verify found damaged sstables or log segments
+)
+
+// time that servers have to answer commands they already read when they are stopped
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
+type command struct {
+	args        string
+	description string
+	run         func(ctx *context, args []string) int
+}
+
+// context of one run, the engine is opened by the first command that needs it and closed when the run ends
+type context struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	json           bool
+	engine         *engine.Engine
+}
+
+// record as it is printed in JSON, values that aren't valid UTF-8 are encoded in base64
+type jsonRecord struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"` // "utf8" or "base64"
+}
+
+func newJSONRecord(key string, value []byte) jsonRecord {
+	if utf8.Valid(value) {
+		return jsonRecord{Key: key, Value: string(value), Encoding: "utf8"}
+	}
+	return jsonRecord{Key: key, Value: base64.StdEncoding.EncodeToString(value), Encoding: "base64"}
+}
+
+func commands() map[string]command {
+	return map[string]command{
+		"get":     {"<key>", "prints the value of the key", runGet},
+		"put":     {"<key> (<value> | - | --file <path>)", "saves the value, '-' reads it from stdin", runPut},
+		"delete":  {"<key>", "deletes the key", runDelete},
+		"scan":    {"(--prefix <prefix> | --range <start> <end>) [--limit n] [--keys-only]", "prints records in key order", runScan},
+		"compact": {"[--start <key> --end <key>]", "compacts all sstables, or the ones that overlap the range", runCompact},
+		"stats":   {"", "prints sstables per level, compaction state and access statistics", runStats},
+		"verify":  {"[--repair]", "checks all sstables and the log, --repair moves damaged sstables to quarantine", runVerify},
+		"serve":   {"[--resp <address>] [--http <address>] [--grpc <address>]", "serves redis, HTTP and gRPC clients until it is stopped with a signal", runServe},
+		"bf":      {"create|add|exists|merge|delete ...", "bloom filters", runBloom},
+		"cms":     {"create|incr|query|merge|delete ...", "count min sketches", runCMS},
+		"hll":     {"create|add|count|merge|delete ...", "hyperloglogs", runHLL},
+		"cf":      {"create|add|exists|remove|count|delete ...", "cuckoo filters", runCuckoo},
+		"topk":    {"create|add|list|query|delete ...", "top k heavy hitters", runTopK},
+		"simhash": {"fingerprint|distance|delete ...", "simhash fingerprints", runSimHash},
+		"repl":    {"", "starts the command prompt", runRepl},
+		"interactive": {"", "starts the numbered menu", func(ctx *context, args []string) int {
+			consoleinterface.StartEngine()
+			return EXIT_OK
+		}},
+	}
+}
+
+// Run runs the command in args (without the program name) and returns the exit code
+func Run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	ctx := &context{stdin: stdin, stdout: stdout, stderr: stderr}
+	for len(args) > 0 && (args[0] == "--json" || args[0] == "-json") {
+		ctx.json = true
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		consoleinterface.StartEngine()
+		return EXIT_OK
+	}
+	if args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		ctx.usage(ctx.stdout)
+		return EXIT_OK
+	}
+	cmd, ok := commands()[args[0]]
+	if !ok {
+		fmt.Fprintf(ctx.stderr, "unknown command %q\n", args[0])
+		ctx.usage(ctx.stderr)
+		return EXIT_USAGE
+	}
+	defer ctx.close()
+	return cmd.run(ctx, args[1:])
+}
+
+func (ctx *context) usage(out io.Writer) {
+	fmt.Fprintln(out, "usage: kv [--json] <command> [flags] [args]")
+	list := commands()
+	var names []string
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-11s %s\n", name, list[name].args)
+		fmt.Fprintf(out, "  %-11s   %s\n", "", list[name].description)
+	}
+}
+
+// returns the engine, opening it if needed
+func (ctx *context) open() (*engine.Engine, error) {
+	if ctx.engine == nil {
+		e, err := engine.NewEngine()
+		if err != nil {
+			return nil, err
+		}
+		ctx.engine = e
+	}
+	return ctx.engine, nil
+}
+
+func (ctx *context) close() {
+	if ctx.engine != nil {
+		ctx.engine.Exit()
+		ctx.engine = nil
+	}
+}
+
+// prints the error and returns the exit code for it
+func (ctx *context) fail(err error) int {
+	if ctx.json {
+		content, _ := json.Marshal(map[string]string{"error": err.Error()})
+		fmt.Fprintln(ctx.stderr, string(content))
+	} else {
+		fmt.Fprintf(ctx.stderr, "err: %v\n", err)
+	}
+	if errors.Is(err, engine.ErrRateLimited) {
+		return EXIT_RATE_LIMITED
+	}
+	return EXIT_ERROR
+}
+
+func (ctx *context) usageError(name string, args string) int {
+	fmt.Fprintf(ctx.stderr, "usage: kv %s %s\n", name, args)
+	return EXIT_USAGE
+}
+
+// prints value as JSON, or the plain text
+func (ctx *context) print(plain string, value any) {
+	if ctx.json {
+		content, err := json.Marshal(value)
+		if err != nil {
+			ctx.fail(err)
+			return
+		}
+		fmt.Fprintln(ctx.stdout, string(content))
+	} else {
+		fmt.Fprintln(ctx.stdout, plain)
+	}
+}
+
+// prints the result of a command that only succeeds or fails
+func (ctx *context) done(err error) int {
+	if err != nil {
+		return ctx.fail(err)
+	}
+	ctx.print("OK", map[string]bool{"ok": true})
+	return EXIT_OK
+}
+
+// returns a flag set for the command, with the --json flag that every command has
+func (ctx *context) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ctx.stderr)
+	flags.BoolVar(&ctx.json, "json", ctx.json, "print JSON")
+	return flags
+}
+
+// parses flags written anywhere among args, and returns the args
+// everything after "--" is an arg, so keys that begin with '-' can be passed
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			rest = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+	var positional []string
+	for len(args) > 0 {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	return append(positional, rest...), nil
+}
+
+// returns the arg, or stdin if the arg is "-"
+func (ctx *context) readArg(arg string) ([]byte, error) {
+	if arg == "-" {
+		return io.ReadAll(ctx.stdin)
+	}
+	return []byte(arg), nil
+}
+
+func runGet(ctx *context, args []string) int {
+	flags := ctx.flags("get")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) != 1 {
+		return ctx.usageError("get", "<key>")
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	value, err := e.Get(args[0])
+	if err != nil {
+		return ctx.fail(err)
+	}
+	if value == nil {
+		fmt.Fprintf(ctx.stderr, "key %q does not exist\n", args[0])
+		return EXIT_NOT_FOUND
+	}
+	if ctx.json {
+		ctx.print("", newJSONRecord(args[0], value))
+	} else {
+		ctx.stdout.Write(append(value, '\n'))
+	}
+	return EXIT_OK
+}
+
+func runPut(ctx *context, args []string) int {
+	flags := ctx.flags("put")
+	file := flags.String("file", "", "read the value from the file")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) < 1 || len(args) > 2 || (len(args) == 2) == (*file != "") {
+		return ctx.usageError("put", "<key> (<value> | - | --file <path>)")
+	}
+	var value []byte
+	if *file != "" {
+		value, err = os.ReadFile(*file)
+	} else {
+		value, err = ctx.readArg(args[1])
+	}
+	if err != nil {
+		return ctx.fail(err)
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	return ctx.done(e.Put(args[0], value))
+}
+
+func runDelete(ctx *context, args []string) int {
+	flags := ctx.flags("delete")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) != 1 {
+		return ctx.usageError("delete", "<key>")
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	return ctx.done(e.Delete(args[0]))
+}
+
+// records are printed as they are read, one per line, in JSON as one object per line
+func runScan(ctx *context, args []string) int {
+	const usage = "(--prefix <prefix> | --range <start> <end>) [--limit n] [--keys-only]"
+	flags := ctx.flags("scan")
+	prefix := flags.String("prefix", "", "scan keys that begin with the prefix")
+	isRange := flags.Bool("range", false, "scan keys between the two args, both included")
+	limit := flags.Int("limit", 0, "print at most this many records, 0 means all")
+	keysOnly := flags.Bool("keys-only", false, "print only keys")
+	args, err := parseFlags(flags, args)
+	if err != nil || *limit < 0 {
+		return ctx.usageError("scan", usage)
+	}
+	prefixSet := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "prefix" {
+			prefixSet = true
+		}
+	})
+	if prefixSet == *isRange || (*isRange && len(args) != 2) || (prefixSet && len(args) != 0) {
+		return ctx.usageError("scan", usage)
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	count := 0
+	print := func(record *model.Record) bool {
+		switch {
+		case *keysOnly && ctx.json:
+			ctx.print("", map[string]string{"key": record.Key})
+		case *keysOnly:
+			fmt.Fprintln(ctx.stdout, record.Key)
+		case ctx.json:
+			ctx.print("", newJSONRecord(record.Key, record.Value))
+		default:
+			fmt.Fprintf(ctx.stdout, "%s\t%s\n", record.Key, record.Value)
+		}
+		count++
+		return *limit == 0 || count < *limit
+	}
+	if *isRange {
+		err = e.ScanRange(args[0], args[1], print)
+	} else {
+		err = e.ScanPrefix(*prefix, print)
+	}
+	if err != nil {
+		return ctx.fail(err)
+	}
+	return EXIT_OK
+}
+
+func runCompact(ctx *context, args []string) int {
+	flags := ctx.flags("compact")
+	start := flags.String("start", "", "first key of the range")
+	end := flags.String("end", "", "last key of the range")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) != 0 || (*start == "") != (*end == "") {
+		return ctx.usageError("compact", "[--start <key> --end <key>]")
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	if *start == "" {
+		return ctx.done(e.CompactAll())
+	}
+	return ctx.done(e.CompactRange(*start, *end))
+}
+
+func runStats(ctx *context, args []string) int {
+	flags := ctx.flags("stats")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) != 0 {
+		return ctx.usageError("stats", "")
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	status := e.Status()
+	if ctx.json {
+		ctx.print("", status)
+		return EXIT_OK
+	}
+	for i, count := range status.Levels {
+		fmt.Fprintf(ctx.stdout, "level %d: %d sstables\n", i, count)
+	}
+	fmt.Fprintf(ctx.stdout, "compaction paused: %v\n", status.CompactionPaused)
+	fmt.Fprintf(ctx.stdout, "compaction rate limit: %d bytes/s\n", status.CompactionRateLimit)
+	if status.Access != nil {
+		fmt.Fprintf(ctx.stdout, "reads: %d, writes: %d\n", status.Access.Reads, status.Access.Writes)
+		for _, list := range []struct {
+			name   string
+			counts []string
+		}{
+			{"hot reads", keyCounts(status.Access.HotReads)},
+			{"hot writes", keyCounts(status.Access.HotWrites)},
+			{"hot prefixes", keyCounts(status.Access.HotPrefixes)},
+		} {
+			fmt.Fprintf(ctx.stdout, "%s: %s\n", list.name, strings.Join(list.counts, ", "))
+		}
+	}
+	return EXIT_OK
+}
+
+// the report is always printed as JSON, since it is meant to be read by programs
+// the engine isn't opened, so damaged sstables can be checked and quarantined even if the engine can't start
+func runVerify(ctx *context, args []string) int {
+	flags := ctx.flags("verify")
+	repair := flags.Bool("repair", false, "move damaged sstables to quarantine")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) != 0 {
+		return ctx.usageError("verify", "[--repair]")
+	}
+	report, err := engine.VerifyFiles(*repair)
+	if report != nil {
+		content, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Fprintln(ctx.stdout, string(content))
+	}
+	if err != nil {
+		return ctx.fail(err)
+	}
+	if !report.Ok {
+		return EXIT_DAMAGED
+	}
+	return EXIT_OK
+}
+
+func runRepl(ctx *context, args []string) int {
+	flags := ctx.flags("repl")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) != 0 {
+		return ctx.usageError("repl", "")
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	if err := repl.New(e, ctx.stdin, ctx.stdout).Run(); err != nil {
+		return ctx.fail(err)
+	}
+	return EXIT_OK
+}
+
+// server that runs in serve mode
+type server interface {
+	Serve(listener net.Listener) error
+	Shutdown(ctx gocontext.Context) error
+}
+
+// the engine is closed after the servers answer the requests they already read
+func runServe(ctx *context, args []string) int {
+	flags := ctx.flags("serve")
+	respAddress := flags.String("resp", "", "address of the redis protocol server (default 127.0.0.1:6379 if no server is set)")
+	httpAddress := flags.String("http", "", "address of the HTTP server")
+	grpcAddress := flags.String("grpc", "", "address of the gRPC server")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) != 0 {
+		return ctx.usageError("serve", "[--resp <address>] [--http <address>] [--grpc <address>]")
+	}
+	if *respAddress == "" && *httpAddress == "" && *grpcAddress == "" {
+		*respAddress = "127.0.0.1:6379"
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	var servers []server
+	errs := make(chan error, 3)
+	start := func(s server, address string, clients string) error {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.stderr, "serving %s on %s\n", clients, listener.Addr())
+		servers = append(servers, s)
+		go func() {
+			errs <- s.Serve(listener)
+		}()
+		return nil
+	}
+	if *respAddress != "" {
+		err = start(resp.NewServer(e), *respAddress, "redis clients")
+	}
+	if err == nil && *httpAddress != "" {
+		err = start(rest.NewServer(e), *httpAddress, "HTTP clients")
+	}
+	if err == nil && *grpcAddress != "" {
+		err = start(grpcapi.NewServer(e), *grpcAddress, "gRPC clients")
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	if err == nil {
+		select {
+		case err = <-errs:
+		case <-signals:
+			fmt.Fprintln(ctx.stderr, "shutting down")
+		}
+	}
+	timeout, cancel := gocontext.WithTimeout(gocontext.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+	for _, s := range servers {
+		if shutdownErr := s.Shutdown(timeout); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+	if err != nil {
+		return ctx.fail(err)
+	}
+	return EXIT_OK
+}
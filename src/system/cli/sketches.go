@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/accessStats"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+// subcommand of a sketch command, like "create" in "kv bf create"
+// define adds flags of the subcommand to the set and returns the function that runs it with their values
+type subcommand struct {
+	args    string
+	minArgs int
+	maxArgs int // -1 means there is no limit
+	define  func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int
+}
+
+func noFlags(run func(ctx *context, e *engine.Engine, args []string) int) func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int {
+	return func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int {
+		return run
+	}
+}
+
+func runSubcommand(ctx *context, name string, args []string, subcommands map[string]subcommand) int {
+	var names []string
+	for sub := range subcommands {
+		names = append(names, sub)
+	}
+	sort.Strings(names)
+	if len(args) == 0 {
+		return ctx.usageError(name, strings.Join(names, "|")+" ...")
+	}
+	sub, ok := subcommands[args[0]]
+	if !ok {
+		return ctx.usageError(name, strings.Join(names, "|")+" ...")
+	}
+	flags := ctx.flags(name + " " + args[0])
+	run := sub.define(flags)
+	rest, err := parseFlags(flags, args[1:])
+	if err != nil || len(rest) < sub.minArgs || (sub.maxArgs >= 0 && len(rest) > sub.maxArgs) {
+		return ctx.usageError(name+" "+args[0], sub.args)
+	}
+	e, err := ctx.open()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	return run(ctx, e, rest)
+}
+
+// prints whether the element exists, the exit code is EXIT_NOT_FOUND if it doesn't
+func (ctx *context) exists(exists bool, err error) int {
+	if err != nil {
+		return ctx.fail(err)
+	}
+	ctx.print(fmt.Sprint(exists), map[string]bool{"exists": exists})
+	if !exists {
+		return EXIT_NOT_FOUND
+	}
+	return EXIT_OK
+}
+
+func (ctx *context) count(count uint64, err error) int {
+	if err != nil {
+		return ctx.fail(err)
+	}
+	ctx.print(fmt.Sprint(count), map[string]uint64{"count": count})
+	return EXIT_OK
+}
+
+// calls add for every element, stops at the first error
+func addAll(ctx *context, name string, elements []string, add func(name string, element string) error) int {
+	for _, element := range elements {
+		if err := add(name, element); err != nil {
+			return ctx.fail(err)
+		}
+	}
+	return ctx.done(nil)
+}
+
+func deleteSketch(prefix string) subcommand {
+	return subcommand{"<name>", 1, 1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+		return ctx.done(e.DeleteSketch(prefix, args[0]))
+	})}
+}
+
+func mergeSketches(merge func(e *engine.Engine) func(dst string, names []string) error) subcommand {
+	return subcommand{"<dst> <name>...", 2, -1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+		return ctx.done(merge(e)(args[0], args[1:]))
+	})}
+}
+
+func keyCounts(counts []accessStats.KeyCount) []string {
+	var list []string
+	for _, count := range counts {
+		list = append(list, fmt.Sprintf("%s (%d)", count.Key, count.Count))
+	}
+	return list
+}
+
+func runBloom(ctx *context, args []string) int {
+	return runSubcommand(ctx, "bf", args, map[string]subcommand{
+		"create": {"<name> [--expected n] [--fp rate]", 1, 1, func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int {
+			expected := flags.Int("expected", 1000, "expected number of elements")
+			rate := flags.Float64("fp", 0.01, "false positive rate")
+			return func(ctx *context, e *engine.Engine, args []string) int {
+				return ctx.done(e.BloomCreate(args[0], *expected, *rate))
+			}
+		}},
+		"add": {"<name> <element>...", 2, -1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return addAll(ctx, args[0], args[1:], e.BloomAdd)
+		})},
+		"exists": {"<name> <element>", 2, 2, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return ctx.exists(e.BloomExists(args[0], args[1]))
+		})},
+		"merge": mergeSketches(func(e *engine.Engine) func(string, []string) error {
+			return e.MergeBloomFilters
+		}),
+		"delete": deleteSketch(engine.BF_KEY),
+	})
+}
+
+func runCMS(ctx *context, args []string) int {
+	return runSubcommand(ctx, "cms", args, map[string]subcommand{
+		"create": {"<name> [--epsilon e] [--delta d]", 1, 1, func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int {
+			epsilon := flags.Float64("epsilon", 0.01, "error of counts")
+			delta := flags.Float64("delta", 0.01, "probability of a bigger error")
+			return func(ctx *context, e *engine.Engine, args []string) int {
+				return ctx.done(e.CMSCreate(args[0], *epsilon, *delta))
+			}
+		}},
+		"incr": {"<name> <event>...", 2, -1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return addAll(ctx, args[0], args[1:], e.CMSIncr)
+		})},
+		"query": {"<name> <event>", 2, 2, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			count, err := e.CMSQuery(args[0], args[1])
+			return ctx.count(uint64(count), err)
+		})},
+		"merge": mergeSketches(func(e *engine.Engine) func(string, []string) error {
+			return e.MergeCMS
+		}),
+		"delete": deleteSketch(engine.CMS_KEY),
+	})
+}
+
+func runHLL(ctx *context, args []string) int {
+	return runSubcommand(ctx, "hll", args, map[string]subcommand{
+		"create": {"<name> [--precision p]", 1, 1, func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int {
+			precision := flags.Uint("precision", 10, "number of bits used for registers")
+			return func(ctx *context, e *engine.Engine, args []string) int {
+				if *precision > 255 {
+					return ctx.usageError("hll create", "<name> [--precision p]")
+				}
+				return ctx.done(e.HLLCreate(args[0], uint8(*precision)))
+			}
+		}},
+		"add": {"<name> <element>...", 2, -1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return addAll(ctx, args[0], args[1:], e.HLLAdd)
+		})},
+		"count": {"<name>", 1, 1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return ctx.count(e.HLLCount(args[0]))
+		})},
+		"merge": mergeSketches(func(e *engine.Engine) func(string, []string) error {
+			return e.MergeHLL
+		}),
+		"delete": deleteSketch(engine.HLL_KEY),
+	})
+}
+
+func runCuckoo(ctx *context, args []string) int {
+	return runSubcommand(ctx, "cf", args, map[string]subcommand{
+		"create": {"<name> [--expected n]", 1, 1, func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int {
+			expected := flags.Int("expected", 1000, "expected number of elements")
+			return func(ctx *context, e *engine.Engine, args []string) int {
+				return ctx.done(e.CuckooCreate(args[0], *expected))
+			}
+		}},
+		"add": {"<name> <element>...", 2, -1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return addAll(ctx, args[0], args[1:], e.CuckooAdd)
+		})},
+		"exists": {"<name> <element>", 2, 2, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return ctx.exists(e.CuckooExists(args[0], args[1]))
+		})},
+		"remove": {"<name> <element>", 2, 2, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return ctx.exists(e.CuckooRemove(args[0], args[1]))
+		})},
+		"count": {"<name>", 1, 1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return ctx.count(e.CuckooCount(args[0]))
+		})},
+		"delete": deleteSketch(engine.CF_KEY),
+	})
+}
+
+func runTopK(ctx *context, args []string) int {
+	return runSubcommand(ctx, "topk", args, map[string]subcommand{
+		"create": {"<name> [--k k] [--epsilon e] [--delta d]", 1, 1, func(flags *flag.FlagSet) func(ctx *context, e *engine.Engine, args []string) int {
+			k := flags.Uint("k", 10, "number of items that are kept")
+			epsilon := flags.Float64("epsilon", 0.01, "error of counts")
+			delta := flags.Float64("delta", 0.01, "probability of a bigger error")
+			return func(ctx *context, e *engine.Engine, args []string) int {
+				return ctx.done(e.TopKCreate(args[0], uint32(*k), *epsilon, *delta))
+			}
+		}},
+		"add": {"<name> <item>...", 2, -1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			return addAll(ctx, args[0], args[1:], e.TopKAdd)
+		})},
+		"list": {"<name>", 1, 1, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			items, err := e.TopKList(args[0])
+			if err != nil {
+				return ctx.fail(err)
+			}
+			if ctx.json {
+				ctx.print("", items)
+				return EXIT_OK
+			}
+			for _, item := range items {
+				fmt.Fprintf(ctx.stdout, "%s\t%d\n", item.Key, item.Count)
+			}
+			return EXIT_OK
+		})},
+		"query": {"<name> <item>", 2, 2, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			count, err := e.TopKQuery(args[0], args[1])
+			return ctx.count(uint64(count), err)
+		})},
+		"delete": deleteSketch(engine.TK_KEY),
+	})
+}
+
+func runSimHash(ctx *context, args []string) int {
+	return runSubcommand(ctx, "simhash", args, map[string]subcommand{
+		"fingerprint": {"<name> (<text> | -)", 2, 2, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			text, err := ctx.readArg(args[1])
+			if err != nil {
+				return ctx.fail(err)
+			}
+			fingerprint, err := e.SimHashFingerprint(args[0], string(text))
+			if err != nil {
+				return ctx.fail(err)
+			}
+			ctx.print(fmt.Sprintf("%016x", fingerprint), map[string]string{"fingerprint": fmt.Sprintf("%016x", fingerprint)})
+			return EXIT_OK
+		})},
+		"distance": {"<name1> <name2>", 2, 2, noFlags(func(ctx *context, e *engine.Engine, args []string) int {
+			distance, err := e.SimHashDistance(args[0], args[1])
+			return ctx.count(uint64(distance), err)
+		})},
+		"delete": deleteSketch(engine.SH_KEY),
+	})
+}
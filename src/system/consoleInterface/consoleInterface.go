@@ -2,7 +2,7 @@ package consoleinterface
 
 import (
 	"bufio"
-	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -13,9 +13,6 @@ import (
 	"github.com/natasakasikovic/Key-Value-engine/src/utils"
 
 	"github.com/natasakasikovic/Key-Value-engine/src/model"
-	countMinSketch "github.com/natasakasikovic/Key-Value-engine/src/structs/CountMinSketch"
-	hyperLogLog "github.com/natasakasikovic/Key-Value-engine/src/structs/HyperLogLog"
-	bloomFilter "github.com/natasakasikovic/Key-Value-engine/src/structs/bloomFilter"
 	iterators "github.com/natasakasikovic/Key-Value-engine/src/structs/iterators"
 	scan "github.com/natasakasikovic/Key-Value-engine/src/structs/scan"
 	simHash "github.com/natasakasikovic/Key-Value-engine/src/structs/simHash"
@@ -28,9 +25,16 @@ const (
 	HLL_KEY = "hyperLogLog"
 	SH_KEY  = "simhash"
 	TB_KEY  = "tokenBucket"
+	CF_KEY  = "cuckooFilter"
+	TK_KEY  = "topK"
+	SHI_KEY = "simhashIndex"
+	SHN_KEY = "simhashNamespace"
 )
 
-func prefixScan(isSStableCompressed bool, prefix string, compressionMap map[string]uint64) ([]*model.Record, error) {
+// depth of the key range trees compared when syncing
+const SYNC_DEPTH = engine.DEFAULT_SYNC_DEPTH
+
+func prefixScan(isSStableCompressed bool, prefix string, compressionMap map[string]uint64) ([]*model.Record, iterators.ScanStats, error) {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	fmt.Print("Enter the page number: ")
@@ -40,7 +44,7 @@ func prefixScan(isSStableCompressed bool, prefix string, compressionMap map[stri
 	pgNumber, err := strconv.Atoi(strings.TrimSpace(pgNum))
 	if err != nil {
 		fmt.Println("Wrong input. Please try again.")
-		return nil, err
+		return nil, iterators.ScanStats{}, err
 	}
 
 	fmt.Print("Enter the page size: ")
@@ -50,14 +54,14 @@ func prefixScan(isSStableCompressed bool, prefix string, compressionMap map[stri
 	pageSize, err := strconv.Atoi(strings.TrimSpace(pgSize))
 	if err != nil {
 		fmt.Println("Wrong input. Please try again.")
-		return nil, err
+		return nil, iterators.ScanStats{}, err
 	}
 
-	records, err := scan.PrefixScan(prefix, pgNumber, pageSize, isSStableCompressed, compressionMap)
+	records, stats, err := scan.PrefixScanWithStats(prefix, pgNumber, pageSize, isSStableCompressed, compressionMap)
 	if err == nil {
-		return records, err
+		return records, stats, err
 	} else {
-		return nil, err
+		return nil, stats, err
 	}
 
 }
@@ -251,13 +255,15 @@ func scanning(engine *engine.Engine) {
 			fmt.Print("Enter the prefix: ")
 			scanner.Scan()
 			prefix := scanner.Text()
-			records, err := prefixScan(engine.Config.CompressionOn, prefix, engine.CompressionMap)
+			records, stats, err := prefixScan(engine.Config.CompressionOn, prefix, engine.CompressionMap)
 			if records == nil && err != nil {
 				fmt.Printf("err: %v\n", err)
 			} else if records != nil && err == nil {
 				for _, record := range records {
 					fmt.Printf("record: %v\n", record)
 				}
+				fmt.Printf("SSTables read: %d, skipped by key range: %d, skipped by prefix filter: %d\n",
+					stats.TablesRead, stats.TablesSkippedByRange, stats.TablesSkippedByFilter)
 			}
 		case 2:
 			records, err := rangeScan(engine.Config.CompressionOn, engine.CompressionMap)
@@ -313,7 +319,7 @@ func getByKey(engine *engine.Engine) {
 	var key string
 	fmt.Print("Enter the key: ")
 	fmt.Scanln(&key)
-	if strings.HasPrefix(key, BF_KEY) || strings.HasPrefix(key, CMS_KEY) || strings.HasPrefix(key, HLL_KEY) || strings.HasPrefix(key, SH_KEY) || strings.HasPrefix(key, TB_KEY) {
+	if isReservedKey(key) {
 		fmt.Println("key must not begin with system prefix")
 		return
 	}
@@ -330,7 +336,7 @@ func deleteRequest(engine *engine.Engine) {
 	var key string
 	fmt.Print("Enter the key: ")
 	fmt.Scanln(&key)
-	if strings.HasPrefix(key, BF_KEY) || strings.HasPrefix(key, CMS_KEY) || strings.HasPrefix(key, HLL_KEY) || strings.HasPrefix(key, SH_KEY) || strings.HasPrefix(key, TB_KEY) {
+	if isReservedKey(key) {
 		fmt.Println("key must not begin with system prefix")
 		return
 	}
@@ -348,7 +354,7 @@ func putRequest(engine *engine.Engine) {
 	fmt.Scanln(&key)
 	fmt.Print("Enter the value: ")
 	fmt.Scanln(&value)
-	if strings.HasPrefix(key, BF_KEY) || strings.HasPrefix(key, CMS_KEY) || strings.HasPrefix(key, HLL_KEY) || strings.HasPrefix(key, SH_KEY) || strings.HasPrefix(key, TB_KEY) {
+	if isReservedKey(key) {
 		fmt.Println("key must not begin with system prefix")
 		return
 	}
@@ -359,8 +365,67 @@ func putRequest(engine *engine.Engine) {
 		fmt.Printf("err: %v\n", err)
 	}
 }
+
+// Reads names of the instances to merge and the name of the result, then merges them with the passed function
+// Names can be written with or without the system prefix
+func mergeSketches(merge func(dst string, names []string) error) {
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Print("Enter the names of instances to merge, separated by commas: ")
+	scanner.Scan()
+	var names []string = make([]string, 0)
+	for _, name := range strings.Split(scanner.Text(), ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, trimSystemPrefix(name))
+		}
+	}
+
+	fmt.Print("Enter the name of the result: ")
+	scanner.Scan()
+	dst := trimSystemPrefix(strings.TrimSpace(scanner.Text()))
+	if len(names) == 0 || dst == "" {
+		fmt.Println("Wrong input. Please try again.")
+		return
+	}
+
+	err := merge(dst, names)
+	if err == nil {
+		fmt.Println("Request Successfully Completed")
+	} else {
+		fmt.Printf("err: %v\n", err)
+	}
+}
+
+// the engine parameter of the request functions hides the package
+func isReservedKey(key string) bool {
+	return engine.IsReservedKey(key)
+}
+
+// Removes the system prefix, so the name of an instance can be written as it is shown in the list of instances
+func trimSystemPrefix(name string) string {
+	for _, prefix := range []string{BF_KEY, CMS_KEY, HLL_KEY, SHI_KEY, SHN_KEY, SH_KEY, TB_KEY, CF_KEY, TK_KEY} {
+		if strings.HasPrefix(name, prefix+"_") {
+			return strings.TrimPrefix(name, prefix+"_")
+		}
+	}
+	return name
+}
+
+// Reads the name of an instance, it can be written with or without the system prefix
+func readName(scanner *bufio.Scanner, structure string) string {
+	fmt.Printf("Enter the %s name: ", structure)
+	scanner.Scan()
+	return trimSystemPrefix(strings.TrimSpace(scanner.Text()))
+}
+func printResult(err error) {
+	if err == nil {
+		fmt.Println("Request Successfully Completed")
+	} else {
+		fmt.Printf("err: %v\n", err)
+	}
+}
 func useBF(engine *engine.Engine) {
-	records, err := prefixScan(engine.Config.CompressionOn, BF_KEY, engine.CompressionMap)
+	records, _, err := prefixScan(engine.Config.CompressionOn, BF_KEY, engine.CompressionMap)
 	if err != nil || len(records) == 0 {
 		fmt.Println("There are no existing BloomFilters.")
 	} else if records != nil && err == nil {
@@ -376,7 +441,8 @@ func useBF(engine *engine.Engine) {
 		fmt.Println("2 --> Delete the existing instance")
 		fmt.Println("3 --> Insert new element")
 		fmt.Println("4 --> Check if the element exists")
-		fmt.Println("5 --> Exit")
+		fmt.Println("5 --> Merge instances into another one")
+		fmt.Println("6 --> Exit")
 
 		scanner.Scan()
 		input := scanner.Text()
@@ -388,99 +454,139 @@ func useBF(engine *engine.Engine) {
 		}
 		switch option {
 		case 1:
-			//create new bf
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the key: ")
-			scanner.Scan()
-			input := scanner.Text()
-			key := BF_KEY + "_" + input
+			name := readName(scanner, "BloomFilter")
 
 			fmt.Print("Enter the expected num of elems: ")
 			scanner.Scan()
-			expected := scanner.Text()
-			n, err1 := strconv.Atoi(strings.TrimSpace(expected))
-			if err1 != nil {
+			n, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+			if err != nil {
 				fmt.Println("Wrong input. Please try again.")
-				return
+				continue
 			}
 
 			fmt.Print("Enter the false positive rate(float): ")
 			scanner.Scan()
-			fpr := scanner.Text()
-			p, err1 := strconv.ParseFloat(fpr, 64)
-			if err1 != nil {
+			p, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+			if err != nil {
 				fmt.Println("Wrong input. Please try again.")
-				return
+				continue
 			}
 
-			bf := bloomFilter.NewBf(n, p)
-			value := bf.Serialize()
-			err := engine.Put(key, value)
-			if err == nil {
-				fmt.Println("Request Successfully Completed")
-			} else {
+			printResult(engine.BloomCreate(name, n, p))
+		case 2:
+			name := readName(scanner, "BloomFilter")
+			printResult(engine.DeleteSketch(BF_KEY, name))
+		case 3:
+			name := readName(scanner, "BloomFilter")
+			fmt.Print("Enter the element: ")
+			scanner.Scan()
+			printResult(engine.BloomAdd(name, scanner.Text()))
+		case 4:
+			name := readName(scanner, "BloomFilter")
+			fmt.Print("Enter the element: ")
+			scanner.Scan()
+			elem := scanner.Text()
+			exists, err := engine.BloomExists(name, elem)
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
+			} else if !exists {
+				fmt.Println("Element does not exist in the BloomFilter: " + name)
+			} else {
+				fmt.Println("Element might exist in the BloomFilter: " + name)
+			}
+		case 5:
+			mergeSketches(engine.MergeBloomFilters)
+		case 6:
+			fmt.Println("Exit.")
+			return
+		default:
+			fmt.Println("Wrong input. Please try again.")
+		}
+	}
+}
+func useCF(engine *engine.Engine) {
+	records, _, err := prefixScan(engine.Config.CompressionOn, CF_KEY, engine.CompressionMap)
+	if err != nil || len(records) == 0 {
+		fmt.Println("There are no existing CuckooFilters.")
+	} else if records != nil && err == nil {
+		for _, record := range records {
+			fmt.Printf("record: %v\n", record)
+		}
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Println("\nCuckooFilter")
+		fmt.Println("\nChoose an option:")
+		fmt.Println("1 --> Create new instance")
+		fmt.Println("2 --> Delete the existing instance")
+		fmt.Println("3 --> Insert new element")
+		fmt.Println("4 --> Check if the element exists")
+		fmt.Println("5 --> Delete an element")
+		fmt.Println("6 --> Count elements")
+		fmt.Println("7 --> Exit")
+
+		scanner.Scan()
+		input := scanner.Text()
+
+		option, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil {
+			fmt.Println("Wrong input. Please try again.")
+			continue
+		}
+		switch option {
+		case 1:
+			name := readName(scanner, "CuckooFilter")
+
+			fmt.Print("Enter the expected num of elems: ")
+			scanner.Scan()
+			n, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+			if err != nil {
+				fmt.Println("Wrong input. Please try again.")
+				continue
 			}
 
+			printResult(engine.CuckooCreate(name, n))
 		case 2:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the BloomFilter name: ")
+			name := readName(scanner, "CuckooFilter")
+			printResult(engine.DeleteSketch(CF_KEY, name))
+		case 3:
+			name := readName(scanner, "CuckooFilter")
+			fmt.Print("Enter the element: ")
 			scanner.Scan()
-			key := scanner.Text()
-			err := engine.Delete(key)
-			if err == nil {
-				fmt.Println("Request Successfully Completed")
-			} else {
+			printResult(engine.CuckooAdd(name, scanner.Text()))
+		case 4:
+			name := readName(scanner, "CuckooFilter")
+			fmt.Print("Enter the element: ")
+			scanner.Scan()
+			exists, err := engine.CuckooExists(name, scanner.Text())
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
+			} else if !exists {
+				fmt.Println("Element does not exist in the CuckooFilter: " + name)
+			} else {
+				fmt.Println("Element might exist in the CuckooFilter: " + name)
 			}
-		case 3:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the BloomFilter name: ")
-			scanner.Scan()
-			key := scanner.Text()
-			value, err := engine.Get(key)
-			if value == nil && err == nil {
-				fmt.Println("Bloom filter does not exists.")
-			} else if err != nil {
+		case 5:
+			name := readName(scanner, "CuckooFilter")
+			fmt.Print("Enter the element: ")
+			scanner.Scan()
+			removed, err := engine.CuckooRemove(name, scanner.Text())
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
+			} else if !removed {
+				fmt.Println("Element does not exist in the CuckooFilter: " + name)
 			} else {
-				bf := bloomFilter.Deserialize(value)
-				fmt.Print("Enter the element: ")
-				scanner.Scan()
-				elem := scanner.Text()
-				bf.Insert(elem)
-				serializedBf := bf.Serialize()
-				err := engine.Put(key, serializedBf)
-				if err == nil {
-					fmt.Println("Request Successfully Completed")
-				} else {
-					fmt.Printf("err: %v\n", err)
-				}
+				fmt.Println("Request Successfully Completed")
 			}
-		case 4:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the BloomFilter name: ")
-			scanner.Scan()
-			key := scanner.Text()
-			value, err := engine.Get(key)
-			if value == nil && err == nil {
-				fmt.Println("Bloom filter does not exist.")
-			} else if err != nil {
+		case 6:
+			name := readName(scanner, "CuckooFilter")
+			count, err := engine.CuckooCount(name)
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
 			} else {
-				bf := bloomFilter.Deserialize(value)
-				fmt.Print("Enter the element: ")
-				scanner.Scan()
-				elem := scanner.Text()
-				exists := bf.Find(elem)
-				if !exists {
-					fmt.Println("Element does not exists in BloomFilter" + key)
-				} else {
-					fmt.Println("Element might exist in the BloomFilter: " + key)
-				}
+				fmt.Printf("Number of elements: %d\n", count)
 			}
-
-		case 5:
+		case 7:
 			fmt.Println("Exit.")
 			return
 		default:
@@ -488,10 +594,10 @@ func useBF(engine *engine.Engine) {
 		}
 	}
 }
-func useCMS(engine *engine.Engine) {
-	records, err := prefixScan(engine.Config.CompressionOn, CMS_KEY, engine.CompressionMap)
+func useTopK(engine *engine.Engine) {
+	records, _, err := prefixScan(engine.Config.CompressionOn, TK_KEY, engine.CompressionMap)
 	if err != nil || len(records) == 0 {
-		fmt.Println("There are no existing instances of CountMinSketch.")
+		fmt.Println("There are no existing instances of TopK.")
 	} else if records != nil && err == nil {
 		for _, record := range records {
 			fmt.Printf("record: %v\n", record)
@@ -499,13 +605,14 @@ func useCMS(engine *engine.Engine) {
 	}
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
-		fmt.Println("\nCountMinSketch")
+		fmt.Println("\nTopK")
 		fmt.Println("\nChoose an option:")
 		fmt.Println("1 --> Create new instance")
 		fmt.Println("2 --> Delete the existing instance")
-		fmt.Println("3 --> Insert new event")
-		fmt.Println("4 --> Check the frequency of events")
-		fmt.Println("5 --> Exit")
+		fmt.Println("3 --> Insert new item")
+		fmt.Println("4 --> List the most frequent items")
+		fmt.Println("5 --> Check the frequency of an item")
+		fmt.Println("6 --> Exit")
 
 		scanner.Scan()
 		input := scanner.Text()
@@ -517,96 +624,139 @@ func useCMS(engine *engine.Engine) {
 		}
 		switch option {
 		case 1:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the key: ")
+			name := readName(scanner, "TopK")
+
+			fmt.Print("Enter k: ")
 			scanner.Scan()
-			input := scanner.Text()
-			key := CMS_KEY + "_" + input
+			k, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 32)
+			if err != nil {
+				fmt.Println("Wrong input. Please try again.")
+				continue
+			}
 
 			fmt.Print("Enter the epsilon(float): ")
 			scanner.Scan()
-			epsilon := scanner.Text()
-			e, err1 := strconv.ParseFloat(epsilon, 64)
-			if err1 != nil {
+			e, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+			if err != nil {
 				fmt.Println("Wrong input. Please try again.")
-				return
+				continue
 			}
 
 			fmt.Print("Enter the delta(float): ")
 			scanner.Scan()
-			delta := scanner.Text()
-			d, err1 := strconv.ParseFloat(delta, 64)
-			if err1 != nil {
+			d, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+			if err != nil {
 				fmt.Println("Wrong input. Please try again.")
-				return
-			}
-
-			cms := countMinSketch.CreateCMS(e, d)
-			value := cms.Serialize()
-
-			err := engine.Put(key, value)
-			if err == nil {
-				fmt.Println("Request Successfully Completed")
-			} else {
-				fmt.Printf("err: %v\n", err)
+				continue
 			}
 
+			printResult(engine.TopKCreate(name, uint32(k), e, d))
 		case 2:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the CountMinSketch name: ")
+			name := readName(scanner, "TopK")
+			printResult(engine.DeleteSketch(TK_KEY, name))
+		case 3:
+			name := readName(scanner, "TopK")
+			fmt.Print("Enter the item: ")
 			scanner.Scan()
-			key := scanner.Text()
-			err := engine.Delete(key)
-			if err == nil {
-				fmt.Println("Request Successfully Completed")
-			} else {
+			printResult(engine.TopKAdd(name, scanner.Text()))
+		case 4:
+			name := readName(scanner, "TopK")
+			items, err := engine.TopKList(name)
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
+				continue
 			}
-		case 3:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the CountMinSketch name: ")
-			scanner.Scan()
-			key := scanner.Text()
-			value, err := engine.Get(key)
-			if value == nil && err == nil {
-				fmt.Println("CountMinSketch does not exist.")
-			} else if err != nil {
+			for i, item := range items {
+				fmt.Printf("%d. %s: %d\n", i+1, item.Key, item.Count)
+			}
+		case 5:
+			name := readName(scanner, "TopK")
+			fmt.Print("Enter the item: ")
+			scanner.Scan()
+			frequency, err := engine.TopKQuery(name, scanner.Text())
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
 			} else {
-				cms := countMinSketch.Deserialize(value)
-				fmt.Print("Enter the event: ")
-				scanner.Scan()
-				elem := scanner.Text()
-				cms.Insert(elem)
-				serializedCMS := cms.Serialize()
-				err := engine.Put(key, serializedCMS)
-				if err == nil {
-					fmt.Println("Request Successfully Completed")
-				} else {
-					fmt.Printf("err: %v\n", err)
-				}
+				fmt.Println("There are ", frequency, " occurrence(s)")
 			}
+		case 6:
+			fmt.Println("Exit.")
+			return
+		default:
+			fmt.Println("Wrong input. Please try again.")
+		}
+	}
+}
+func useCMS(engine *engine.Engine) {
+	records, _, err := prefixScan(engine.Config.CompressionOn, CMS_KEY, engine.CompressionMap)
+	if err != nil || len(records) == 0 {
+		fmt.Println("There are no existing instances of CountMinSketch.")
+	} else if records != nil && err == nil {
+		for _, record := range records {
+			fmt.Printf("record: %v\n", record)
+		}
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Println("\nCountMinSketch")
+		fmt.Println("\nChoose an option:")
+		fmt.Println("1 --> Create new instance")
+		fmt.Println("2 --> Delete the existing instance")
+		fmt.Println("3 --> Insert new event")
+		fmt.Println("4 --> Check the frequency of events")
+		fmt.Println("5 --> Merge instances into another one")
+		fmt.Println("6 --> Exit")
 
+		scanner.Scan()
+		input := scanner.Text()
+
+		option, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil {
+			fmt.Println("Wrong input. Please try again.")
+			continue
+		}
+		switch option {
+		case 1:
+			name := readName(scanner, "CountMinSketch")
+
+			fmt.Print("Enter the epsilon(float): ")
+			scanner.Scan()
+			e, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+			if err != nil {
+				fmt.Println("Wrong input. Please try again.")
+				continue
+			}
+
+			fmt.Print("Enter the delta(float): ")
+			scanner.Scan()
+			d, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+			if err != nil {
+				fmt.Println("Wrong input. Please try again.")
+				continue
+			}
+
+			printResult(engine.CMSCreate(name, e, d))
+		case 2:
+			name := readName(scanner, "CountMinSketch")
+			printResult(engine.DeleteSketch(CMS_KEY, name))
+		case 3:
+			name := readName(scanner, "CountMinSketch")
+			fmt.Print("Enter the event: ")
+			scanner.Scan()
+			printResult(engine.CMSIncr(name, scanner.Text()))
 		case 4:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the CountMinSketch name: ")
-			scanner.Scan()
-			key := scanner.Text()
-			value, err := engine.Get(key)
-			if value == nil && err == nil {
-				fmt.Println("CountMinSketch does not exists.")
-			} else if err != nil {
+			name := readName(scanner, "CountMinSketch")
+			fmt.Print("Enter the event: ")
+			scanner.Scan()
+			frequency, err := engine.CMSQuery(name, scanner.Text())
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
 			} else {
-				cms := countMinSketch.Deserialize(value)
-				fmt.Print("Enter the events: ")
-				scanner.Scan()
-				elem := scanner.Text()
-				frequency := cms.Search(elem)
 				fmt.Println("There are ", frequency, " event(s)")
 			}
-
 		case 5:
+			mergeSketches(engine.MergeCMS)
+		case 6:
 			fmt.Println("Exit.")
 			return
 		default:
@@ -615,7 +765,7 @@ func useCMS(engine *engine.Engine) {
 	}
 }
 func useHLL(engine *engine.Engine) {
-	records, err := prefixScan(engine.Config.CompressionOn, HLL_KEY, engine.CompressionMap)
+	records, _, err := prefixScan(engine.Config.CompressionOn, HLL_KEY, engine.CompressionMap)
 	if err != nil || len(records) == 0 {
 		fmt.Println("There are no existing instances of HyperLogLog")
 	} else if records != nil && err == nil {
@@ -631,7 +781,8 @@ func useHLL(engine *engine.Engine) {
 		fmt.Println("2 --> Delete the existing instance")
 		fmt.Println("3 --> Insert new element")
 		fmt.Println("4 --> Check the cardinality")
-		fmt.Println("5 --> Exit")
+		fmt.Println("5 --> Merge instances into another one")
+		fmt.Println("6 --> Exit")
 
 		scanner.Scan()
 		input := scanner.Text()
@@ -643,82 +794,36 @@ func useHLL(engine *engine.Engine) {
 		}
 		switch option {
 		case 1:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the key: ")
-			scanner.Scan()
-			input := scanner.Text()
-			key := HLL_KEY + "_" + input
+			name := readName(scanner, "HyperLogLog")
 
 			fmt.Print("Enter p: ")
 			scanner.Scan()
-			param := scanner.Text()
-			p, err1 := strconv.ParseUint(param, 10, 8)
-			if err1 != nil {
+			p, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 8)
+			if err != nil {
 				fmt.Println("Wrong input. Please try again.")
-				return
-			}
-
-			hll := hyperLogLog.CreateHLL(uint8(p))
-			value := hll.Serialize()
-			err := engine.Put(key, value)
-			if err == nil {
-				fmt.Println("Request Successfully Completed")
-			} else {
-				fmt.Printf("err: %v\n", err)
+				continue
 			}
 
+			printResult(engine.HLLCreate(name, uint8(p)))
 		case 2:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the HyperLogLog name: ")
-			scanner.Scan()
-			key := scanner.Text()
-			err := engine.Delete(key)
-			if err == nil {
-				fmt.Println("Request Successfully Completed")
-			} else {
-				fmt.Printf("err: %v\n", err)
-			}
+			name := readName(scanner, "HyperLogLog")
+			printResult(engine.DeleteSketch(HLL_KEY, name))
 		case 3:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the HyperLogLog name: ")
-			scanner.Scan()
-			key := scanner.Text()
-			value, err := engine.Get(key)
-			if value == nil && err == nil {
-				fmt.Println("HyperLogLog does not exists.")
-			} else if err != nil {
-				fmt.Printf("err: %v\n", err)
-			} else {
-				hll := hyperLogLog.Deserialize(value)
-				fmt.Print("Enter the element: ")
-				scanner.Scan()
-				elem := scanner.Text()
-				hll.Insert(elem)
-				serializedHLL := hll.Serialize()
-				err := engine.Put(key, serializedHLL)
-				if err == nil {
-					fmt.Println("Request Successfully Completed")
-				} else {
-					fmt.Printf("err: %v\n", err)
-				}
-			}
+			name := readName(scanner, "HyperLogLog")
+			fmt.Print("Enter the element: ")
+			scanner.Scan()
+			printResult(engine.HLLAdd(name, scanner.Text()))
 		case 4:
-			scanner := bufio.NewScanner(os.Stdin)
-			fmt.Print("Enter the HyperLogLog name: ")
-			scanner.Scan()
-			key := scanner.Text()
-			value, err := engine.Get(key)
-			if value == nil && err == nil {
-				fmt.Println("HyperLogLog does not exists.")
-			} else if err != nil {
+			name := readName(scanner, "HyperLogLog")
+			cardinality, err := engine.HLLCount(name)
+			if err != nil {
 				fmt.Printf("err: %v\n", err)
 			} else {
-				hll := hyperLogLog.Deserialize(value)
-				cardinality := hll.Estimate()
 				fmt.Println("There are ", cardinality, " different elements.")
 			}
-
 		case 5:
+			mergeSketches(engine.MergeHLL)
+		case 6:
 			fmt.Println("Exit.")
 			return
 		default:
@@ -726,6 +831,54 @@ func useHLL(engine *engine.Engine) {
 		}
 	}
 }
+
+// Reads options of a SimHash namespace, empty answers keep the default options
+// Returns false if an answer isn't valid
+func readSimHashOptions(scanner *bufio.Scanner) (simHash.Options, bool) {
+	options := simHash.DefaultOptions()
+	read := func(prompt string) string {
+		fmt.Print(prompt)
+		scanner.Scan()
+		return strings.TrimSpace(scanner.Text())
+	}
+
+	if tokenizer := read("Enter the tokenizer (whitespace, words, ngrams): "); tokenizer != "" {
+		options.Tokenizer = tokenizer
+	}
+	if options.Tokenizer == simHash.TOKENIZER_NGRAMS {
+		if size := read("Enter the n-gram size: "); size != "" {
+			n, err := strconv.Atoi(size)
+			if err != nil {
+				return options, false
+			}
+			options.NgramSize = n
+		}
+	}
+	if size := read("Enter the shingle size: "); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return options, false
+		}
+		options.ShingleSize = n
+	}
+	stopWords := read("Enter stop words separated by commas, or english for the English list: ")
+	if stopWords == "english" {
+		options.StopWords = simHash.ENGLISH_STOP_WORDS
+	} else if stopWords != "" {
+		for _, word := range strings.Split(stopWords, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				options.StopWords = append(options.StopWords, word)
+			}
+		}
+	}
+	if weighting := read("Enter the weighting (tf, tfidf): "); weighting != "" {
+		options.Weighting = weighting
+	}
+	if hash := read("Enter the hash (md5, xxhash): "); hash != "" {
+		options.Hash = hash
+	}
+	return options, options.Validate() == nil
+}
 func useSimHash(engine *engine.Engine) {
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
@@ -733,7 +886,16 @@ func useSimHash(engine *engine.Engine) {
 		fmt.Println("\nChoose an option:")
 		fmt.Println("1 --> Save fingerprint")
 		fmt.Println("2 --> Calculate Hamming distance")
-		fmt.Println("3 --> Exit")
+		fmt.Println("3 --> Create near-duplicate index")
+		fmt.Println("4 --> Delete near-duplicate index")
+		fmt.Println("5 --> Add document to index")
+		fmt.Println("6 --> Remove document from index")
+		fmt.Println("7 --> Find similar documents")
+		fmt.Println("8 --> Create namespace")
+		fmt.Println("9 --> Delete namespace")
+		fmt.Println("10 --> Save fingerprint in namespace")
+		fmt.Println("11 --> Calculate Hamming distance in namespace")
+		fmt.Println("12 --> Exit")
 
 		scanner.Scan()
 		input := scanner.Text()
@@ -749,15 +911,9 @@ func useSimHash(engine *engine.Engine) {
 			scanner.Scan()
 			input := scanner.Text()
 
-			fingerprint := simHash.GetFingerprint(input)
-			byteSlice := make([]byte, 8)
-			binary.BigEndian.PutUint64(byteSlice, fingerprint)
-			err := engine.Put(SH_KEY+"_"+input, byteSlice)
-			if err == nil {
-				fmt.Println("Request Successfully Completed")
-			} else {
-				fmt.Printf("err: %v\n", err)
-			}
+			//The fingerprint is saved under the text itself
+			_, err := engine.SimHashFingerprint(input, input)
+			printResult(err)
 		case 2:
 			fmt.Print("Enter text1: ")
 			scanner.Scan()
@@ -767,24 +923,146 @@ func useSimHash(engine *engine.Engine) {
 			scanner.Scan()
 			input2 := scanner.Text()
 
-			var fingerprint1, fingerprint2 uint64
-			fp1, err1 := engine.Get(SH_KEY + "_" + input1)
-			if err1 == nil && fp1 != nil {
-				fingerprint1 = binary.BigEndian.Uint64(fp1)
-			} else if fp1 == nil {
-				fingerprint1 = simHash.GetFingerprint(input1)
+			//Saved fingerprints are made from the text they are saved under, so they are computed again
+			distance := simHash.HammingDistance(simHash.GetFingerprint(input1), simHash.GetFingerprint(input2))
+			fmt.Println("Hamming distance: ", distance)
+
+		case 3:
+			name := readName(scanner, "index")
+			fmt.Print("Enter the max distance of the index: ")
+			scanner.Scan()
+			maxDistance, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 8)
+			if err != nil {
+				fmt.Println("Wrong input. Please try again.")
+				continue
 			}
-			fp2, err2 := engine.Get(SH_KEY + "_" + input2)
-			if err2 == nil && fp2 != nil {
-				fingerprint2 = binary.BigEndian.Uint64(fp2)
-			} else if fp1 == nil {
-				fingerprint2 = simHash.GetFingerprint(input2)
+			fmt.Print("Enter the namespace (empty for the default options): ")
+			scanner.Scan()
+			printResult(engine.SimHashIndexCreate(name, uint8(maxDistance), trimSystemPrefix(strings.TrimSpace(scanner.Text()))))
+		case 4:
+			name := readName(scanner, "index")
+			printResult(engine.SimHashIndexDelete(name))
+		case 5:
+			name := readName(scanner, "index")
+			fmt.Print("Enter the document name: ")
+			scanner.Scan()
+			document := strings.TrimSpace(scanner.Text())
+			fmt.Print("Enter text: ")
+			scanner.Scan()
+			printResult(engine.SimHashIndexAdd(name, document, scanner.Text()))
+		case 6:
+			name := readName(scanner, "index")
+			fmt.Print("Enter the document name: ")
+			scanner.Scan()
+			printResult(engine.SimHashIndexRemove(name, strings.TrimSpace(scanner.Text())))
+		case 7:
+			name := readName(scanner, "index")
+			fmt.Print("Enter text: ")
+			scanner.Scan()
+			text := scanner.Text()
+			fmt.Print("Enter the max distance: ")
+			scanner.Scan()
+			distance, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 8)
+			if err != nil {
+				fmt.Println("Wrong input. Please try again.")
+				continue
+			}
+			matches, err := engine.SimHashIndexQuery(name, text, uint8(distance))
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+				continue
+			}
+			if len(matches) == 0 {
+				fmt.Println("There are no similar documents.")
+			}
+			for _, match := range matches {
+				fmt.Printf("%s (distance %d)\n", match.Document, match.Distance)
+			}
+		case 8:
+			name := readName(scanner, "namespace")
+			options, ok := readSimHashOptions(scanner)
+			if !ok {
+				fmt.Println("Wrong input. Please try again.")
+				continue
+			}
+			printResult(engine.SimHashNamespaceCreate(name, options))
+		case 9:
+			name := readName(scanner, "namespace")
+			printResult(engine.SimHashNamespaceDelete(name))
+		case 10:
+			namespace := readName(scanner, "namespace")
+			fmt.Print("Enter the fingerprint name: ")
+			scanner.Scan()
+			name := strings.TrimSpace(scanner.Text())
+			fmt.Print("Enter text: ")
+			scanner.Scan()
+			fingerprint, err := engine.SimHashNamespaceFingerprint(namespace, name, scanner.Text())
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+				continue
+			}
+			fmt.Printf("Fingerprint: %064b\n", fingerprint)
+		case 11:
+			namespace := readName(scanner, "namespace")
+			fmt.Print("Enter the first fingerprint name: ")
+			scanner.Scan()
+			name1 := strings.TrimSpace(scanner.Text())
+			fmt.Print("Enter the second fingerprint name: ")
+			scanner.Scan()
+			distance, err := engine.SimHashNamespaceDistance(namespace, name1, strings.TrimSpace(scanner.Text()))
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+				continue
 			}
-
-			distance := simHash.HammingDistance(fingerprint1, fingerprint2)
 			fmt.Println("Hamming distance: ", distance)
+		case 12:
+			fmt.Println("Exit.")
+			return
+		default:
+			fmt.Println("Wrong input. Please try again.")
+		}
+	}
+}
+func useMerkle(engine *engine.Engine) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Println("\nChoose an option:")
+		fmt.Println("1 --> Check sstable for changes")
+		fmt.Println("2 --> Get record with proof")
+		fmt.Println("3 --> Verify all sstables and the log")
+		fmt.Println("4 --> Exit")
 
+		scanner.Scan()
+		choice, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			fmt.Println("Wrong input. Please try again.")
+			continue
+		}
+
+		switch choice {
+		case 1:
+			checkSSTable(engine)
+		case 2:
+			fmt.Print("Enter key: ")
+			scanner.Scan()
+			proof, err := engine.GetWithProof(strings.TrimSpace(scanner.Text()))
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+				continue
+			}
+			fmt.Println("SSTable: ", proof.Table)
+			fmt.Println("Record: ", proof.Record.String())
+			fmt.Printf("Merkle root (%s): %x\n", proof.HashType, proof.Root)
+			fmt.Println("Proof verified: ", proof.Verify(proof.HashType, proof.Root, proof.Leaves))
 		case 3:
+			fmt.Print("Move damaged sstables to quarantine? (y/n): ")
+			scanner.Scan()
+			report, err := engine.Verify(strings.TrimSpace(scanner.Text()) == "y")
+			if report != nil {
+				printReport(report)
+			}
+			printResult(err)
+		case 4:
 			fmt.Println("Exit.")
 			return
 		default:
@@ -792,7 +1070,7 @@ func useSimHash(engine *engine.Engine) {
 		}
 	}
 }
-func useMerkle(engine *engine.Engine) {
+func checkSSTable(engine *engine.Engine) {
 	var err error
 	fmt.Println("Please enter path of sstable:")
 	scanner := bufio.NewScanner(os.Stdin)
@@ -808,16 +1086,19 @@ func useMerkle(engine *engine.Engine) {
 
 	if len(content) == 1 {
 		sstableLoaded, err = sstable.LoadSStableSingle(sstablePath)
-		if err != nil {
-			log.Fatal(err)
-		}
 	} else {
 		sstableLoaded, err = sstable.LoadSSTableSeparate(sstablePath)
-		if err != nil {
-			log.Fatal(err)
-		}
 	}
+	if err != nil {
+		fmt.Printf("err: %v\n", err)
+		return
+	}
+	defer sstableLoaded.Close()
 	sstableLoaded.LoadMerkle(len(content) != 1, sstablePath)
+	if sstableLoaded.Merkle == nil {
+		fmt.Println("Merkle tree of the sstable is not valid.")
+		return
+	}
 
 	// load data
 	offset1 := sstableLoaded.DataOffset
@@ -845,7 +1126,7 @@ func useMerkle(engine *engine.Engine) {
 
 	var bytesToCheck [][]byte
 	for _, record := range records {
-		bytesToAppend, _ := record.Serialize(engine.Config.CompressionOn, engine.CompressionMap)
+		bytesToAppend, _ := sstable.MerkleLeaf(sstableLoaded.Merkle, record, engine.Config.CompressionOn, engine.CompressionMap)
 		bytesToCheck = append(bytesToCheck, bytesToAppend)
 	}
 
@@ -858,6 +1139,152 @@ func useMerkle(engine *engine.Engine) {
 	}
 
 }
+func compaction(engine *engine.Engine) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		state := "running"
+		if engine.IsCompactionPaused() {
+			state = "paused"
+		}
+		fmt.Println("\nCompaction (" + state + ")")
+		fmt.Println("\nChoose an option:")
+		fmt.Println("1 --> Compact range")
+		fmt.Println("2 --> Compact all")
+		fmt.Println("3 --> Pause compaction")
+		fmt.Println("4 --> Resume compaction")
+		fmt.Println("5 --> Set I/O rate limit")
+		fmt.Println("6 --> Exit")
+
+		scanner.Scan()
+		input := scanner.Text()
+
+		option, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil {
+			fmt.Println("Wrong input. Please try again.")
+			continue
+		}
+		switch option {
+		case 1:
+			fmt.Print("Enter start of the range: ")
+			scanner.Scan()
+			start := scanner.Text()
+
+			fmt.Print("Enter end of the range: ")
+			scanner.Scan()
+			end := scanner.Text()
+
+			err := engine.CompactRange(start, end)
+			if err == nil {
+				fmt.Println("Request Successfully Completed")
+			} else {
+				fmt.Printf("err: %v\n", err)
+			}
+		case 2:
+			err := engine.CompactAll()
+			if err == nil {
+				fmt.Println("Request Successfully Completed")
+			} else {
+				fmt.Printf("err: %v\n", err)
+			}
+		case 3:
+			engine.PauseCompaction()
+			fmt.Println("Request Successfully Completed")
+		case 4:
+			err := engine.ResumeCompaction()
+			if err == nil {
+				fmt.Println("Request Successfully Completed")
+			} else {
+				fmt.Printf("err: %v\n", err)
+			}
+		case 5:
+			fmt.Print("Enter max number of bytes per second (0 for no limit): ")
+			scanner.Scan()
+			limit, err := strconv.ParseUint(strings.TrimSpace(scanner.Text()), 10, 64)
+			if err != nil {
+				fmt.Println("Wrong input. Please try again.")
+				continue
+			}
+			engine.SetCompactionRateLimit(limit)
+			fmt.Println("Request Successfully Completed")
+		case 6:
+			fmt.Println("Exit.")
+			return
+		default:
+			fmt.Println("Wrong input. Please try again.")
+		}
+	}
+}
+func accessStatistics(engine *engine.Engine) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Println("\nAccess statistics")
+		fmt.Println("\nChoose an option:")
+		fmt.Println("1 --> Show hot keys and prefixes")
+		fmt.Println("2 --> Check accesses of a key")
+		fmt.Println("3 --> Check accesses of a prefix")
+		fmt.Println("4 --> Reset statistics")
+		fmt.Println("5 --> Exit")
+
+		scanner.Scan()
+		input := scanner.Text()
+
+		option, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil {
+			fmt.Println("Wrong input. Please try again.")
+			continue
+		}
+		switch option {
+		case 1:
+			report, err := engine.AccessStats()
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+				continue
+			}
+			fmt.Printf("Reads: %d, writes: %d (1 of %d accesses sampled)\n", report.Reads, report.Writes, report.SampleRate)
+			fmt.Println("Most read keys:")
+			for i, item := range report.HotReads {
+				pinned := ""
+				if engine.Cache.IsPinned(item.Key) {
+					pinned = " (pinned in cache)"
+				}
+				fmt.Printf("%d. %s: %d%s\n", i+1, item.Key, item.Count, pinned)
+			}
+			fmt.Println("Most written keys:")
+			for i, item := range report.HotWrites {
+				fmt.Printf("%d. %s: %d\n", i+1, item.Key, item.Count)
+			}
+			fmt.Println("Most accessed prefixes:")
+			for i, item := range report.HotPrefixes {
+				fmt.Printf("%d. %s: %d\n", i+1, item.Key, item.Count)
+			}
+		case 2:
+			fmt.Print("Enter the key: ")
+			scanner.Scan()
+			reads, writes, err := engine.KeyAccessCount(scanner.Text())
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+			} else {
+				fmt.Printf("Reads: %d, writes: %d\n", reads, writes)
+			}
+		case 3:
+			fmt.Print("Enter the prefix: ")
+			scanner.Scan()
+			count, err := engine.PrefixAccessCount(scanner.Text())
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+			} else {
+				fmt.Printf("Accesses: %d\n", count)
+			}
+		case 4:
+			printResult(engine.ResetAccessStats())
+		case 5:
+			fmt.Println("Exit.")
+			return
+		default:
+			fmt.Println("Wrong input. Please try again.")
+		}
+	}
+}
 func probStructs(engine *engine.Engine) {
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
@@ -866,7 +1293,9 @@ func probStructs(engine *engine.Engine) {
 		fmt.Println("2 --> CountMinSketch")
 		fmt.Println("3 --> HyperLogLog")
 		fmt.Println("4 --> SimHash")
-		fmt.Println("5 --> Exit")
+		fmt.Println("5 --> CuckooFilter")
+		fmt.Println("6 --> TopK")
+		fmt.Println("7 --> Exit")
 
 		scanner.Scan()
 		input := scanner.Text()
@@ -887,14 +1316,81 @@ func probStructs(engine *engine.Engine) {
 		case 4:
 			useSimHash(engine)
 		case 5:
+			useCF(engine)
+		case 6:
+			useTopK(engine)
+		case 7:
+			fmt.Println("Exit.")
+			return
+		default:
+			fmt.Println("Wrong input. Please try again.")
+		}
+
+	}
+}
+func syncEngines(engine *engine.Engine) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Println("\nSync")
+		fmt.Println("\nChoose an option:")
+		fmt.Println("1 --> Wait for an engine to sync with this one")
+		fmt.Println("2 --> Sync with a waiting engine")
+		fmt.Println("3 --> Show Merkle summary")
+		fmt.Println("4 --> Exit")
+
+		scanner.Scan()
+		input := scanner.Text()
+
+		option, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil {
+			fmt.Println("Wrong input. Please try again.")
+			continue
+		}
+		switch option {
+		case 1:
+			fmt.Print("Enter the socket path or host:port: ")
+			scanner.Scan()
+			fmt.Println("Waiting for an engine...")
+			printResult(engine.ServeSync(strings.TrimSpace(scanner.Text())))
+		case 2:
+			fmt.Print("Enter the socket path or host:port: ")
+			scanner.Scan()
+			address := strings.TrimSpace(scanner.Text())
+			fmt.Print("Enter the mode (mirror, twoway): ")
+			scanner.Scan()
+			report, err := engine.SyncWith(address, strings.TrimSpace(scanner.Text()), SYNC_DEPTH)
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+				continue
+			}
+			fmt.Printf("Nodes compared: %d, different leaves: %d\n", report.NodesCompared, report.DifferentLeaves)
+			fmt.Printf("Pushed: %d, pulled: %d, deleted: %d\n", report.Pushed, report.Pulled, report.Deleted)
+		case 3:
+			tree, err := engine.KeyRangeSummary(SYNC_DEPTH)
+			if err != nil {
+				fmt.Printf("err: %v\n", err)
+				continue
+			}
+			fmt.Printf("Root: %x\n", tree.Root())
+		case 4:
 			fmt.Println("Exit.")
 			return
 		default:
 			fmt.Println("Wrong input. Please try again.")
 		}
+	}
+}
 
+// prints the verify report as JSON
+func printReport(report *engine.VerifyReport) {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Printf("err: %v\n", err)
+		return
 	}
+	fmt.Println(string(content))
 }
+
 func StartEngine() {
 	engine, err := engine.NewEngine()
 	if err != nil {
@@ -910,8 +1406,11 @@ func StartEngine() {
 		fmt.Println("3 --> Delete")
 		fmt.Println("4 --> Use probabilistic structures")
 		fmt.Println("5 --> Use Merkle Tree")
-		fmt.Println("6 --> Clear Log")
-		fmt.Println("7 --> Exit")
+		fmt.Println("6 --> Compaction")
+		fmt.Println("7 --> Access statistics")
+		fmt.Println("8 --> Sync with another engine")
+		fmt.Println("9 --> Clear Log")
+		fmt.Println("10 --> Exit")
 
 		scanner.Scan()
 		input := scanner.Text()
@@ -938,11 +1437,17 @@ func StartEngine() {
 		case 5:
 			useMerkle(engine)
 		case 6:
+			compaction(engine)
+		case 7:
+			accessStatistics(engine)
+		case 8:
+			syncEngines(engine)
+		case 9:
 			err := engine.Wal.ClearLog()
 			if err != nil {
 				log.Fatal(err)
 			}
-		case 7:
+		case 10:
 			fmt.Println("Exit program.")
 			engine.Exit()
 			return
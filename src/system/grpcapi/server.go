@@ -0,0 +1,278 @@
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative kvpb/kv.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/WAL"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+	"github.com/natasakasikovic/Key-Value-engine/src/system/grpcapi/kvpb"
+)
+
+const (
+	SCAN_BATCH     = 256              // records read from the iterator while holding the engine lock, before they are sent
+	WATCH_BUFFER   = 1024             // changes a watcher can fall behind before it is closed
+	MAX_BATCH_SIZE = 10000            // most operations of one batch
+	MAX_MESSAGE    = 64 * 1024 * 1024 // largest message that is received
+)
+
+// Server answers gRPC clients of the KV service from kvpb/kv.proto
+// Requests are run one at a time through engine.Locked, scans hold the lock only while they read a batch of records
+type Server struct {
+	kvpb.UnimplementedKVServer
+	engine  *engine.Engine
+	grpc    *grpc.Server
+	closing chan struct{} // closed by Shutdown, so watch streams end
+	once    sync.Once
+}
+
+func NewServer(e *engine.Engine) *Server {
+	server := &Server{engine: e, closing: make(chan struct{})}
+	server.grpc = grpc.NewServer(grpc.MaxRecvMsgSize(MAX_MESSAGE))
+	kvpb.RegisterKVServer(server.grpc, server)
+	return server
+}
+
+// ListenAndServe listens on the TCP address and serves requests until Shutdown
+func (server *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(listener)
+}
+
+// Serve answers requests from connections accepted by the listener until Shutdown
+func (server *Server) Serve(listener net.Listener) error {
+	return server.grpc.Serve(listener)
+}
+
+// Shutdown stops accepting connections, ends watch streams and waits until other requests are finished
+// If the context ends first, the remaining requests are cancelled and the context error is returned
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.once.Do(func() {
+		close(server.closing)
+	})
+	done := make(chan struct{})
+	go func() {
+		server.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+// returns the error as a status with the code that matches it
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, engine.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, WAL.ErrRecordTooLarge):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func checkKey(key string) error {
+	if key == "" {
+		return status.Error(codes.InvalidArgument, "key must not be empty")
+	}
+	if engine.IsReservedKey(key) {
+		return status.Error(codes.InvalidArgument, "key must not begin with system prefix")
+	}
+	return nil
+}
+
+func (server *Server) Get(ctx context.Context, request *kvpb.GetRequest) (*kvpb.GetResponse, error) {
+	if err := checkKey(request.Key); err != nil {
+		return nil, err
+	}
+	var value []byte
+	var err error
+	server.engine.Locked(func() {
+		value, err = server.engine.Get(request.Key)
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if value == nil {
+		return nil, status.Errorf(codes.NotFound, "key %q not found", request.Key)
+	}
+	return &kvpb.GetResponse{Value: value}, nil
+}
+
+func (server *Server) Put(ctx context.Context, request *kvpb.PutRequest) (*kvpb.PutResponse, error) {
+	if err := checkKey(request.Key); err != nil {
+		return nil, err
+	}
+	var err error
+	server.engine.Locked(func() {
+		err = server.engine.Put(request.Key, request.Value)
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &kvpb.PutResponse{}, nil
+}
+
+func (server *Server) Delete(ctx context.Context, request *kvpb.DeleteRequest) (*kvpb.DeleteResponse, error) {
+	if err := checkKey(request.Key); err != nil {
+		return nil, err
+	}
+	var err error
+	server.engine.Locked(func() {
+		err = server.engine.Delete(request.Key)
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &kvpb.DeleteResponse{}, nil
+}
+
+// keys of all operations are checked before any of them is run
+func (server *Server) Batch(ctx context.Context, request *kvpb.BatchRequest) (*kvpb.BatchResponse, error) {
+	if len(request.Operations) > MAX_BATCH_SIZE {
+		return nil, status.Errorf(codes.InvalidArgument, "batch has more than %d operations", MAX_BATCH_SIZE)
+	}
+	for i, operation := range request.Operations {
+		var key string
+		switch op := operation.Op.(type) {
+		case *kvpb.Operation_Put:
+			key = op.Put.GetKey()
+		case *kvpb.Operation_Delete:
+			key = op.Delete
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "operation %d is empty", i)
+		}
+		if err := checkKey(key); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "operation %d: %s", i, status.Convert(err).Message())
+		}
+	}
+
+	var err error
+	var applied uint32 = 0
+	server.engine.Locked(func() {
+		for i, operation := range request.Operations {
+			switch op := operation.Op.(type) {
+			case *kvpb.Operation_Put:
+				err = server.engine.Put(op.Put.Key, op.Put.Value)
+			case *kvpb.Operation_Delete:
+				err = server.engine.Delete(op.Delete)
+			}
+			if err != nil {
+				err = fmt.Errorf("operation %d: %w", i, err)
+				return
+			}
+			applied++
+		}
+	})
+	if err != nil {
+		// operations before the failed one stay applied, so the client is told how many there are
+		failed := status.Convert(toStatus(err))
+		if withApplied, detailErr := failed.WithDetails(&kvpb.BatchResponse{Applied: applied}); detailErr == nil {
+			failed = withApplied
+		}
+		return nil, failed.Err()
+	}
+	return &kvpb.BatchResponse{Applied: applied}, nil
+}
+
+// The iterator is kept for the whole stream, records are read from it in batches while holding the engine lock
+// and sent after it is released, so a slow client doesn't stop other requests
+func (server *Server) Scan(request *kvpb.ScanRequest, stream kvpb.KV_ScanServer) error {
+	isRange := request.Start != "" || request.End != ""
+	if isRange && (request.Start == "" || request.End == "") {
+		return status.Error(codes.InvalidArgument, "range needs both start and end")
+	}
+	if isRange && request.Prefix != "" {
+		return status.Error(codes.InvalidArgument, "pass either prefix, or start and end")
+	}
+
+	var scanner *engine.Scanner
+	var err error
+	server.engine.Locked(func() {
+		if isRange {
+			scanner, err = server.engine.NewRangeScanner(request.Start, request.End)
+		} else {
+			scanner, err = server.engine.NewPrefixScanner(request.Prefix)
+		}
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+	defer scanner.Stop()
+
+	var sent uint64 = 0
+	for {
+		size := SCAN_BATCH
+		if request.Limit > 0 && request.Limit-sent < uint64(size) {
+			size = int(request.Limit - sent)
+		}
+		if size == 0 {
+			return nil
+		}
+		var records []*kvpb.Record
+		server.engine.Locked(func() {
+			batch, scanErr := scanner.Next(size)
+			err = scanErr
+			for _, record := range batch {
+				records = append(records, &kvpb.Record{Key: record.Key, Value: record.Value})
+			}
+		})
+		if err != nil {
+			return toStatus(err)
+		}
+		for _, record := range records {
+			if request.KeysOnly {
+				record.Value = nil
+			}
+			if err := stream.Send(record); err != nil {
+				return err
+			}
+		}
+		sent += uint64(len(records))
+		if len(records) < size {
+			return nil
+		}
+	}
+}
+
+// The stream ends when the client cancels it, when the server shuts down, or when the watcher falls behind
+func (server *Server) Watch(request *kvpb.WatchRequest, stream kvpb.KV_WatchServer) error {
+	watcher := server.engine.Watch(request.Prefix, WATCH_BUFFER)
+	defer watcher.Close()
+	// the headers are sent at once, so the client knows the watch started and can begin writing
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-server.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case change, ok := <-watcher.Changes():
+			if !ok {
+				return status.Error(codes.ResourceExhausted, watcher.Err().Error())
+			}
+			err := stream.Send(&kvpb.Change{Key: change.Key, Value: change.Value, Deleted: change.Deleted, Timestamp: change.Timestamp})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
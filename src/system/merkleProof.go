@@ -0,0 +1,42 @@
+package system
+
+import (
+	"errors"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/memtable"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/sstable"
+)
+
+// GetWithProof returns the latest version of the record with the key and the proof that it is saved in an sstable
+// If the key is deleted, the proof is of its tombstone
+// Records that are still in memtables have no proof, since they aren't in any merkle tree yet
+// The proof is checked with proof.Verify(hashType, root, leaves), where they are of the sstable's tree the client already trusts
+func (engine *Engine) GetWithProof(key string) (*sstable.RecordProof, error) {
+	if IsReservedKey(key) {
+		return nil, errors.New("key must not begin with system prefix")
+	}
+	if !engine.TokenBucket.IsRequestAvailable() {
+		return nil, ErrRateLimited
+	}
+	if _, err := memtable.Get(key); err == nil {
+		return nil, errors.New("record is in memtable, it has no proof until it is flushed")
+	}
+	for _, name := range engine.LSMTree.TableNames() {
+		record, err := sstable.SearchTables([]string{name}, key, engine.CompressionMap)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+		proof, err := sstable.ProveRecord(name, key, engine.Config.CompressionOn, engine.CompressionMap)
+		if err != nil {
+			return nil, err
+		}
+		if proof == nil {
+			return nil, errors.New("record was not found in the sstable")
+		}
+		return proof, nil
+	}
+	return nil, errors.New("record does not exist")
+}
@@ -0,0 +1,384 @@
+package repl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+type command struct {
+	args        string
+	description string
+	minArgs     int
+	maxArgs     int // -1 means there is no limit
+	run         func(repl *REPL, args []string) error
+	keyArg      func(args []string) bool // returns true if the arg after args is a key, used for completion
+	keywords    []string                 // words that are completed in args
+}
+
+func firstArg(args []string) bool {
+	return len(args) == 0
+}
+
+// commands without HELP, EXIT and QUIT, which are handled by the REPL
+var commands = map[string]command{
+	"GET": {args: "key", description: "prints the value of the key", minArgs: 1, maxArgs: 1, run: get, keyArg: firstArg},
+	"PUT": {args: "key value", description: "saves the value", minArgs: 2, maxArgs: 2, run: put, keyArg: firstArg},
+	"SET": {args: "key value", description: "same as PUT", minArgs: 2, maxArgs: 2, run: put, keyArg: firstArg},
+	"DEL": {args: "key", description: "deletes the key", minArgs: 1, maxArgs: 1, run: del, keyArg: firstArg},
+	"SCAN": {args: "PREFIX prefix | RANGE start end [LIMIT n]", description: "prints records in key order, a page at a time",
+		minArgs: 2, maxArgs: 5, run: scan, keywords: []string{"PREFIX", "RANGE", "LIMIT"},
+		keyArg: func(args []string) bool {
+			return len(args) > 0 && ((strings.EqualFold(args[0], "PREFIX") && len(args) == 1) ||
+				(strings.EqualFold(args[0], "RANGE") && len(args) <= 2))
+		}},
+	"COMPACT": {args: "[start end]", description: "compacts all sstables, or the ones that overlap the range", minArgs: 0, maxArgs: 2, run: compact, keyArg: func(args []string) bool { return len(args) < 2 }},
+	"STATS":   {args: "", description: "prints sstables per level, compaction state and access statistics", minArgs: 0, maxArgs: 0, run: stats},
+	"VERIFY":  {args: "[REPAIR]", description: "checks all sstables and the log, REPAIR moves damaged sstables to quarantine", minArgs: 0, maxArgs: 1, run: verify, keywords: []string{"REPAIR"}},
+	"HISTORY": {args: "", description: "prints entered commands", minArgs: 0, maxArgs: 0, run: history},
+
+	"BF.CREATE": {args: "name [expected] [fp]", description: "creates a bloom filter, by default for 1000 elements and 0.01 false positives", minArgs: 1, maxArgs: 3, run: bloomCreate},
+	"BF.ADD":    {args: "name element [element ...]", description: "adds elements to the bloom filter", minArgs: 2, maxArgs: -1, run: addAll(func(e *engine.Engine) func(string, string) error { return e.BloomAdd })},
+	"BF.EXISTS": {args: "name element", description: "checks if the element may be in the bloom filter", minArgs: 2, maxArgs: 2, run: exists(func(e *engine.Engine) func(string, string) (bool, error) { return e.BloomExists })},
+	"BF.MERGE":  {args: "dst name [name ...]", description: "merges bloom filters into dst", minArgs: 2, maxArgs: -1, run: merge(func(e *engine.Engine) func(string, []string) error { return e.MergeBloomFilters })},
+	"BF.DEL":    {args: "name", description: "deletes the bloom filter", minArgs: 1, maxArgs: 1, run: deleteSketch(engine.BF_KEY)},
+
+	"CMS.CREATE": {args: "name [epsilon] [delta]", description: "creates a count min sketch, by default with epsilon and delta 0.01", minArgs: 1, maxArgs: 3, run: cmsCreate},
+	"CMS.INCR":   {args: "name event [event ...]", description: "counts the events", minArgs: 2, maxArgs: -1, run: addAll(func(e *engine.Engine) func(string, string) error { return e.CMSIncr })},
+	"CMS.QUERY":  {args: "name event", description: "prints how many times the event happened", minArgs: 2, maxArgs: 2, run: cmsQuery},
+	"CMS.MERGE":  {args: "dst name [name ...]", description: "merges count min sketches into dst", minArgs: 2, maxArgs: -1, run: merge(func(e *engine.Engine) func(string, []string) error { return e.MergeCMS })},
+	"CMS.DEL":    {args: "name", description: "deletes the count min sketch", minArgs: 1, maxArgs: 1, run: deleteSketch(engine.CMS_KEY)},
+
+	"HLL.CREATE": {args: "name [precision]", description: "creates a hyperloglog, by default with precision 10", minArgs: 1, maxArgs: 2, run: hllCreate},
+	"HLL.ADD":    {args: "name element [element ...]", description: "adds elements to the hyperloglog", minArgs: 2, maxArgs: -1, run: addAll(func(e *engine.Engine) func(string, string) error { return e.HLLAdd })},
+	"HLL.COUNT":  {args: "name", description: "prints the estimated number of distinct elements", minArgs: 1, maxArgs: 1, run: hllCount},
+	"HLL.MERGE":  {args: "dst name [name ...]", description: "merges hyperloglogs into dst", minArgs: 2, maxArgs: -1, run: merge(func(e *engine.Engine) func(string, []string) error { return e.MergeHLL })},
+	"HLL.DEL":    {args: "name", description: "deletes the hyperloglog", minArgs: 1, maxArgs: 1, run: deleteSketch(engine.HLL_KEY)},
+
+	"CF.CREATE": {args: "name [expected]", description: "creates a cuckoo filter, by default for 1000 elements", minArgs: 1, maxArgs: 2, run: cuckooCreate},
+	"CF.ADD":    {args: "name element [element ...]", description: "adds elements to the cuckoo filter", minArgs: 2, maxArgs: -1, run: addAll(func(e *engine.Engine) func(string, string) error { return e.CuckooAdd })},
+	"CF.EXISTS": {args: "name element", description: "checks if the element may be in the cuckoo filter", minArgs: 2, maxArgs: 2, run: exists(func(e *engine.Engine) func(string, string) (bool, error) { return e.CuckooExists })},
+	"CF.REMOVE": {args: "name element", description: "removes the element from the cuckoo filter", minArgs: 2, maxArgs: 2, run: exists(func(e *engine.Engine) func(string, string) (bool, error) { return e.CuckooRemove })},
+	"CF.COUNT":  {args: "name", description: "prints the number of elements in the cuckoo filter", minArgs: 1, maxArgs: 1, run: cuckooCount},
+	"CF.DEL":    {args: "name", description: "deletes the cuckoo filter", minArgs: 1, maxArgs: 1, run: deleteSketch(engine.CF_KEY)},
+
+	"TOPK.CREATE": {args: "name [k] [epsilon] [delta]", description: "creates a top k sketch, by default with k 10 and epsilon and delta 0.01", minArgs: 1, maxArgs: 4, run: topKCreate},
+	"TOPK.ADD":    {args: "name item [item ...]", description: "counts the items", minArgs: 2, maxArgs: -1, run: addAll(func(e *engine.Engine) func(string, string) error { return e.TopKAdd })},
+	"TOPK.LIST":   {args: "name", description: "prints the most frequent items", minArgs: 1, maxArgs: 1, run: topKList},
+	"TOPK.QUERY":  {args: "name item", description: "prints the estimated count of the item", minArgs: 2, maxArgs: 2, run: topKQuery},
+	"TOPK.DEL":    {args: "name", description: "deletes the top k sketch", minArgs: 1, maxArgs: 1, run: deleteSketch(engine.TK_KEY)},
+
+	"SIMHASH.FINGERPRINT": {args: "name text", description: "saves and prints the fingerprint of the text", minArgs: 2, maxArgs: 2, run: simHashFingerprint},
+	"SIMHASH.DISTANCE":    {args: "name1 name2", description: "prints the hamming distance of two fingerprints", minArgs: 2, maxArgs: 2, run: simHashDistance},
+	"SIMHASH.DEL":         {args: "name", description: "deletes the fingerprint", minArgs: 1, maxArgs: 1, run: deleteSketch(engine.SH_KEY)},
+}
+
+func parseInt(arg string, name string) (int, error) {
+	value, err := strconv.Atoi(arg)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	return value, nil
+}
+
+func parseFloat(arg string, name string) (float64, error) {
+	value, err := strconv.ParseFloat(arg, 64)
+	if err != nil || value <= 0 || value >= 1 {
+		return 0, fmt.Errorf("%s must be a number between 0 and 1", name)
+	}
+	return value, nil
+}
+
+func ok(repl *REPL, err error) error {
+	if err != nil {
+		return err
+	}
+	return repl.println("OK")
+}
+
+func get(repl *REPL, args []string) error {
+	value, err := repl.engine.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return repl.println("(nil)")
+	}
+	return repl.println("%s", quote(value))
+}
+
+func put(repl *REPL, args []string) error {
+	return ok(repl, repl.engine.Put(args[0], []byte(args[1])))
+}
+
+func del(repl *REPL, args []string) error {
+	return ok(repl, repl.engine.Delete(args[0]))
+}
+
+func scan(repl *REPL, args []string) error {
+	limit := 0
+	if len(args) >= 2 && strings.EqualFold(args[len(args)-2], "LIMIT") {
+		var err error
+		if limit, err = parseInt(args[len(args)-1], "limit"); err != nil {
+			return err
+		}
+		args = args[:len(args)-2]
+	}
+
+	count := 0
+	var printErr error
+	print := func(record *model.Record) bool {
+		count++
+		printErr = repl.println("%d) %s %s", count, quote([]byte(record.Key)), quote(record.Value))
+		return printErr == nil && (limit == 0 || count < limit)
+	}
+	var err error
+	switch {
+	case strings.EqualFold(args[0], "PREFIX") && len(args) == 2:
+		err = repl.engine.ScanPrefix(args[1], print)
+	case strings.EqualFold(args[0], "RANGE") && len(args) == 3:
+		err = repl.engine.ScanRange(args[1], args[2], print)
+	default:
+		return errors.New("usage: SCAN PREFIX prefix | RANGE start end [LIMIT n]")
+	}
+	if err != nil {
+		return err
+	}
+	if printErr != nil {
+		return printErr
+	}
+	if count == 0 {
+		return repl.println("(empty)")
+	}
+	return nil
+}
+
+func compact(repl *REPL, args []string) error {
+	switch len(args) {
+	case 0:
+		return ok(repl, repl.engine.CompactAll())
+	case 2:
+		return ok(repl, repl.engine.CompactRange(args[0], args[1]))
+	}
+	return errors.New("usage: COMPACT [start end]")
+}
+
+func stats(repl *REPL, args []string) error {
+	return printJSON(repl, repl.engine.Status())
+}
+
+func verify(repl *REPL, args []string) error {
+	if len(args) == 1 && !strings.EqualFold(args[0], "REPAIR") {
+		return errors.New("usage: VERIFY [REPAIR]")
+	}
+	report, err := repl.engine.Verify(len(args) == 1)
+	if report != nil {
+		if err := printJSON(repl, report); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// prints the value as indented JSON, a line at a time so long values are paged
+func printJSON(repl *REPL, value any) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if err := repl.println("%s", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func history(repl *REPL, args []string) error {
+	for i, line := range repl.editor.history {
+		if err := repl.println("%4d  %s", i+1, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addAll(add func(e *engine.Engine) func(name string, element string) error) func(repl *REPL, args []string) error {
+	return func(repl *REPL, args []string) error {
+		for _, element := range args[1:] {
+			if err := add(repl.engine)(args[0], element); err != nil {
+				return err
+			}
+		}
+		return repl.println("OK")
+	}
+}
+
+func exists(check func(e *engine.Engine) func(name string, element string) (bool, error)) func(repl *REPL, args []string) error {
+	return func(repl *REPL, args []string) error {
+		exists, err := check(repl.engine)(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return repl.println("%v", exists)
+	}
+}
+
+func merge(merge func(e *engine.Engine) func(dst string, names []string) error) func(repl *REPL, args []string) error {
+	return func(repl *REPL, args []string) error {
+		return ok(repl, merge(repl.engine)(args[0], args[1:]))
+	}
+}
+
+func deleteSketch(prefix string) func(repl *REPL, args []string) error {
+	return func(repl *REPL, args []string) error {
+		return ok(repl, repl.engine.DeleteSketch(prefix, args[0]))
+	}
+}
+
+func bloomCreate(repl *REPL, args []string) error {
+	expected, rate := 1000, 0.01
+	var err error
+	if len(args) > 1 {
+		if expected, err = parseInt(args[1], "expected"); err != nil {
+			return err
+		}
+	}
+	if len(args) > 2 {
+		if rate, err = parseFloat(args[2], "fp"); err != nil {
+			return err
+		}
+	}
+	return ok(repl, repl.engine.BloomCreate(args[0], expected, rate))
+}
+
+func cmsCreate(repl *REPL, args []string) error {
+	epsilon, delta := 0.01, 0.01
+	var err error
+	if len(args) > 1 {
+		if epsilon, err = parseFloat(args[1], "epsilon"); err != nil {
+			return err
+		}
+	}
+	if len(args) > 2 {
+		if delta, err = parseFloat(args[2], "delta"); err != nil {
+			return err
+		}
+	}
+	return ok(repl, repl.engine.CMSCreate(args[0], epsilon, delta))
+}
+
+func cmsQuery(repl *REPL, args []string) error {
+	count, err := repl.engine.CMSQuery(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return repl.println("%d", count)
+}
+
+func hllCreate(repl *REPL, args []string) error {
+	precision := 10
+	if len(args) > 1 {
+		var err error
+		if precision, err = parseInt(args[1], "precision"); err != nil {
+			return err
+		}
+		if precision > 255 {
+			return errors.New("precision must be at most 255")
+		}
+	}
+	return ok(repl, repl.engine.HLLCreate(args[0], uint8(precision)))
+}
+
+func hllCount(repl *REPL, args []string) error {
+	count, err := repl.engine.HLLCount(args[0])
+	if err != nil {
+		return err
+	}
+	return repl.println("%d", count)
+}
+
+func cuckooCreate(repl *REPL, args []string) error {
+	expected := 1000
+	if len(args) > 1 {
+		var err error
+		if expected, err = parseInt(args[1], "expected"); err != nil {
+			return err
+		}
+	}
+	return ok(repl, repl.engine.CuckooCreate(args[0], expected))
+}
+
+func cuckooCount(repl *REPL, args []string) error {
+	count, err := repl.engine.CuckooCount(args[0])
+	if err != nil {
+		return err
+	}
+	return repl.println("%d", count)
+}
+
+func topKCreate(repl *REPL, args []string) error {
+	k, epsilon, delta := 10, 0.01, 0.01
+	var err error
+	if len(args) > 1 {
+		if k, err = parseInt(args[1], "k"); err != nil {
+			return err
+		}
+	}
+	if len(args) > 2 {
+		if epsilon, err = parseFloat(args[2], "epsilon"); err != nil {
+			return err
+		}
+	}
+	if len(args) > 3 {
+		if delta, err = parseFloat(args[3], "delta"); err != nil {
+			return err
+		}
+	}
+	return ok(repl, repl.engine.TopKCreate(args[0], uint32(k), epsilon, delta))
+}
+
+func topKList(repl *REPL, args []string) error {
+	items, err := repl.engine.TopKList(args[0])
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return repl.println("(empty)")
+	}
+	for i, item := range items {
+		if err := repl.println("%d) %s %d", i+1, quote([]byte(item.Key)), item.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func topKQuery(repl *REPL, args []string) error {
+	count, err := repl.engine.TopKQuery(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return repl.println("%d", count)
+}
+
+func simHashFingerprint(repl *REPL, args []string) error {
+	fingerprint, err := repl.engine.SimHashFingerprint(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return repl.println("%016x", fingerprint)
+}
+
+func simHashDistance(repl *REPL, args []string) error {
+	distance, err := repl.engine.SimHashDistance(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return repl.println("%d", distance)
+}
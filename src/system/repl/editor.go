@@ -0,0 +1,360 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	HISTORY_PATH = "../data/history"
+	HISTORY_SIZE = 1000 // number of lines kept in the history file
+)
+
+// keys read in raw mode
+const (
+	KEY_CTRL_A    = 1
+	KEY_CTRL_B    = 2
+	KEY_CTRL_C    = 3
+	KEY_CTRL_D    = 4
+	KEY_CTRL_E    = 5
+	KEY_CTRL_F    = 6
+	KEY_CTRL_H    = 8
+	KEY_TAB       = 9
+	KEY_LF        = 10
+	KEY_CTRL_K    = 11
+	KEY_CTRL_L    = 12
+	KEY_ENTER     = 13
+	KEY_CTRL_N    = 14
+	KEY_CTRL_P    = 16
+	KEY_CTRL_U    = 21
+	KEY_CTRL_W    = 23
+	KEY_ESC       = 27
+	KEY_BACKSPACE = 127
+)
+
+// completer returns candidates for the word that ends at the end of the line, and the index in the line where the word starts
+type completer func(line string) (int, []string)
+
+// lineEditor reads lines with editing keys, history and completion if the input is a terminal,
+// otherwise lines are read as they are, so commands can be piped in
+type lineEditor struct {
+	reader   *bufio.Reader
+	file     *os.File // input, if it is a terminal
+	out      io.Writer
+	history  []string
+	complete completer
+}
+
+func newLineEditor(in io.Reader, out io.Writer, complete completer) *lineEditor {
+	editor := &lineEditor{reader: bufio.NewReader(in), out: out, complete: complete}
+	if file, ok := in.(*os.File); ok {
+		if restore, err := makeRaw(file); err == nil {
+			restore()
+			editor.file = file
+			editor.loadHistory()
+		}
+	}
+	return editor
+}
+
+func (editor *lineEditor) isTerminal() bool {
+	return editor.file != nil
+}
+
+func (editor *lineEditor) loadHistory() {
+	content, err := os.ReadFile(HISTORY_PATH)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			editor.history = append(editor.history, line)
+		}
+	}
+	if len(editor.history) > HISTORY_SIZE {
+		editor.history = editor.history[len(editor.history)-HISTORY_SIZE:]
+	}
+}
+
+// saves the last HISTORY_SIZE lines
+func (editor *lineEditor) saveHistory() error {
+	if !editor.isTerminal() {
+		return nil
+	}
+	if len(editor.history) > HISTORY_SIZE {
+		editor.history = editor.history[len(editor.history)-HISTORY_SIZE:]
+	}
+	content := strings.Join(editor.history, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	// entered commands can hold values, so only the owner can read them,
+	// files saved with other permissions before are changed too, since WriteFile keeps them
+	if err := os.WriteFile(HISTORY_PATH, []byte(content), 0600); err != nil {
+		return err
+	}
+	return os.Chmod(HISTORY_PATH, 0600)
+}
+
+func (editor *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(editor.history) > 0 && editor.history[len(editor.history)-1] == line {
+		return
+	}
+	editor.history = append(editor.history, line)
+}
+
+// readKey waits for one key in raw mode, used for paging
+func (editor *lineEditor) readKey() (rune, error) {
+	restore, err := makeRaw(editor.file)
+	if err != nil {
+		return 0, err
+	}
+	defer restore()
+	r, _, err := editor.reader.ReadRune()
+	return r, err
+}
+
+// ReadLine prints the prompt and returns the line without the newline, io.EOF is returned when the input ends
+func (editor *lineEditor) ReadLine(prompt string) (string, error) {
+	if !editor.isTerminal() {
+		line, err := editor.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	restore, err := makeRaw(editor.file)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	state := &lineState{editor: editor, prompt: prompt, historyIndex: len(editor.history)}
+	state.refresh()
+	lastTab := false
+	for {
+		r, _, err := editor.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		tab := false
+		switch r {
+		case KEY_ENTER, KEY_LF:
+			fmt.Fprint(editor.out, "\r\n")
+			line := string(state.line)
+			editor.addHistory(line)
+			return line, nil
+		case KEY_CTRL_C:
+			fmt.Fprint(editor.out, "^C\r\n")
+			return "", nil
+		case KEY_CTRL_D:
+			if len(state.line) == 0 {
+				fmt.Fprint(editor.out, "\r\n")
+				return "", io.EOF
+			}
+			state.deleteAt(state.cursor)
+		case KEY_BACKSPACE, KEY_CTRL_H:
+			if state.cursor > 0 {
+				state.cursor--
+				state.deleteAt(state.cursor)
+			}
+		case KEY_CTRL_A:
+			state.cursor = 0
+		case KEY_CTRL_E:
+			state.cursor = len(state.line)
+		case KEY_CTRL_B:
+			state.move(-1)
+		case KEY_CTRL_F:
+			state.move(1)
+		case KEY_CTRL_K:
+			state.line = state.line[:state.cursor]
+		case KEY_CTRL_U:
+			state.line = state.line[state.cursor:]
+			state.cursor = 0
+		case KEY_CTRL_W:
+			state.deleteWord()
+		case KEY_CTRL_L:
+			fmt.Fprint(editor.out, "\x1b[H\x1b[2J")
+		case KEY_CTRL_P:
+			state.historyMove(-1)
+		case KEY_CTRL_N:
+			state.historyMove(1)
+		case KEY_TAB:
+			state.completeWord(lastTab)
+			tab = true
+		case KEY_ESC:
+			state.escape()
+		default:
+			if r >= 32 {
+				state.insert(r)
+			}
+		}
+		lastTab = tab
+		state.refresh()
+	}
+}
+
+// line that is being edited
+type lineState struct {
+	editor       *lineEditor
+	prompt       string
+	line         []rune
+	cursor       int
+	historyIndex int    // index of the shown history line, len(history) is the new line
+	saved        string // new line, kept while history is shown
+}
+
+// writes the prompt and the line again and puts the cursor where it is in the line
+func (state *lineState) refresh() {
+	fmt.Fprintf(state.editor.out, "\r%s%s\x1b[K", state.prompt, string(state.line))
+	if back := len(state.line) - state.cursor; back > 0 {
+		fmt.Fprintf(state.editor.out, "\x1b[%dD", back)
+	}
+}
+
+func (state *lineState) insert(r rune) {
+	state.line = append(state.line[:state.cursor], append([]rune{r}, state.line[state.cursor:]...)...)
+	state.cursor++
+}
+
+func (state *lineState) insertString(text string) {
+	for _, r := range text {
+		state.insert(r)
+	}
+}
+
+func (state *lineState) deleteAt(i int) {
+	if i < len(state.line) {
+		state.line = append(state.line[:i], state.line[i+1:]...)
+	}
+}
+
+func (state *lineState) move(offset int) {
+	state.cursor += offset
+	if state.cursor < 0 {
+		state.cursor = 0
+	} else if state.cursor > len(state.line) {
+		state.cursor = len(state.line)
+	}
+}
+
+// deletes the word before the cursor, with spaces after it
+func (state *lineState) deleteWord() {
+	start := state.cursor
+	for start > 0 && state.line[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && state.line[start-1] != ' ' {
+		start--
+	}
+	state.line = append(state.line[:start], state.line[state.cursor:]...)
+	state.cursor = start
+}
+
+func (state *lineState) historyMove(offset int) {
+	history := state.editor.history
+	index := state.historyIndex + offset
+	if index < 0 || index > len(history) {
+		return
+	}
+	if state.historyIndex == len(history) {
+		state.saved = string(state.line)
+	}
+	state.historyIndex = index
+	if index == len(history) {
+		state.line = []rune(state.saved)
+	} else {
+		state.line = []rune(history[index])
+	}
+	state.cursor = len(state.line)
+}
+
+// handles arrows, home, end and delete, which are sent as escape sequences
+func (state *lineState) escape() {
+	reader := state.editor.reader
+	first, _, err := reader.ReadRune()
+	if err != nil || (first != '[' && first != 'O') {
+		return
+	}
+	code, _, err := reader.ReadRune()
+	if err != nil {
+		return
+	}
+	if code >= '0' && code <= '9' {
+		// sequences like ESC [ 3 ~
+		end, _, err := reader.ReadRune()
+		if err != nil || end != '~' {
+			return
+		}
+		switch code {
+		case '1', '7':
+			state.cursor = 0
+		case '4', '8':
+			state.cursor = len(state.line)
+		case '3':
+			state.deleteAt(state.cursor)
+		}
+		return
+	}
+	switch code {
+	case 'A':
+		state.historyMove(-1)
+	case 'B':
+		state.historyMove(1)
+	case 'C':
+		state.move(1)
+	case 'D':
+		state.move(-1)
+	case 'H':
+		state.cursor = 0
+	case 'F':
+		state.cursor = len(state.line)
+	}
+}
+
+// completes the word before the cursor to the longest prefix that all candidates share,
+// candidates are listed if there is nothing to complete and tab was pressed twice
+func (state *lineState) completeWord(listCandidates bool) {
+	if state.editor.complete == nil {
+		return
+	}
+	before := string(state.line[:state.cursor])
+	start, candidates := state.editor.complete(before)
+	if len(candidates) == 0 {
+		return
+	}
+	word := before[start:]
+	common := candidates[0]
+	for _, candidate := range candidates[1:] {
+		common = commonPrefix(common, candidate)
+	}
+	if len(candidates) == 1 {
+		common += " "
+	}
+	if len(common) > len(word) && strings.HasPrefix(common, word) {
+		state.insertString(common[len(word):])
+		return
+	}
+	if listCandidates {
+		fmt.Fprintf(state.editor.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func commonPrefix(a string, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	// the prefix must not end in the middle of a character
+	for i > 0 && !utf8.ValidString(a[:i]) {
+		i--
+	}
+	return a[:i]
+}
@@ -0,0 +1,118 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// splits the line into args separated by spaces
+// Args can be quoted: in double quotes \" \\ \n \r \t and \xHH (any byte) are escaped, single quotes keep the text as it is
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var arg []byte
+	inArg := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, string(arg))
+				arg = nil
+				inArg = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unbalanced quotes")
+			}
+			arg = append(arg, line[i+1:i+1+end]...)
+			inArg = true
+			i += end + 1
+		case c == '"':
+			inArg = true
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '"' {
+					closed = true
+					break
+				}
+				if line[i] != '\\' {
+					arg = append(arg, line[i])
+					continue
+				}
+				if i+1 == len(line) {
+					break
+				}
+				i++
+				switch line[i] {
+				case 'n':
+					arg = append(arg, '\n')
+				case 'r':
+					arg = append(arg, '\r')
+				case 't':
+					arg = append(arg, '\t')
+				case 'x':
+					var b byte
+					if i+2 >= len(line) || !isHex(line[i+1]) || !isHex(line[i+2]) {
+						return nil, fmt.Errorf("invalid escape at %d, \\x needs two hex digits", i)
+					}
+					fmt.Sscanf(line[i+1:i+3], "%02x", &b)
+					arg = append(arg, b)
+					i += 2
+				default:
+					arg = append(arg, line[i])
+				}
+			}
+			if !closed {
+				return nil, errors.New("unbalanced quotes")
+			}
+		default:
+			arg = append(arg, c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args, nil
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// returns the value in double quotes, escaped so that it can be typed back as an arg
+func quote(value []byte) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for len(value) > 0 {
+		r, size := utf8.DecodeRune(value)
+		switch {
+		case r == '"' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case r == '\n':
+			builder.WriteString("\\n")
+		case r == '\r':
+			builder.WriteString("\\r")
+		case r == '\t':
+			builder.WriteString("\\t")
+		case r == utf8.RuneError && size == 1, !unicode.IsPrint(r):
+			for _, b := range value[:size] {
+				fmt.Fprintf(&builder, "\\x%02x", b)
+			}
+		default:
+			builder.WriteRune(r)
+		}
+		value = value[size:]
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func needsQuotes(arg string) bool {
+	return arg == "" || strings.ContainsAny(arg, " '") || quote([]byte(arg)) != "\""+arg+"\""
+}
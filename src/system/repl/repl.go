@@ -0,0 +1,185 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+const (
+	PROMPT          = "kv> "
+	PAGE_SIZE       = 20  // lines printed before asking for the next page
+	COMPLETION_KEYS = 100 // at most this many keys are read when completing a key
+)
+
+var errStop = errors.New("stopped")
+
+// REPL reads commands like "GET key" or "HLL.ADD name element" and runs them on the engine
+type REPL struct {
+	engine  *engine.Engine
+	editor  *lineEditor
+	out     io.Writer
+	printed int // lines printed by the current command, used for paging
+}
+
+func New(e *engine.Engine, in io.Reader, out io.Writer) *REPL {
+	repl := &REPL{engine: e, out: out}
+	repl.editor = newLineEditor(in, out, repl.complete)
+	return repl
+}
+
+// Run reads and runs commands until EXIT or the end of the input
+func (repl *REPL) Run() error {
+	if repl.editor.isTerminal() {
+		fmt.Fprintln(repl.out, "Type HELP for the list of commands, TAB completes commands and keys.")
+	}
+	defer repl.editor.saveHistory()
+	for {
+		line, err := repl.editor.ReadLine(PROMPT)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if repl.execute(line) {
+			return nil
+		}
+	}
+}
+
+// runs the line and returns true if the REPL should stop
+func (repl *REPL) execute(line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		fmt.Fprintf(repl.out, "err: %v\n", err)
+		return false
+	}
+	if len(args) == 0 {
+		return false
+	}
+	name := strings.ToUpper(args[0])
+	switch name {
+	case "EXIT", "QUIT":
+		return true
+	case "HELP":
+		repl.help(args[1:])
+		return false
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(repl.out, "err: unknown command %q, type HELP for the list of commands\n", args[0])
+		return false
+	}
+	args = args[1:]
+	if len(args) < cmd.minArgs || (cmd.maxArgs >= 0 && len(args) > cmd.maxArgs) {
+		fmt.Fprintf(repl.out, "usage: %s %s\n", name, cmd.args)
+		return false
+	}
+	repl.printed = 0
+	if err := cmd.run(repl, args); err != nil && err != errStop {
+		fmt.Fprintf(repl.out, "err: %v\n", err)
+	}
+	return false
+}
+
+// prints all commands, or the ones that are passed
+func (repl *REPL) help(names []string) {
+	if len(names) == 0 {
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	repl.printed = 0
+	for _, name := range names {
+		cmd, ok := commands[strings.ToUpper(name)]
+		if !ok {
+			fmt.Fprintf(repl.out, "err: unknown command %q\n", name)
+			continue
+		}
+		if repl.println("%s %s", strings.ToUpper(name), cmd.args) != nil || repl.println("    %s", cmd.description) != nil {
+			return
+		}
+	}
+	if len(names) > 1 {
+		repl.println("EXIT, QUIT\n    exits")
+	}
+}
+
+// println prints one line of output, asking for the next page after every PAGE_SIZE lines
+// returns errStop if the user doesn't want more
+func (repl *REPL) println(format string, args ...any) error {
+	if repl.editor.isTerminal() && repl.printed > 0 && repl.printed%PAGE_SIZE == 0 {
+		fmt.Fprint(repl.out, "-- more -- (Enter or Space for the next page, q to stop)")
+		key, err := repl.editor.readKey()
+		fmt.Fprint(repl.out, "\r\x1b[K")
+		if err != nil || key == 'q' || key == 'Q' || key == KEY_CTRL_C {
+			return errStop
+		}
+	}
+	fmt.Fprintf(repl.out, format+"\n", args...)
+	repl.printed++
+	return nil
+}
+
+// completes command names in the first word, keywords of the command, and keys where the command expects a key
+func (repl *REPL) complete(line string) (int, []string) {
+	start := strings.LastIndexAny(line, " \t") + 1
+	word := line[start:]
+	if strings.HasPrefix(word, "\"") || strings.HasPrefix(word, "'") {
+		return start, nil
+	}
+	args, err := splitArgs(line[:start])
+	if err != nil {
+		return start, nil
+	}
+
+	var candidates []string
+	if len(args) == 0 {
+		for name := range commands {
+			candidates = append(candidates, name)
+		}
+		candidates = append(candidates, "HELP", "EXIT", "QUIT")
+		return start, matchCase(word, candidates)
+	}
+	cmd, ok := commands[strings.ToUpper(args[0])]
+	if !ok {
+		return start, nil
+	}
+	if cmd.keyArg != nil && cmd.keyArg(args[1:]) {
+		repl.engine.ScanPrefix(word, func(record *model.Record) bool {
+			if needsQuotes(record.Key) {
+				// quoted keys can't be completed, but they are listed
+				candidates = append(candidates, quote([]byte(record.Key)))
+			} else {
+				candidates = append(candidates, record.Key)
+			}
+			return len(candidates) < COMPLETION_KEYS
+		})
+		return start, candidates
+	}
+	return start, matchCase(word, cmd.keywords)
+}
+
+// returns names that begin with the word, ignoring case, written in the case of the word
+func matchCase(word string, names []string) []string {
+	lower := word != "" && strings.ToLower(word) == word
+	var matches []string
+	for _, name := range names {
+		if !strings.HasPrefix(strings.ToUpper(name), strings.ToUpper(word)) {
+			continue
+		}
+		if lower {
+			name = strings.ToLower(name)
+		}
+		matches = append(matches, name)
+	}
+	sort.Strings(matches)
+	return matches
+}
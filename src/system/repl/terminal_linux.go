@@ -0,0 +1,38 @@
+//go:build linux
+
+package repl
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// puts the terminal in raw mode, so keys are read as they are pressed and aren't echoed
+// returns the function that restores the previous mode, or an error if the file isn't a terminal
+func makeRaw(file *os.File) (func(), error) {
+	var old syscall.Termios
+	if err := ioctl(file.Fd(), syscall.TCGETS, &old); err != nil {
+		return nil, err
+	}
+	raw := old
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.BRKINT | syscall.INPCK | syscall.ISTRIP
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(file.Fd(), syscall.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+	return func() {
+		ioctl(file.Fd(), syscall.TCSETS, &old)
+	}, nil
+}
+
+func ioctl(fd uintptr, request uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package repl
+
+import (
+	"errors"
+	"os"
+)
+
+// raw mode is only supported on linux, elsewhere lines are read as they are, without editing keys
+func makeRaw(file *os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported")
+}
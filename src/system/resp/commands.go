@@ -0,0 +1,349 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+const (
+	HLL_PRECISION    = 12   // precision of hyperloglogs made by PFADD, registers fit in a log segment of the default config
+	BF_EXPECTED      = 100  // size of bloom filters made by BF.ADD, as in RedisBloom
+	BF_RATE          = 0.01 // false positive rate of bloom filters made by BF.ADD
+	SCAN_COUNT       = 10   // records checked by SCAN if COUNT isn't passed
+	MAX_SCAN_CURSORS = 1024 // unfinished scans kept per connection, older ones are forgotten after it
+)
+
+type command struct {
+	arity int // number of args with the name, negative if it is the least number of args
+	run   func(server *Server, c *conn, args []string, w writer)
+}
+
+var commands = map[string]command{
+	"PING":       {-1, ping},
+	"ECHO":       {2, echo},
+	"QUIT":       {1, func(server *Server, c *conn, args []string, w writer) { w.simple("OK") }},
+	"SELECT":     {2, selectDB},
+	"COMMAND":    {-1, func(server *Server, c *conn, args []string, w writer) { w.array(0) }},
+	"CLIENT":     {-2, func(server *Server, c *conn, args []string, w writer) { w.simple("OK") }},
+	"GET":        {2, get},
+	"SET":        {-3, set},
+	"DEL":        {-2, del},
+	"EXISTS":     {-2, exists},
+	"SCAN":       {-2, scan},
+	"PFADD":      {-2, pfAdd},
+	"PFCOUNT":    {-2, pfCount},
+	"BF.RESERVE": {4, bfReserve},
+	"BF.ADD":     {3, bfAdd},
+	"BF.MADD":    {-3, bfAdd},
+	"BF.EXISTS":  {3, bfExists},
+	"BF.MEXISTS": {-3, bfExists},
+}
+
+// runs one command and writes its reply, returns true if the connection should be closed
+func (server *Server) execute(c *conn, rawArgs [][]byte, w writer) bool {
+	args := make([]string, len(rawArgs))
+	for i, arg := range rawArgs {
+		args[i] = string(arg)
+	}
+	name := strings.ToUpper(args[0])
+	cmd, ok := commands[name]
+	if !ok {
+		w.error(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return false
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		w.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(args[0])))
+		return false
+	}
+	server.engine.Locked(func() {
+		cmd.run(server, c, args, w)
+	})
+	return name == "QUIT"
+}
+
+func ping(server *Server, c *conn, args []string, w writer) {
+	switch len(args) {
+	case 1:
+		w.simple("PONG")
+	case 2:
+		w.bulk([]byte(args[1]))
+	default:
+		w.error("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+func echo(server *Server, c *conn, args []string, w writer) {
+	w.bulk([]byte(args[1]))
+}
+
+// there is only one database
+func selectDB(server *Server, c *conn, args []string, w writer) {
+	if args[1] != "0" {
+		w.error("ERR DB index is out of range")
+		return
+	}
+	w.simple("OK")
+}
+
+func get(server *Server, c *conn, args []string, w writer) {
+	value, err := server.engine.Get(args[1])
+	if err != nil {
+		w.error(errorMessage(err))
+		return
+	}
+	w.bulk(value)
+}
+
+// SET key value [NX | XX] [GET], expiration options aren't supported since records don't expire
+func set(server *Server, c *conn, args []string, w writer) {
+	var nx, xx, get bool
+	for _, option := range args[3:] {
+		switch strings.ToUpper(option) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GET":
+			get = true
+		case "EX", "PX", "EXAT", "PXAT", "KEEPTTL":
+			w.error("ERR expiration is not supported")
+			return
+		default:
+			w.error(errorMessage(errSyntax))
+			return
+		}
+	}
+	if nx && xx {
+		w.error(errorMessage(errSyntax))
+		return
+	}
+	var old []byte
+	if nx || xx || get {
+		var err error
+		if old, err = server.engine.Get(args[1]); err != nil {
+			w.error(errorMessage(err))
+			return
+		}
+	}
+	if (nx && old != nil) || (xx && old == nil) {
+		w.bulk(old)
+		return
+	}
+	if err := server.engine.Put(args[1], []byte(args[2])); err != nil {
+		w.error(errorMessage(err))
+		return
+	}
+	if get {
+		w.bulk(old)
+		return
+	}
+	w.simple("OK")
+}
+
+// replies with the number of keys that existed
+func del(server *Server, c *conn, args []string, w writer) {
+	var deleted int64 = 0
+	for _, key := range args[1:] {
+		value, err := server.engine.Get(key)
+		if err != nil {
+			w.error(errorMessage(err))
+			return
+		}
+		if value == nil {
+			continue
+		}
+		if err := server.engine.Delete(key); err != nil {
+			w.error(errorMessage(err))
+			return
+		}
+		deleted++
+	}
+	w.integer(deleted)
+}
+
+// replies with the number of keys that exist, a key passed more than once is counted more than once
+func exists(server *Server, c *conn, args []string, w writer) {
+	var count int64 = 0
+	for _, key := range args[1:] {
+		value, err := server.engine.Get(key)
+		if err != nil {
+			w.error(errorMessage(err))
+			return
+		}
+		if value != nil {
+			count++
+		}
+	}
+	w.integer(count)
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+// Cursors are kept by the connection, each one holds the last key it returned, so keys are returned in order
+// and a key that exists during the whole scan is returned once
+func scan(server *Server, c *conn, args []string, w writer) {
+	cursor, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		w.error("ERR invalid cursor")
+		return
+	}
+	pattern := "*"
+	count := SCAN_COUNT
+	keyType := "string"
+	for i := 2; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			w.error(errorMessage(errSyntax))
+			return
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {
+				w.error("ERR value is not an integer or out of range")
+				return
+			}
+		case "TYPE":
+			keyType = strings.ToLower(args[i+1])
+		default:
+			w.error(errorMessage(errSyntax))
+			return
+		}
+	}
+
+	var after string
+	started := cursor != 0
+	if started {
+		var ok bool
+		if after, ok = c.cursors[cursor]; !ok {
+			w.error("ERR invalid cursor")
+			return
+		}
+		delete(c.cursors, cursor)
+	}
+	var keys []string
+	checked := 0
+	last := after
+	fn := func(record *model.Record) bool {
+		checked++
+		last = record.Key
+		if keyType == "string" && matchPattern(pattern, record.Key) {
+			keys = append(keys, record.Key)
+		}
+		return checked < count
+	}
+	if started {
+		err = server.engine.ScanPrefixAfter(patternPrefix(pattern), after, fn)
+	} else {
+		err = server.engine.ScanPrefix(patternPrefix(pattern), fn)
+	}
+	if err != nil {
+		w.error(errorMessage(err))
+		return
+	}
+
+	// the scan is finished when fewer records than count were left
+	next := uint64(0)
+	if checked == count {
+		if len(c.cursors) >= MAX_SCAN_CURSORS {
+			c.cursors = make(map[uint64]string)
+		}
+		c.nextCursor++
+		next = c.nextCursor
+		c.cursors[next] = last
+	}
+	w.array(2)
+	w.bulk([]byte(strconv.FormatUint(next, 10)))
+	w.bulkStrings(keys)
+}
+
+// PFADD key [element ...], replies 1 if the hyperloglog was created or changed
+func pfAdd(server *Server, c *conn, args []string, w writer) {
+	changed, err := server.engine.HLLAddElements(args[1], args[2:], HLL_PRECISION)
+	if err != nil {
+		w.error(errorMessage(err))
+		return
+	}
+	w.integer(boolInt(changed))
+}
+
+// PFCOUNT key [key ...], with more keys replies with the count of their union
+func pfCount(server *Server, c *conn, args []string, w writer) {
+	count, err := server.engine.HLLCountUnion(args[1:])
+	if err != nil {
+		w.error(errorMessage(err))
+		return
+	}
+	w.integer(int64(count))
+}
+
+// BF.RESERVE key error_rate capacity
+func bfReserve(server *Server, c *conn, args []string, w writer) {
+	rate, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		w.error("ERR bad error rate")
+		return
+	}
+	capacity, err := strconv.Atoi(args[3])
+	if err != nil {
+		w.error("ERR bad capacity")
+		return
+	}
+	// checking an element fails only if the filter doesn't exist
+	_, err = server.engine.BloomExists(args[1], "")
+	if err == nil {
+		w.error("ERR item exists")
+		return
+	}
+	if !errors.Is(err, engine.ErrSketchNotFound) {
+		w.error(errorMessage(err))
+		return
+	}
+	w.result(server.engine.BloomCreate(args[1], capacity, rate))
+}
+
+// BF.ADD key item replies 1 if the item was added, BF.MADD key item [item ...] replies with an array of those
+func bfAdd(server *Server, c *conn, args []string, w writer) {
+	added, err := server.engine.BloomAddElements(args[1], args[2:], BF_EXPECTED, BF_RATE)
+	if err != nil {
+		w.error(errorMessage(err))
+		return
+	}
+	if strings.EqualFold(args[0], "BF.MADD") {
+		w.array(len(added))
+	}
+	for _, ok := range added {
+		w.integer(boolInt(ok))
+	}
+}
+
+// BF.EXISTS key item, BF.MEXISTS key item [item ...], items of a filter that doesn't exist don't exist
+func bfExists(server *Server, c *conn, args []string, w writer) {
+	results := make([]bool, 0, len(args)-2)
+	for _, item := range args[2:] {
+		found, err := server.engine.BloomExists(args[1], item)
+		if err != nil && !errors.Is(err, engine.ErrSketchNotFound) {
+			w.error(errorMessage(err))
+			return
+		}
+		results = append(results, found)
+	}
+	if strings.EqualFold(args[0], "BF.MEXISTS") {
+		w.array(len(results))
+	}
+	for _, found := range results {
+		w.integer(boolInt(found))
+	}
+}
+
+func boolInt(value bool) int64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package resp
+
+import "strings"
+
+// returns the part of the glob pattern before the first special character, all matching keys begin with it
+func patternPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// matchPattern reports whether the key matches the glob pattern as redis matches them:
+// * is any text, ? is any byte, [abc] [^abc] [a-z] are sets of bytes, and \ escapes the next byte
+// Only the last star is backtracked to, by giving it one more byte of the key, so matching takes at most
+// len(pattern) * len(key) steps however many stars the pattern has
+func matchPattern(pattern string, key string) bool {
+	p, k := 0, 0
+	star, starKey := -1, 0 // where the pattern goes on after the last star, and where in the key that was tried
+	for k < len(key) {
+		if p < len(pattern) {
+			if pattern[p] == '*' {
+				p++
+				star, starKey = p, k
+				continue
+			}
+			if next, ok := matchByte(pattern, p, key[k]); ok {
+				p = next
+				k++
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		starKey++
+		p, k = star, starKey
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matches the byte against the part of the pattern at p that isn't a star, returns where that part ends
+func matchByte(pattern string, p int, c byte) (int, bool) {
+	switch pattern[p] {
+	case '?':
+		return p + 1, true
+	case '[':
+		end, ok := matchSet(pattern[p:], c)
+		return p + end, ok
+	case '\\':
+		if p+1 < len(pattern) {
+			p++
+		}
+	}
+	return p + 1, pattern[p] == c
+}
+
+// matches the byte against the set at the start of the pattern, returns where the set ends
+func matchSet(pattern string, c byte) (int, bool) {
+	i := 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+	matched := false
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			matched = matched || pattern[i] == c
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			low, high := pattern[i], pattern[i+2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = matched || (c >= low && c <= high)
+			i += 2
+		default:
+			matched = matched || pattern[i] == c
+		}
+	}
+	if i < len(pattern) {
+		i++ // the closing bracket
+	}
+	return i, matched != negate
+}
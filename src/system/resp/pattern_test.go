@@ -0,0 +1,64 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"", "", true},
+		{"", "a", false},
+		{"user:*", "user:1", true},
+		{"user:*", "users:1", false},
+		{"*:name", "user:1:name", true},
+		{"*:name", "user:1:names", false},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "abcabc", true},
+		{"a*b*c", "acb", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[c-a]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"[\\]]", "]", true},
+		{"**a", "ba", true},
+		{"a**", "a", true},
+		{"*?", "", false},
+		{"*?", "x", true},
+	}
+	for _, test := range tests {
+		if got := matchPattern(test.pattern, test.key); got != test.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", test.pattern, test.key, got, test.want)
+		}
+	}
+}
+
+// KEYS and SCAN MATCH hold the engine lock while they match, so a pattern from a client must not take exponential time
+func TestMatchPatternWithManyStarsIsFast(t *testing.T) {
+	pattern := strings.Repeat("*a", 30) + "*b"
+	key := strings.Repeat("a", 10000)
+	start := time.Now()
+	if matchPattern(pattern, key) {
+		t.Fatal("pattern ending with b matched a key without b")
+	}
+	if !matchPattern(pattern, key+"b") {
+		t.Fatal("pattern didn't match")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("matching took %v", elapsed)
+	}
+}
@@ -0,0 +1,156 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+const (
+	MAX_BULK_SIZE  = 64 * 1024 * 1024 // largest argument that is accepted
+	MAX_ARRAY_SIZE = 1024 * 1024      // most arguments of one command
+	MAX_LINE       = 64 * 1024        // longest inline command, the reader buffer has this size
+)
+
+// errProtocol is returned for input that isn't RESP, after it the connection is closed since the rest can't be read
+type errProtocol struct {
+	message string
+}
+
+func (err errProtocol) Error() string {
+	return "Protocol error: " + err.message
+}
+
+// reads one line ending with \r\n, without the ending
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, errProtocol{"line is too long"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(line[:len(line)-1], []byte{'\r'}), nil
+}
+
+func parseSize(line []byte, max int) (int, error) {
+	size, err := strconv.Atoi(string(line))
+	if err != nil || size > max {
+		return 0, errProtocol{fmt.Sprintf("invalid size %q", line)}
+	}
+	return size, nil
+}
+
+// readCommand reads one command, sent as an array of bulk strings like clients do,
+// or as an inline command (args separated by spaces) like typed in telnet
+// Returns no args for an empty inline line
+func readCommand(reader *bufio.Reader) ([][]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] != '*' {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Fields(line), nil
+	}
+
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	count, err := parseSize(line[1:], MAX_ARRAY_SIZE)
+	if err != nil || count < 0 {
+		return nil, errProtocol{fmt.Sprintf("invalid multibulk length %q", line[1:])}
+	}
+	// the count alone doesn't make room for all args, they are appended as they arrive
+	args := make([][]byte, 0, min(count, 16))
+	for i := 0; i < count; i++ {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol{fmt.Sprintf("expected '$', got %q", line)}
+		}
+		size, err := parseSize(line[1:], MAX_BULK_SIZE)
+		if err != nil || size < 0 {
+			return nil, errProtocol{fmt.Sprintf("invalid bulk length %q", line[1:])}
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, arg); err != nil {
+			return nil, err
+		}
+		if !bytes.HasSuffix(arg, []byte("\r\n")) {
+			return nil, errProtocol{"bulk string doesn't end with CRLF"}
+		}
+		args = append(args, arg[:size])
+	}
+	return args, nil
+}
+
+// writer writes replies, they are buffered until Flush so pipelined commands are answered together
+type writer struct {
+	*bufio.Writer
+}
+
+func (w writer) simple(value string) {
+	w.WriteString("+" + value + "\r\n")
+}
+
+func (w writer) error(message string) {
+	w.WriteString("-" + message + "\r\n")
+}
+
+func (w writer) integer(value int64) {
+	w.WriteString(":" + strconv.FormatInt(value, 10) + "\r\n")
+}
+
+// writes the value as a bulk string, nil is written as the null bulk string
+func (w writer) bulk(value []byte) {
+	if value == nil {
+		w.WriteString("$-1\r\n")
+		return
+	}
+	w.WriteString("$" + strconv.Itoa(len(value)) + "\r\n")
+	w.Write(value)
+	w.WriteString("\r\n")
+}
+
+// writes the header of an array, its items are written after it
+func (w writer) array(size int) {
+	w.WriteString("*" + strconv.Itoa(size) + "\r\n")
+}
+
+func (w writer) bulkStrings(values []string) {
+	w.array(len(values))
+	for _, value := range values {
+		w.bulk([]byte(value))
+	}
+}
+
+// writes ok, or the error if there is one
+func (w writer) result(err error) {
+	if err != nil {
+		w.error(errorMessage(err))
+		return
+	}
+	w.simple("OK")
+}
+
+var errSyntax = errors.New("syntax error")
+
+// returns the message of the error as it is sent, with the error code in front
+func errorMessage(err error) string {
+	if errors.Is(err, engine.ErrRateLimited) {
+		return "RATELIMIT " + err.Error()
+	}
+	return "ERR " + err.Error()
+}
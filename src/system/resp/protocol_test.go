@@ -0,0 +1,87 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(input string) ([][]string, error) {
+	reader := bufio.NewReaderSize(strings.NewReader(input), MAX_LINE)
+	var commands [][]string
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return commands, err
+		}
+		command := make([]string, len(args))
+		for i, arg := range args {
+			command[i] = string(arg)
+		}
+		commands = append(commands, command)
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+	}{
+		{"inline", "PING\r\n", [][]string{{"PING"}}},
+		{"inline with spaces and LF only", "  set a   b \n", [][]string{{"set", "a", "b"}}},
+		{"empty inline", "\r\n", [][]string{{}}},
+		{"multibulk", "*2\r\n$3\r\nGET\r\n$1\r\na\r\n", [][]string{{"GET", "a"}}},
+		{"binary bulk", "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\na\r\nb\r\n", [][]string{{"SET", "k", "a\r\nb"}}},
+		{"empty bulk", "*2\r\n$4\r\nECHO\r\n$0\r\n\r\n", [][]string{{"ECHO", ""}}},
+		{"empty multibulk", "*0\r\n", [][]string{{}}},
+		{"pipelined", "*1\r\n$4\r\nPING\r\nGET a\r\n*2\r\n$3\r\nDEL\r\n$1\r\nb\r\n", [][]string{{"PING"}, {"GET", "a"}, {"DEL", "b"}}},
+	}
+	for _, test := range tests {
+		commands, err := readAll(test.input)
+		if err != io.EOF {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(commands, test.want) {
+			t.Fatalf("%s: read %q, want %q", test.name, commands, test.want)
+		}
+	}
+}
+
+func TestReadCommandRejectsBadLengths(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"negative multibulk length", "*-1\r\n"},
+		{"multibulk length isn't a number", "*abc\r\n"},
+		{"too many args", fmt.Sprintf("*%d\r\n", MAX_ARRAY_SIZE+1)},
+		{"arg isn't a bulk string", "*2\r\n$3\r\nGET\r\n:1\r\n"},
+		{"negative bulk length", "*1\r\n$-1\r\n"},
+		{"bulk length isn't a number", "*1\r\n$x\r\n"},
+		{"too long bulk", fmt.Sprintf("*1\r\n$%d\r\n", MAX_BULK_SIZE+1)},
+		{"bulk longer than its length", "*1\r\n$3\r\nGETX\r\n"},
+		{"too long inline", strings.Repeat("a", MAX_LINE+1) + "\r\n"},
+	}
+	for _, test := range tests {
+		_, err := readAll(test.input)
+		var protocolErr errProtocol
+		if !errors.As(err, &protocolErr) {
+			t.Fatalf("%s: expected a protocol error, got %v", test.name, err)
+		}
+	}
+}
+
+// a command cut off by the end of the input isn't a protocol error, the client just went away
+func TestReadCommandCutOff(t *testing.T) {
+	for _, input := range []string{"*2\r\n$3\r\nGET\r\n", "*1\r\n$3\r\nGE", "PING"} {
+		_, err := readAll(input)
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			t.Fatalf("%q: expected end of input, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = errors.New("resp: server closed")
+
+// Server answers redis clients, speaking RESP2 over TCP
+// Commands of all connections are run one at a time through engine.Locked
+type Server struct {
+	engine   *engine.Engine
+	listener net.Listener
+	closing  atomic.Bool
+
+	connsLock sync.Mutex
+	conns     map[*conn]struct{}
+	active    sync.WaitGroup
+}
+
+// state of one client connection
+type conn struct {
+	net.Conn
+	cursors    map[uint64]string // last key returned for every unfinished SCAN
+	nextCursor uint64
+}
+
+func NewServer(e *engine.Engine) *Server {
+	return &Server{engine: e, conns: make(map[*conn]struct{})}
+}
+
+// ListenAndServe listens on the TCP address and serves connections until Shutdown
+func (server *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(listener)
+}
+
+// Serve accepts connections from the listener and answers each one in its own goroutine until Shutdown
+func (server *Server) Serve(listener net.Listener) error {
+	server.connsLock.Lock()
+	server.listener = listener
+	server.connsLock.Unlock()
+	if server.closing.Load() {
+		listener.Close()
+		return ErrServerClosed
+	}
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			if server.closing.Load() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		c := &conn{Conn: netConn, cursors: make(map[uint64]string)}
+		server.connsLock.Lock()
+		if server.closing.Load() {
+			server.connsLock.Unlock()
+			netConn.Close()
+			return ErrServerClosed
+		}
+		server.conns[c] = struct{}{}
+		server.active.Add(1)
+		server.connsLock.Unlock()
+		go server.serveConn(c)
+	}
+}
+
+// Addr returns the address the server listens on, or nil before Serve
+func (server *Server) Addr() net.Addr {
+	server.connsLock.Lock()
+	defer server.connsLock.Unlock()
+	if server.listener == nil {
+		return nil
+	}
+	return server.listener.Addr()
+}
+
+// Shutdown stops accepting connections, lets every connection finish the commands it already sent and closes it
+// If the context ends first, the remaining connections are closed and the context error is returned
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.closing.Store(true)
+	server.connsLock.Lock()
+	if server.listener != nil {
+		server.listener.Close()
+	}
+	// reads waiting for the next command return at once, commands that are already read are still answered
+	for c := range server.conns {
+		c.SetReadDeadline(time.Now())
+	}
+	server.connsLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		server.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.connsLock.Lock()
+		for c := range server.conns {
+			c.Close()
+		}
+		server.connsLock.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (server *Server) serveConn(c *conn) {
+	defer func() {
+		c.Close()
+		server.connsLock.Lock()
+		delete(server.conns, c)
+		server.connsLock.Unlock()
+		server.active.Done()
+	}()
+	reader := bufio.NewReaderSize(c, MAX_LINE)
+	w := writer{bufio.NewWriter(c)}
+	defer w.Flush()
+	for {
+		if server.closing.Load() && reader.Buffered() == 0 {
+			return
+		}
+		args, err := readCommand(reader)
+		if err != nil {
+			var protocolErr errProtocol
+			if errors.As(err, &protocolErr) {
+				w.error("ERR " + protocolErr.Error())
+			} else if err != io.EOF && !errors.Is(err, os.ErrDeadlineExceeded) {
+				w.error("ERR " + err.Error())
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := server.execute(c, args, w)
+		// replies to pipelined commands are sent together, when there is nothing more to read
+		if reader.Buffered() == 0 || quit {
+			if w.Flush() != nil || quit {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+// The engine reads its config and log state from paths relative to src and saves data to ../data,
+// so every test runs in its own copy of them
+func useTempEngine(t *testing.T) *engine.Engine {
+	root := t.TempDir()
+	for _, dir := range []string{"src/config", "src/structs/WAL", "data/sstable", "data/compressionInfo", "data/log"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"config/config.json", "structs/WAL/bytesFromLastSegment.log"} {
+		content, err := os.ReadFile(filepath.Join("../..", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "src", file), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(root, "src")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+
+	e, err := engine.NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+type testServer struct {
+	*Server
+	address string
+	served  chan error // what Serve returned
+}
+
+// starts a server on a loopback port, it is shut down when the test ends
+func startServer(t *testing.T) *testServer {
+	server := NewServer(useTempEngine(t))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+	return &testServer{Server: server, address: listener.Addr().String(), served: served}
+}
+
+func dial(t *testing.T, server *testServer) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", server.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+// reads replies until they are as long as the expected ones
+func expectReplies(t *testing.T, reader *bufio.Reader, want string) {
+	t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(reader, got); err != nil {
+		t.Fatalf("read %q, then %v", got, err)
+	}
+	if string(got) != want {
+		t.Fatalf("replies are %q, want %q", got, want)
+	}
+}
+
+func TestPipelinedCommands(t *testing.T) {
+	server := startServer(t)
+	conn, reader := dial(t, server)
+
+	// all commands are sent before any reply is read, in both formats
+	commands := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n" +
+		"GET key\r\n" +
+		"*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n" +
+		"SET key other\r\n" +
+		"*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"
+	if _, err := conn.Write([]byte(commands)); err != nil {
+		t.Fatal(err)
+	}
+	expectReplies(t, reader, "+OK\r\n$5\r\nvalue\r\n$-1\r\n+OK\r\n$5\r\nother\r\n")
+}
+
+func TestProtocolErrorClosesConnection(t *testing.T) {
+	server := startServer(t)
+	conn, reader := dial(t, server)
+	if _, err := conn.Write([]byte("PING\r\n*x\r\nPING\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	expectReplies(t, reader, "+PONG\r\n")
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "-ERR Protocol error: invalid multibulk length") {
+		t.Fatalf("expected a protocol error, got %q, %v", line, err)
+	}
+	if rest, err := reader.ReadString('\n'); err != io.EOF {
+		t.Fatalf("connection should be closed after a protocol error, read %q, %v", rest, err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	server := startServer(t)
+	conn, reader := dial(t, server)
+	if _, err := conn.Write([]byte("SET a 1\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	expectReplies(t, reader, "+OK\r\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	select {
+	case err := <-server.served:
+		if err != ErrServerClosed {
+			t.Fatalf("Serve returned %v, want ErrServerClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve didn't return after shutdown")
+	}
+
+	// the idle connection is closed, and no new ones are accepted
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Fatalf("connection should be closed, read returned %v", err)
+	}
+	if other, err := net.DialTimeout("tcp", server.address, time.Second); err == nil {
+		other.Close()
+		t.Fatal("server accepts connections after shutdown")
+	}
+}
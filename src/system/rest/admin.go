@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+)
+
+// POST /admin/compact compacts all sstables, or with ?start=&end= the ones that overlap the range
+func handleCompact(server *Server, w http.ResponseWriter, r *http.Request, rest string) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	query := r.URL.Query()
+	var err error
+	switch {
+	case !query.Has("start") && !query.Has("end"):
+		err = server.engine.CompactAll()
+	case query.Get("start") == "" || query.Get("end") == "":
+		writeError(w, http.StatusBadRequest, errors.New("range needs both start and end"))
+		return
+	default:
+		err = server.engine.CompactRange(query.Get("start"), query.Get("end"))
+	}
+	if err != nil {
+		server.fail(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /admin/stats returns sstables per level, compaction state and access statistics
+func handleStats(server *Server, w http.ResponseWriter, r *http.Request, rest string) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, server.engine.Status())
+}
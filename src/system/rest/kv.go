@@ -0,0 +1,251 @@
+package rest
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+// record as it is sent in JSON, values that aren't valid UTF-8 are encoded in base64
+type jsonRecord struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"` // "utf8" or "base64"
+}
+
+func newJSONRecord(key string, value []byte) jsonRecord {
+	if utf8.Valid(value) {
+		return jsonRecord{Key: key, Value: string(value), Encoding: "utf8"}
+	}
+	return jsonRecord{Key: key, Value: base64.StdEncoding.EncodeToString(value), Encoding: "base64"}
+}
+
+// body of PUT /kv/{key} sent as JSON, encoding is "utf8" if it isn't passed
+type putBody struct {
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"`
+}
+
+// operation of a batch, op is "put" or "delete"
+type batchOperation struct {
+	Op       string `json:"op"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"`
+}
+
+type batchBody struct {
+	Operations []batchOperation `json:"operations"`
+}
+
+// page of a scan, more is true if there are records after it
+type scanPage struct {
+	Records []jsonRecord `json:"records"`
+	Page    int          `json:"page"`
+	Size    int          `json:"size"`
+	More    bool         `json:"more"`
+}
+
+// GET returns the record, or the raw value if application/octet-stream is accepted
+// PUT saves the value from a JSON body, or the raw body if it isn't sent as JSON, with If-None-Match: * only if the key doesn't exist
+// DELETE deletes the key, it succeeds even if the key doesn't exist
+func handleKey(server *Server, w http.ResponseWriter, r *http.Request, rest string) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPut, http.MethodDelete) {
+		return
+	}
+	key, err := url.PathUnescape(rest)
+	if err != nil || key == "" {
+		writeError(w, http.StatusBadRequest, errors.New("invalid key"))
+		return
+	}
+	if engine.IsReservedKey(key) {
+		writeError(w, http.StatusBadRequest, errors.New("key must not begin with system prefix"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		value, err := server.engine.Get(key)
+		if err != nil {
+			server.fail(w, err, http.StatusInternalServerError)
+			return
+		}
+		if value == nil {
+			writeError(w, http.StatusNotFound, errors.New("key not found"))
+			return
+		}
+		if r.Header.Get("Accept") == "application/octet-stream" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(value)
+			return
+		}
+		writeJSON(w, http.StatusOK, newJSONRecord(key, value))
+	case http.MethodPut:
+		value, err := readValue(r)
+		if err != nil {
+			server.fail(w, err, http.StatusBadRequest)
+			return
+		}
+		// with If-None-Match: * the key is saved only if it doesn't exist
+		if r.Header.Get("If-None-Match") == "*" {
+			old, err := server.engine.Get(key)
+			if err != nil {
+				server.fail(w, err, http.StatusInternalServerError)
+				return
+			}
+			if old != nil {
+				writeError(w, http.StatusPreconditionFailed, errors.New("key already exists"))
+				return
+			}
+		}
+		if err := server.engine.Put(key, value); err != nil {
+			server.fail(w, err, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := server.engine.Delete(key); err != nil {
+			server.fail(w, err, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// reads the value from the body of a PUT request
+func readValue(r *http.Request) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return io.ReadAll(r.Body)
+	}
+	var body putBody
+	if err := readJSON(r, &body); err != nil {
+		return nil, err
+	}
+	return decodeValue(body.Value, body.Encoding)
+}
+
+func decodeValue(value string, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "utf8":
+		return []byte(value), nil
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, badRequest("value is not valid base64")
+		}
+		return decoded, nil
+	}
+	return nil, badRequest("encoding must be utf8 or base64")
+}
+
+// GET /kv?prefix=&page=&size= returns a page of records with the prefix, all records if it isn't passed
+// GET /kv?start=&end=&page=&size= returns a page of records from the range [start, end]
+// With after=key pages are counted from the record after that key, so the next page can be read without skipping the previous ones
+// POST /kv runs a batch of operations
+func handleScan(server *Server, w http.ResponseWriter, r *http.Request, rest string) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodPost {
+		handleBatch(server, w, r)
+		return
+	}
+	query := r.URL.Query()
+	prefix, start, end := query.Get("prefix"), query.Get("start"), query.Get("end")
+	if query.Has("prefix") && (query.Has("start") || query.Has("end")) {
+		writeError(w, http.StatusBadRequest, errors.New("pass either prefix, or start and end"))
+		return
+	}
+	if query.Has("start") || query.Has("end") {
+		if start == "" || end == "" {
+			writeError(w, http.StatusBadRequest, errors.New("range needs both start and end"))
+			return
+		}
+		prefix = start
+	}
+	page, err := queryInt(r, "page", 1)
+	if err == nil && page < 1 {
+		err = badRequest("page must be at least 1")
+	}
+	if err != nil {
+		server.fail(w, err, http.StatusBadRequest)
+		return
+	}
+	size, err := queryInt(r, "size", DEFAULT_PAGE_SIZE)
+	if err == nil && (size < 1 || size > MAX_PAGE_SIZE) {
+		err = badRequest("size must be from 1 to 1000")
+	}
+	if err != nil {
+		server.fail(w, err, http.StatusBadRequest)
+		return
+	}
+
+	records, more, err := server.engine.ScanPage(prefix, end, query.Get("after"), page, size)
+	if err != nil {
+		server.fail(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, scanPage{Records: jsonRecords(records), Page: page, Size: size, More: more})
+}
+
+func jsonRecords(records []*model.Record) []jsonRecord {
+	list := make([]jsonRecord, 0, len(records))
+	for _, record := range records {
+		list = append(list, newJSONRecord(record.Key, record.Value))
+	}
+	return list
+}
+
+// runs the operations in order, all of them are checked before the first one is run
+// If one fails, the ones before it stay applied and the error tells which one failed
+func handleBatch(server *Server, w http.ResponseWriter, r *http.Request) {
+	var body batchBody
+	if err := readJSON(r, &body); err != nil {
+		server.fail(w, err, http.StatusBadRequest)
+		return
+	}
+	if len(body.Operations) > MAX_BATCH_SIZE {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("batch has more than %d operations", MAX_BATCH_SIZE))
+		return
+	}
+	values := make([][]byte, len(body.Operations))
+	for i, operation := range body.Operations {
+		var err error
+		switch {
+		case operation.Op != "put" && operation.Op != "delete":
+			err = errors.New("op must be put or delete")
+		case operation.Key == "":
+			err = errors.New("invalid key")
+		case engine.IsReservedKey(operation.Key):
+			err = errors.New("key must not begin with system prefix")
+		case operation.Op == "put":
+			values[i], err = decodeValue(operation.Value, operation.Encoding)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("operation %d: %w", i, err))
+			return
+		}
+	}
+	for i, operation := range body.Operations {
+		var err error
+		if operation.Op == "put" {
+			err = server.engine.Put(operation.Key, values[i])
+		} else {
+			err = server.engine.Delete(operation.Key)
+		}
+		if err != nil {
+			server.fail(w, fmt.Errorf("operation %d: %w", i, err), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,211 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/WAL"
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+const (
+	MAX_BODY_SIZE     = 64 * 1024 * 1024 // largest request body that is read
+	READ_TIMEOUT      = time.Minute      // time to read a whole request
+	IDLE_TIMEOUT      = 2 * time.Minute  // time a kept alive connection waits for the next request
+	MAX_HEADER_BYTES  = 64 * 1024
+	DEFAULT_PAGE_SIZE = 100
+	MAX_PAGE_SIZE     = 1000
+	MAX_BATCH_SIZE    = 10000 // most operations of one batch
+)
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = http.ErrServerClosed
+
+// Server answers HTTP requests with JSON, routes are:
+//
+//	GET|PUT|DELETE /kv/{key}
+//	GET /kv?prefix=&page=&size= or /kv?start=&end=&page=&size=, both with an optional after=
+//	POST /kv with a batch of operations
+//	/sketches/{type}/{name}[/{operation}], see sketches.go
+//	POST /admin/compact[?start=&end=], GET /admin/stats, GET /healthz
+//
+// Requests of all connections are run one at a time through engine.Locked
+type Server struct {
+	engine *engine.Engine
+	http   *http.Server
+}
+
+func NewServer(e *engine.Engine) *Server {
+	server := &Server{engine: e}
+	server.http = &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: READ_TIMEOUT,
+		ReadTimeout:       READ_TIMEOUT,
+		IdleTimeout:       IDLE_TIMEOUT,
+		MaxHeaderBytes:    MAX_HEADER_BYTES,
+	}
+	return server
+}
+
+// ListenAndServe listens on the TCP address and serves requests until Shutdown
+func (server *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return server.Serve(listener)
+}
+
+// Serve answers requests from connections accepted by the listener until Shutdown
+func (server *Server) Serve(listener net.Listener) error {
+	return server.http.Serve(listener)
+}
+
+// Shutdown stops accepting connections and waits until requests that are being answered are finished
+// If the context ends first, the context error is returned
+func (server *Server) Shutdown(ctx context.Context) error {
+	return server.http.Shutdown(ctx)
+}
+
+// route handles one path prefix, rest is the path after it, still escaped
+type route struct {
+	prefix string
+	handle func(server *Server, w http.ResponseWriter, r *http.Request, rest string)
+}
+
+var routes = []route{
+	{"/kv/", handleKey},
+	{"/kv", handleScan},
+	{"/sketches/", handleSketch},
+	{"/admin/compact", handleCompact},
+	{"/admin/stats", handleStats},
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.EscapedPath()
+	// answered without waiting for other requests, so it shows whether the server is up even while it is busy
+	if path == "/healthz" {
+		if !allowMethods(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+	for _, route := range routes {
+		rest, ok := strings.CutPrefix(path, route.prefix)
+		if !ok || (rest != "" && !strings.HasSuffix(route.prefix, "/")) {
+			continue
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE)
+		server.engine.Locked(func() {
+			route.handle(server, w, r, rest)
+		})
+		return
+	}
+	writeError(w, http.StatusNotFound, errors.New("no such path"))
+}
+
+// errBadRequest is returned for requests with invalid parameters or body
+type errBadRequest struct {
+	message string
+}
+
+func (err errBadRequest) Error() string {
+	return err.message
+}
+
+func badRequest(message string) error {
+	return errBadRequest{message}
+}
+
+// returns the status code for the error, fallback is used for errors that aren't recognized
+func statusCode(err error, fallback int) int {
+	var maxBytesErr *http.MaxBytesError
+	var badRequestErr errBadRequest
+	switch {
+	case errors.Is(err, engine.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, engine.ErrSketchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, WAL.ErrRecordTooLarge), errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &badRequestErr):
+		return http.StatusBadRequest
+	}
+	return fallback
+}
+
+// writes the error with the status code it has, requests rejected by the token bucket
+// are told to retry after the bucket is filled again
+func (server *Server) fail(w http.ResponseWriter, err error, fallback int) {
+	status := statusCode(err, fallback)
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", strconv.FormatUint(uint64(server.engine.Config.TokenResetInterval), 10))
+	}
+	writeError(w, status, err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// returns false and answers with 405 if the request method isn't one of the passed methods
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method "+r.Method+" is not allowed"))
+	return false
+}
+
+// decodes the JSON body into value, an empty body leaves it unchanged
+func readJSON(r *http.Request, value any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(value)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	if err != nil && err != io.EOF {
+		return badRequest("invalid JSON body: " + err.Error())
+	}
+	return nil
+}
+
+// returns the integer query parameter, or fallback if it isn't passed
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, badRequest(name + " must be an integer")
+	}
+	return number, nil
+}
+
+// returns the query parameter, or an error if it isn't passed
+func queryRequired(r *http.Request, name string) (string, error) {
+	if !r.URL.Query().Has(name) {
+		return "", badRequest(name + " parameter is missing")
+	}
+	return r.URL.Query().Get(name), nil
+}
@@ -0,0 +1,288 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	engine "github.com/natasakasikovic/Key-Value-engine/src/system"
+)
+
+// Probabilistic structures are used through /sketches/{type}/{name}, where type is bf, cms, hll, cf, topk or simhash
+//
+//	PUT    /sketches/{type}/{name}              creates it, parameters are sent in the JSON body
+//	DELETE /sketches/{type}/{name}              deletes it
+//	POST   /sketches/{type}/{name}/add          adds {"elements": [...]} (incr for cms)
+//	GET    /sketches/{type}/{name}/exists?element=
+//	POST   /sketches/{type}/{name}/merge        saves the union of {"names": [...]} under name
+//
+// and the other operations listed in sketchTypes
+
+// operation on an existing structure, a nil result is answered with 204
+type operation struct {
+	method string
+	run    func(e *engine.Engine, r *http.Request, name string) (any, error)
+}
+
+type sketchType struct {
+	prefix     string // key prefix of the type, used to delete it
+	create     func(e *engine.Engine, r *http.Request, name string) (any, error)
+	operations map[string]operation
+}
+
+// body of add and incr requests
+type elementsBody struct {
+	Elements []string `json:"elements"`
+}
+
+// body of merge requests
+type namesBody struct {
+	Names []string `json:"names"`
+}
+
+type topKItem struct {
+	Key   string `json:"key"`
+	Count uint32 `json:"count"`
+}
+
+var sketchTypes = map[string]sketchType{
+	"bf": {engine.BF_KEY, createBloom, map[string]operation{
+		"add":    addElements(func(e *engine.Engine) func(string, string) error { return e.BloomAdd }),
+		"exists": elementExists(func(e *engine.Engine) func(string, string) (bool, error) { return e.BloomExists }),
+		"merge":  merge(func(e *engine.Engine) func(string, []string) error { return e.MergeBloomFilters }),
+	}},
+	"cms": {engine.CMS_KEY, createCMS, map[string]operation{
+		"incr": addElements(func(e *engine.Engine) func(string, string) error { return e.CMSIncr }),
+		"query": {http.MethodGet, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+			element, err := queryRequired(r, "element")
+			if err != nil {
+				return nil, err
+			}
+			count, err := e.CMSQuery(name, element)
+			return map[string]uint32{"count": count}, err
+		}},
+		"merge": merge(func(e *engine.Engine) func(string, []string) error { return e.MergeCMS }),
+	}},
+	"hll": {engine.HLL_KEY, createHLL, map[string]operation{
+		"add": addElements(func(e *engine.Engine) func(string, string) error { return e.HLLAdd }),
+		"count": {http.MethodGet, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+			count, err := e.HLLCount(name)
+			return map[string]uint64{"count": count}, err
+		}},
+		"merge": merge(func(e *engine.Engine) func(string, []string) error { return e.MergeHLL }),
+	}},
+	"cf": {engine.CF_KEY, createCuckoo, map[string]operation{
+		"add":    addElements(func(e *engine.Engine) func(string, string) error { return e.CuckooAdd }),
+		"exists": elementExists(func(e *engine.Engine) func(string, string) (bool, error) { return e.CuckooExists }),
+		"remove": {http.MethodPost, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+			var body struct {
+				Element string `json:"element"`
+			}
+			if err := readJSON(r, &body); err != nil {
+				return nil, err
+			}
+			removed, err := e.CuckooRemove(name, body.Element)
+			return map[string]bool{"removed": removed}, err
+		}},
+		"count": {http.MethodGet, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+			count, err := e.CuckooCount(name)
+			return map[string]uint64{"count": count}, err
+		}},
+	}},
+	"topk": {engine.TK_KEY, createTopK, map[string]operation{
+		"add": addElements(func(e *engine.Engine) func(string, string) error { return e.TopKAdd }),
+		"list": {http.MethodGet, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+			items, err := e.TopKList(name)
+			list := make([]topKItem, 0, len(items))
+			for _, item := range items {
+				list = append(list, topKItem{item.Key, item.Count})
+			}
+			return map[string][]topKItem{"items": list}, err
+		}},
+		"query": {http.MethodGet, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+			element, err := queryRequired(r, "element")
+			if err != nil {
+				return nil, err
+			}
+			count, err := e.TopKQuery(name, element)
+			return map[string]uint32{"count": count}, err
+		}},
+	}},
+	"simhash": {engine.SH_KEY, createSimHash, map[string]operation{
+		"distance": {http.MethodGet, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+			other, err := queryRequired(r, "other")
+			if err != nil {
+				return nil, err
+			}
+			distance, err := e.SimHashDistance(name, other)
+			return map[string]uint8{"distance": distance}, err
+		}},
+	}},
+}
+
+func handleSketch(server *Server, w http.ResponseWriter, r *http.Request, rest string) {
+	parts := strings.Split(rest, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, errors.New("no such path"))
+		return
+	}
+	sketch, ok := sketchTypes[parts[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown structure type %q", parts[0]))
+		return
+	}
+	name, err := url.PathUnescape(parts[1])
+	if err != nil || name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("invalid name"))
+		return
+	}
+
+	status := http.StatusOK
+	var result any
+	if len(parts) == 2 {
+		if !allowMethods(w, r, http.MethodPut, http.MethodDelete) {
+			return
+		}
+		if r.Method == http.MethodPut {
+			status = http.StatusCreated
+			result, err = sketch.create(server.engine, r, name)
+		} else {
+			err = server.engine.DeleteSketch(sketch.prefix, name)
+		}
+	} else {
+		op, ok := sketch.operations[parts[2]]
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown operation %q", parts[2]))
+			return
+		}
+		if !allowMethods(w, r, op.method) {
+			return
+		}
+		result, err = op.run(server.engine, r, name)
+	}
+	// errors of structures are mostly caused by invalid parameters or elements
+	if err != nil {
+		server.fail(w, err, http.StatusBadRequest)
+		return
+	}
+	if result == nil {
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, result)
+}
+
+func addElements(add func(e *engine.Engine) func(name string, element string) error) operation {
+	return operation{http.MethodPost, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+		var body elementsBody
+		if err := readJSON(r, &body); err != nil {
+			return nil, err
+		}
+		for _, element := range body.Elements {
+			if err := add(e)(name, element); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}}
+}
+
+func elementExists(exists func(e *engine.Engine) func(name string, element string) (bool, error)) operation {
+	return operation{http.MethodGet, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+		element, err := queryRequired(r, "element")
+		if err != nil {
+			return nil, err
+		}
+		found, err := exists(e)(name, element)
+		return map[string]bool{"exists": found}, err
+	}}
+}
+
+func merge(merge func(e *engine.Engine) func(dst string, names []string) error) operation {
+	return operation{http.MethodPost, func(e *engine.Engine, r *http.Request, name string) (any, error) {
+		var body namesBody
+		if err := readJSON(r, &body); err != nil {
+			return nil, err
+		}
+		if len(body.Names) == 0 {
+			return nil, badRequest("names must not be empty")
+		}
+		return nil, merge(e)(name, body.Names)
+	}}
+}
+
+// Create functions read parameters from the body, the ones that aren't sent have the same defaults as in the cli
+
+func createBloom(e *engine.Engine, r *http.Request, name string) (any, error) {
+	body := struct {
+		Expected int     `json:"expected"`
+		FP       float64 `json:"fp"`
+	}{1000, 0.01}
+	if err := readJSON(r, &body); err != nil {
+		return nil, err
+	}
+	return nil, e.BloomCreate(name, body.Expected, body.FP)
+}
+
+func createCMS(e *engine.Engine, r *http.Request, name string) (any, error) {
+	body := struct {
+		Epsilon float64 `json:"epsilon"`
+		Delta   float64 `json:"delta"`
+	}{0.01, 0.01}
+	if err := readJSON(r, &body); err != nil {
+		return nil, err
+	}
+	return nil, e.CMSCreate(name, body.Epsilon, body.Delta)
+}
+
+func createHLL(e *engine.Engine, r *http.Request, name string) (any, error) {
+	body := struct {
+		Precision uint8 `json:"precision"`
+	}{10}
+	if err := readJSON(r, &body); err != nil {
+		return nil, err
+	}
+	return nil, e.HLLCreate(name, body.Precision)
+}
+
+func createCuckoo(e *engine.Engine, r *http.Request, name string) (any, error) {
+	body := struct {
+		Expected int `json:"expected"`
+	}{1000}
+	if err := readJSON(r, &body); err != nil {
+		return nil, err
+	}
+	return nil, e.CuckooCreate(name, body.Expected)
+}
+
+func createTopK(e *engine.Engine, r *http.Request, name string) (any, error) {
+	body := struct {
+		K       uint32  `json:"k"`
+		Epsilon float64 `json:"epsilon"`
+		Delta   float64 `json:"delta"`
+	}{10, 0.01, 0.01}
+	if err := readJSON(r, &body); err != nil {
+		return nil, err
+	}
+	return nil, e.TopKCreate(name, body.K, body.Epsilon, body.Delta)
+}
+
+// the fingerprint of the text is saved under the name and returned as 16 hex digits
+func createSimHash(e *engine.Engine, r *http.Request, name string) (any, error) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := readJSON(r, &body); err != nil {
+		return nil, err
+	}
+	fingerprint, err := e.SimHashFingerprint(name, body.Text)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"fingerprint": fmt.Sprintf("%016x", fingerprint)}, nil
+}
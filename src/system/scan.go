@@ -0,0 +1,173 @@
+package system
+
+import (
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/iterators"
+)
+
+// calls fn for records of the iterator until fn returns false, skipping keys of probabilistic structures
+func (engine *Engine) iterate(iterator iterators.Iterator, fn func(record *model.Record) bool) error {
+	defer iterator.Stop()
+	for {
+		record, err := iterator.Next()
+		if err != nil || record == nil {
+			return err
+		}
+		if IsReservedKey(record.Key) {
+			continue
+		}
+		if !fn(record) {
+			return nil
+		}
+	}
+}
+
+// ScanPrefix calls fn for every record whose key begins with the prefix, in key order, until fn returns false
+// Records are read one by one, so scanning doesn't load all of them; deleted records are skipped
+// A scan takes one request from the token bucket, like a page of ScanPage
+func (engine *Engine) ScanPrefix(prefix string, fn func(record *model.Record) bool) error {
+	if !engine.TokenBucket.IsRequestAvailable() {
+		return ErrRateLimited
+	}
+	return engine.scanPrefix(prefix, fn)
+}
+
+func (engine *Engine) scanPrefix(prefix string, fn func(record *model.Record) bool) error {
+	iterator, err := iterators.NewPrefixIterator(prefix, engine.Config.CompressionOn, engine.CompressionMap)
+	if err != nil {
+		return err
+	}
+	return engine.iterate(iterator, fn)
+}
+
+// ScanRange does the same as ScanPrefix, for records whose keys are between start and end, both included
+func (engine *Engine) ScanRange(start string, end string, fn func(record *model.Record) bool) error {
+	if !engine.TokenBucket.IsRequestAvailable() {
+		return ErrRateLimited
+	}
+	return engine.scanRange(start, end, fn)
+}
+
+func (engine *Engine) scanRange(start string, end string, fn func(record *model.Record) bool) error {
+	iterator, err := iterators.NewRangeIterator(start, end, engine.Config.CompressionOn, engine.CompressionMap)
+	if err != nil {
+		return err
+	}
+	return engine.iterate(iterator, fn)
+}
+
+// ScanPrefixAfter does the same as ScanPrefix, starting after the passed key, so a scan can be continued where it stopped
+func (engine *Engine) ScanPrefixAfter(prefix string, after string, fn func(record *model.Record) bool) error {
+	if !engine.TokenBucket.IsRequestAvailable() {
+		return ErrRateLimited
+	}
+	return engine.scanPrefixAfter(prefix, after, fn)
+}
+
+func (engine *Engine) scanPrefixAfter(prefix string, after string, fn func(record *model.Record) bool) error {
+	return engine.scanPrefix(prefix, func(record *model.Record) bool {
+		if record.Key <= after {
+			return true
+		}
+		return fn(record)
+	})
+}
+
+// ScanPage returns records from the page with the passed number (starting from 1) of a prefix scan,
+// or of a range scan if end isn't empty, in which case prefix is the start of the range
+// Pages are counted as in scan.PrefixScan and scan.RangeScan, but keys of probabilistic structures aren't returned
+// If after isn't empty, only records after that key are scanned, so a client can continue from the last key it read
+// more is true if there are records after the page
+// Every page takes one request from the token bucket
+func (engine *Engine) ScanPage(prefix string, end string, after string, page int, size int) ([]*model.Record, bool, error) {
+	records := make([]*model.Record, 0)
+	if page < 1 || size < 1 {
+		return records, false, nil
+	}
+	if !engine.TokenBucket.IsRequestAvailable() {
+		return nil, false, ErrRateLimited
+	}
+	skip := (page - 1) * size
+	more := false
+	fn := func(record *model.Record) bool {
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if len(records) == size {
+			more = true
+			return false
+		}
+		records = append(records, record)
+		return true
+	}
+	var err error
+	switch {
+	case end != "":
+		// the smallest key after the passed one is the key with a zero byte appended
+		if after != "" && after+"\x00" > prefix {
+			prefix = after + "\x00"
+		}
+		if prefix > end {
+			return records, false, nil
+		}
+		err = engine.scanRange(prefix, end, fn)
+	case after != "":
+		err = engine.scanPrefixAfter(prefix, after, fn)
+	default:
+		err = engine.scanPrefix(prefix, fn)
+	}
+	return records, more, err
+}
+
+// Scanner reads the records of a scan in batches, keeping its iterator between them,
+// so a long scan continues where it stopped instead of skipping the records it already read
+// Records written after the scanner is made aren't returned
+// Like other engine methods, its methods must not run at the same time as them, servers call them through Locked
+type Scanner struct {
+	iterator iterators.Iterator
+	done     bool
+}
+
+// NewPrefixScanner returns a scanner of records whose key begins with the prefix, it must be stopped with Stop
+func (engine *Engine) NewPrefixScanner(prefix string) (*Scanner, error) {
+	iterator, err := iterators.NewPrefixIterator(prefix, engine.Config.CompressionOn, engine.CompressionMap)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{iterator: iterator}, nil
+}
+
+// NewRangeScanner returns a scanner of records whose keys are between start and end, both included
+func (engine *Engine) NewRangeScanner(start string, end string) (*Scanner, error) {
+	iterator, err := iterators.NewRangeIterator(start, end, engine.Config.CompressionOn, engine.CompressionMap)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{iterator: iterator}, nil
+}
+
+// Next returns at most size next records in key order, skipping keys of probabilistic structures
+// Fewer records are returned only when the scan is finished
+func (scanner *Scanner) Next(size int) ([]*model.Record, error) {
+	records := make([]*model.Record, 0, size)
+	for !scanner.done && len(records) < size {
+		record, err := scanner.iterator.Next()
+		if err != nil {
+			return records, err
+		}
+		if record == nil {
+			scanner.done = true
+			break
+		}
+		if !IsReservedKey(record.Key) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Stop closes the files read by the scanner
+func (scanner *Scanner) Stop() {
+	scanner.iterator.Stop()
+}
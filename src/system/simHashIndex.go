@@ -0,0 +1,288 @@
+package system
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	simHash "github.com/natasakasikovic/Key-Value-engine/src/structs/simHash"
+)
+
+// A SimHash index is saved as records with the key prefix SHI_KEY_name:
+// the index itself under SHI_KEY_name, the fingerprint of each document under SHI_KEY_name/doc/document,
+// and an empty record for every band of every fingerprint under SHI_KEY_name/band/band number/value/document,
+// so documents whose fingerprints have the same value of a band are found with a prefix scan,
+// and adding a document writes small records however many documents share a bucket
+
+func indexDocumentKey(name string, document string) string {
+	return sketchKey(SHI_KEY, name) + "/doc/" + document
+}
+
+// keys of all documents in the bucket begin with it
+func indexBucketPrefix(name string, band int, value uint64) string {
+	return fmt.Sprintf("%s/band/%d/%x/", sketchKey(SHI_KEY, name), band, value)
+}
+
+// names of indexes can't contain '/', so keys of one index never begin with keys of another one
+func checkIndexName(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if strings.Contains(name, "/") {
+		return errors.New("name of the index must not contain '/'")
+	}
+	return nil
+}
+
+// Returns the fingerprinter of the namespace the index uses, nil if it uses the default options
+// Namespaces with tf-idf weighting can't be used, since their weights change as texts are learned,
+// so fingerprints saved in the index would stop matching fingerprints of the same texts made later
+func (engine *Engine) getIndexFingerprinter(namespace string) (*simHash.Fingerprinter, error) {
+	if namespace == "" {
+		return nil, nil
+	}
+	fingerprinter, err := engine.getFingerprinter(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if fingerprinter.Options().Weighting == simHash.WEIGHT_TFIDF {
+		return nil, fmt.Errorf("%s %s uses tf-idf weighting, which indexes can't use", SHN_KEY, namespace)
+	}
+	return fingerprinter, nil
+}
+
+// Returns the fingerprint of the text made with options of the namespace of the index, texts aren't learned
+func (engine *Engine) indexFingerprint(index *simHash.Index, text string) (uint64, error) {
+	fingerprinter, err := engine.getIndexFingerprinter(index.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	if fingerprinter == nil {
+		return simHash.GetFingerprint(text), nil
+	}
+	return fingerprinter.Fingerprint(text), nil
+}
+
+func (engine *Engine) getSimHashIndex(name string) (*simHash.Index, error) {
+	if err := checkIndexName(name); err != nil {
+		return nil, err
+	}
+	value, err := engine.getSketch(SHI_KEY, name)
+	if err != nil {
+		return nil, err
+	}
+	index := simHash.DeserializeIndex(value)
+	if index == nil {
+		return nil, fmt.Errorf("%s %s is not valid", SHI_KEY, name)
+	}
+	return index, nil
+}
+
+// Returns the fingerprint of the document, and false if the document isn't in the index
+func (engine *Engine) getIndexedFingerprint(name string, document string) (uint64, bool, error) {
+	value, err := engine.get(indexDocumentKey(name, document))
+	if err != nil || value == nil {
+		return 0, false, err
+	}
+	if len(value) != 8 {
+		return 0, false, fmt.Errorf("fingerprint of %s in %s %s is not valid", document, SHI_KEY, name)
+	}
+	return binary.BigEndian.Uint64(value), true, nil
+}
+
+// Returns names of the documents in the bucket
+func (engine *Engine) getBucket(prefix string) ([]string, error) {
+	keys, err := engine.keysWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]string, len(keys))
+	for i, key := range keys {
+		documents[i] = strings.TrimPrefix(key, prefix)
+	}
+	return documents, nil
+}
+
+// Adds the document to or removes it from the buckets of all bands of the fingerprint
+func (engine *Engine) updateBuckets(name string, index *simHash.Index, fingerprint uint64, document string, add bool) error {
+	for band, value := range index.BandValues(fingerprint) {
+		key := indexBucketPrefix(name, band, value) + document
+		var err error
+		if add {
+			err = engine.put(key, []byte{})
+		} else {
+			err = engine.delete(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SimHashIndexCreate saves a new empty index that finds documents within maxDistance of a text
+// The bigger maxDistance is, the more bands fingerprints are split into, so adding documents is slower
+// Fingerprints are made with options of the namespace, or with the default options if it is empty,
+// the namespace must not use tf-idf weighting
+func (engine *Engine) SimHashIndexCreate(name string, maxDistance uint8, namespace string) error {
+	if err := checkIndexName(name); err != nil {
+		return err
+	}
+	index, err := simHash.NewIndex(maxDistance, namespace)
+	if err != nil {
+		return err
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	if _, err := engine.getIndexFingerprinter(namespace); err != nil {
+		return err
+	}
+	value, err := engine.get(sketchKey(SHI_KEY, name))
+	if err != nil {
+		return err
+	}
+	if value != nil {
+		return fmt.Errorf("%s %s already exists", SHI_KEY, name)
+	}
+	return engine.putSketch(SHI_KEY, name, index.Serialize())
+}
+
+// SimHashIndexAdd saves the fingerprint of the text as the document, replacing the previous one if the document was already added
+func (engine *Engine) SimHashIndexAdd(name string, document string, text string) error {
+	if err := checkName(document); err != nil {
+		return err
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	index, err := engine.getSimHashIndex(name)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := engine.indexFingerprint(index, text)
+	if err != nil {
+		return err
+	}
+	old, exists, err := engine.getIndexedFingerprint(name, document)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := engine.updateBuckets(name, index, old, document, false); err != nil {
+			return err
+		}
+	} else {
+		index.Count++
+	}
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, fingerprint)
+	if err := engine.put(indexDocumentKey(name, document), value); err != nil {
+		return err
+	}
+	if err := engine.updateBuckets(name, index, fingerprint, document, true); err != nil {
+		return err
+	}
+	return engine.putSketch(SHI_KEY, name, index.Serialize())
+}
+
+// SimHashIndexRemove removes the document from the index
+func (engine *Engine) SimHashIndexRemove(name string, document string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	index, err := engine.getSimHashIndex(name)
+	if err != nil {
+		return err
+	}
+	fingerprint, exists, err := engine.getIndexedFingerprint(name, document)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("document %s is not in %s %s", document, SHI_KEY, name)
+	}
+	if err := engine.updateBuckets(name, index, fingerprint, document, false); err != nil {
+		return err
+	}
+	if err := engine.delete(indexDocumentKey(name, document)); err != nil {
+		return err
+	}
+	index.Count--
+	return engine.putSketch(SHI_KEY, name, index.Serialize())
+}
+
+// SimHashIndexQuery returns all documents whose fingerprints are within distance of the fingerprint of the text, from the most similar one
+// distance must not be bigger than the max distance of the index
+func (engine *Engine) SimHashIndexQuery(name string, text string, distance uint8) ([]simHash.Match, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	index, err := engine.getSimHashIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	if distance > index.MaxDistance {
+		return nil, fmt.Errorf("distance must not be bigger than %d", index.MaxDistance)
+	}
+
+	fingerprint, err := engine.indexFingerprint(index, text)
+	if err != nil {
+		return nil, err
+	}
+	checked := make(map[string]bool)
+	matches := make([]simHash.Match, 0)
+	for band, value := range index.BandValues(fingerprint) {
+		bucket, err := engine.getBucket(indexBucketPrefix(name, band, value))
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range bucket {
+			if checked[document] {
+				continue
+			}
+			checked[document] = true
+			other, exists, err := engine.getIndexedFingerprint(name, document)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				continue
+			}
+			if d := simHash.HammingDistance(fingerprint, other); d <= distance {
+				matches = append(matches, simHash.Match{Document: document, Distance: d})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Document < matches[j].Document
+	})
+	return matches, nil
+}
+
+// SimHashIndexCount returns the number of documents in the index
+func (engine *Engine) SimHashIndexCount(name string) (uint64, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	index, err := engine.getSimHashIndex(name)
+	if err != nil {
+		return 0, err
+	}
+	return index.Count, nil
+}
+
+// SimHashIndexDelete deletes the index with all its documents and buckets
+func (engine *Engine) SimHashIndexDelete(name string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	if _, err := engine.getSimHashIndex(name); err != nil {
+		return err
+	}
+
+	if err := engine.deletePrefix(sketchKey(SHI_KEY, name) + "/"); err != nil {
+		return err
+	}
+	return engine.delete(sketchKey(SHI_KEY, name))
+}
@@ -0,0 +1,152 @@
+package system
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	simHash "github.com/natasakasikovic/Key-Value-engine/src/structs/simHash"
+)
+
+// A SimHash namespace keeps options of making fingerprints, saved with the key SHN_KEY_namespace,
+// and fingerprints made with them, saved under SHN_KEY_namespace/fp/name
+// Fingerprints are compared only within their namespace, since texts fingerprinted with different options aren't comparable
+// With tf-idf weighting, fingerprints made before many other texts were learned drift away from ones made later,
+// so texts should be learned up front or fingerprinted again once weights settle, and indexes can't use such namespaces
+
+func namespaceFingerprintKey(namespace string, name string) string {
+	return sketchKey(SHN_KEY, namespace) + "/fp/" + name
+}
+
+// names of namespaces can't contain '/', so keys of one namespace never begin with keys of another one
+func checkNamespace(namespace string) error {
+	if err := checkName(namespace); err != nil {
+		return err
+	}
+	if strings.Contains(namespace, "/") {
+		return errors.New("name of the namespace must not contain '/'")
+	}
+	return nil
+}
+
+func (engine *Engine) getFingerprinter(namespace string) (*simHash.Fingerprinter, error) {
+	if err := checkNamespace(namespace); err != nil {
+		return nil, err
+	}
+	value, err := engine.getSketch(SHN_KEY, namespace)
+	if err != nil {
+		return nil, err
+	}
+	fingerprinter := simHash.DeserializeFingerprinter(value)
+	if fingerprinter == nil {
+		return nil, fmt.Errorf("%s %s is not valid", SHN_KEY, namespace)
+	}
+	return fingerprinter, nil
+}
+
+// Returns the fingerprint of the text made with options of the namespace, or with the default options if the namespace is empty
+// With tf-idf weighting, the text is counted into document frequencies of the namespace first
+func (engine *Engine) fingerprintText(namespace string, text string) (uint64, error) {
+	if namespace == "" {
+		return simHash.GetFingerprint(text), nil
+	}
+	fingerprinter, err := engine.getFingerprinter(namespace)
+	if err != nil {
+		return 0, err
+	}
+	if fingerprinter.Options().Weighting == simHash.WEIGHT_TFIDF {
+		fingerprinter.Learn(text)
+		if err := engine.putSketch(SHN_KEY, namespace, fingerprinter.Serialize()); err != nil {
+			return 0, err
+		}
+	}
+	return fingerprinter.Fingerprint(text), nil
+}
+
+// SimHashNamespaceCreate saves a new namespace whose fingerprints are made with the passed options
+func (engine *Engine) SimHashNamespaceCreate(namespace string, options simHash.Options) error {
+	if err := checkNamespace(namespace); err != nil {
+		return err
+	}
+	fingerprinter, err := simHash.NewFingerprinter(options)
+	if err != nil {
+		return err
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	value, err := engine.get(sketchKey(SHN_KEY, namespace))
+	if err != nil {
+		return err
+	}
+	if value != nil {
+		return fmt.Errorf("%s %s already exists", SHN_KEY, namespace)
+	}
+	return engine.putSketch(SHN_KEY, namespace, fingerprinter.Serialize())
+}
+
+// SimHashNamespaceOptions returns the options of the namespace and the number of texts it learned
+func (engine *Engine) SimHashNamespaceOptions(namespace string) (simHash.Options, uint64, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	fingerprinter, err := engine.getFingerprinter(namespace)
+	if err != nil {
+		return simHash.Options{}, 0, err
+	}
+	return fingerprinter.Options(), fingerprinter.Documents(), nil
+}
+
+// SimHashNamespaceFingerprint computes the fingerprint of the text with options of the namespace, saves it under the passed name and returns it
+// With tf-idf weighting, the text is learned first, so it also changes weights of fingerprints made later
+func (engine *Engine) SimHashNamespaceFingerprint(namespace string, name string, text string) (uint64, error) {
+	if err := checkName(name); err != nil {
+		return 0, err
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	fingerprint, err := engine.fingerprintText(namespace, text)
+	if err != nil {
+		return 0, err
+	}
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, fingerprint)
+	return fingerprint, engine.put(namespaceFingerprintKey(namespace, name), value)
+}
+
+// SimHashNamespaceDistance returns the hamming distance of two fingerprints saved in the namespace
+func (engine *Engine) SimHashNamespaceDistance(namespace string, name1 string, name2 string) (uint8, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	if _, err := engine.getFingerprinter(namespace); err != nil {
+		return 0, err
+	}
+	var fingerprints [2]uint64
+	for i, name := range []string{name1, name2} {
+		value, err := engine.get(namespaceFingerprintKey(namespace, name))
+		if err != nil {
+			return 0, err
+		}
+		if value == nil {
+			return 0, fmt.Errorf("fingerprint %s does not exist in %s %s", name, SHN_KEY, namespace)
+		}
+		if len(value) != 8 {
+			return 0, fmt.Errorf("fingerprint %s in %s %s is not valid", name, SHN_KEY, namespace)
+		}
+		fingerprints[i] = binary.BigEndian.Uint64(value)
+	}
+	return simHash.HammingDistance(fingerprints[0], fingerprints[1]), nil
+}
+
+// SimHashNamespaceDelete deletes the namespace with all its fingerprints
+// Indexes that use it can't be changed or queried until a namespace with the same name is created again
+func (engine *Engine) SimHashNamespaceDelete(namespace string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	if _, err := engine.getFingerprinter(namespace); err != nil {
+		return err
+	}
+	if err := engine.deletePrefix(sketchKey(SHN_KEY, namespace) + "/"); err != nil {
+		return err
+	}
+	return engine.delete(sketchKey(SHN_KEY, namespace))
+}
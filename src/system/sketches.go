@@ -0,0 +1,575 @@
+package system
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	countMinSketch "github.com/natasakasikovic/Key-Value-engine/src/structs/CountMinSketch"
+	hyperLogLog "github.com/natasakasikovic/Key-Value-engine/src/structs/HyperLogLog"
+	bloomFilter "github.com/natasakasikovic/Key-Value-engine/src/structs/bloomFilter"
+	cuckooFilter "github.com/natasakasikovic/Key-Value-engine/src/structs/cuckooFilter"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/iterators"
+	simHash "github.com/natasakasikovic/Key-Value-engine/src/structs/simHash"
+	topK "github.com/natasakasikovic/Key-Value-engine/src/structs/topK"
+)
+
+// Probabilistic structures are saved as records under the key prefix_name, where prefix is reserved for their type
+// Their methods read the structure, change it and save it again while holding the sketch lock,
+// so concurrent changes of the same structure aren't lost
+
+// Returns the key under which the sketch with the passed name is saved
+func sketchKey(prefix string, name string) string {
+	return prefix + "_" + name
+}
+
+func checkName(name string) error {
+	if name == "" {
+		return errors.New("name must not be empty")
+	}
+	return nil
+}
+
+// Returns a copy of the serialized sketch with the passed name, nil if it doesn't exist
+// The saved value is shared with the memtable and the cache, so sketches are read from the copy,
+// and a change is saved only by putSketch, so it is lost and not half applied if saving fails
+func (engine *Engine) findSketch(prefix string, name string) ([]byte, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	value, err := engine.get(sketchKey(prefix, name))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return append([]byte{}, value...), nil
+}
+
+// Returns a copy of the serialized sketch with the passed name, or an error if it doesn't exist
+func (engine *Engine) getSketch(prefix string, name string) ([]byte, error) {
+	value, err := engine.findSketch(prefix, name)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%s %s %w", prefix, name, ErrSketchNotFound)
+	}
+	return value, nil
+}
+
+// Returns the serialized sketches with the passed names, there must be at least one and all must exist
+func (engine *Engine) getSketches(prefix string, names []string) ([][]byte, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no sketches to merge")
+	}
+	values := make([][]byte, len(names))
+	for i, name := range names {
+		value, err := engine.getSketch(prefix, name)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (engine *Engine) putSketch(prefix string, name string, value []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	return engine.put(sketchKey(prefix, name), value)
+}
+
+// DeleteSketch deletes the structure with the passed name, prefix is the key prefix of its type, e.g. BF_KEY
+func (engine *Engine) DeleteSketch(prefix string, name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	return engine.delete(sketchKey(prefix, name))
+}
+
+// Returns keys of all records that begin with the prefix and aren't deleted
+func (engine *Engine) keysWithPrefix(prefix string) ([]string, error) {
+	iterator, err := iterators.NewPrefixIterator(prefix, engine.Config.CompressionOn, engine.CompressionMap)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Stop()
+	keys := make([]string, 0)
+	for {
+		record, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return keys, nil
+		}
+		keys = append(keys, record.Key)
+	}
+}
+
+// Deletes all records whose keys begin with the prefix
+// keys are collected first, so records aren't written while the iterator reads them
+func (engine *Engine) deletePrefix(prefix string) error {
+	keys, err := engine.keysWithPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := engine.delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (engine *Engine) getBloomFilter(name string) (*bloomFilter.BloomFilter, error) {
+	value, err := engine.getSketch(BF_KEY, name)
+	if err != nil {
+		return nil, err
+	}
+	bf := bloomFilter.Deserialize(value)
+	if bf == nil {
+		return nil, fmt.Errorf("%s %s is not valid", BF_KEY, name)
+	}
+	return bf, nil
+}
+
+// BloomCreate saves a new bloom filter for the expected number of elements with the passed false positive rate
+func (engine *Engine) BloomCreate(name string, expected int, falsePositiveRate float64) error {
+	if expected < 1 {
+		return errors.New("expected number of elements must be positive")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return errors.New("false positive rate must be between 0 and 1")
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	return engine.putSketch(BF_KEY, name, bloomFilter.NewBf(expected, falsePositiveRate).Serialize())
+}
+
+// BloomAdd inserts the element into the bloom filter
+func (engine *Engine) BloomAdd(name string, element string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	bf, err := engine.getBloomFilter(name)
+	if err != nil {
+		return err
+	}
+	bf.Insert(element)
+	return engine.putSketch(BF_KEY, name, bf.Serialize())
+}
+
+// BloomExists returns false if the element surely isn't in the bloom filter
+func (engine *Engine) BloomExists(name string, element string) (bool, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	bf, err := engine.getBloomFilter(name)
+	if err != nil {
+		return false, err
+	}
+	return bf.Find(element), nil
+}
+
+// MergeBloomFilters saves the union of the bloom filters with the passed names under the name dst
+func (engine *Engine) MergeBloomFilters(dst string, names []string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	values, err := engine.getSketches(BF_KEY, names)
+	if err != nil {
+		return err
+	}
+	var result *bloomFilter.BloomFilter
+	for i, value := range values {
+		bf := bloomFilter.Deserialize(value)
+		if bf == nil {
+			return fmt.Errorf("%s %s is not valid", BF_KEY, names[i])
+		}
+		if result == nil {
+			result = bf
+		} else if err := result.Merge(bf); err != nil {
+			return err
+		}
+	}
+	return engine.putSketch(BF_KEY, dst, result.Serialize())
+}
+
+// BloomAddElements inserts the elements into the bloom filter, which is created with the passed parameters if it doesn't exist
+// For every element it returns true if the element wasn't found in the filter before, so it surely was added
+func (engine *Engine) BloomAddElements(name string, elements []string, expected int, falsePositiveRate float64) ([]bool, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	value, err := engine.findSketch(BF_KEY, name)
+	if err != nil {
+		return nil, err
+	}
+	var bf *bloomFilter.BloomFilter
+	if value == nil {
+		bf = bloomFilter.NewBf(expected, falsePositiveRate)
+	} else if bf = bloomFilter.Deserialize(value); bf == nil {
+		return nil, fmt.Errorf("%s %s is not valid", BF_KEY, name)
+	}
+	added := make([]bool, len(elements))
+	for i, element := range elements {
+		added[i] = !bf.Find(element)
+		bf.Insert(element)
+	}
+	return added, engine.putSketch(BF_KEY, name, bf.Serialize())
+}
+
+func (engine *Engine) getCMS(name string) (*countMinSketch.CMS, error) {
+	value, err := engine.getSketch(CMS_KEY, name)
+	if err != nil {
+		return nil, err
+	}
+	cms := countMinSketch.Deserialize(value)
+	if cms == nil {
+		return nil, fmt.Errorf("%s %s is not valid", CMS_KEY, name)
+	}
+	return cms, nil
+}
+
+// CMSCreate saves a new count min sketch with the passed error (epsilon) and probability of a bigger error (delta)
+func (engine *Engine) CMSCreate(name string, epsilon float64, delta float64) error {
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return errors.New("epsilon and delta must be between 0 and 1")
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	return engine.putSketch(CMS_KEY, name, countMinSketch.CreateCMS(epsilon, delta).Serialize())
+}
+
+// CMSIncr counts one more occurrence of the event
+func (engine *Engine) CMSIncr(name string, event string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	cms, err := engine.getCMS(name)
+	if err != nil {
+		return err
+	}
+	cms.Insert(event)
+	return engine.putSketch(CMS_KEY, name, cms.Serialize())
+}
+
+// CMSQuery returns the estimated number of occurrences of the event, it is never lower than the real one
+func (engine *Engine) CMSQuery(name string, event string) (uint32, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	cms, err := engine.getCMS(name)
+	if err != nil {
+		return 0, err
+	}
+	return cms.Search(event), nil
+}
+
+// MergeCMS saves the sum of the count min sketches with the passed names under the name dst
+func (engine *Engine) MergeCMS(dst string, names []string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	values, err := engine.getSketches(CMS_KEY, names)
+	if err != nil {
+		return err
+	}
+	var result *countMinSketch.CMS
+	for i, value := range values {
+		cms := countMinSketch.Deserialize(value)
+		if cms == nil {
+			return fmt.Errorf("%s %s is not valid", CMS_KEY, names[i])
+		}
+		if result == nil {
+			result = cms
+		} else if err := result.Merge(cms); err != nil {
+			return err
+		}
+	}
+	return engine.putSketch(CMS_KEY, dst, result.Serialize())
+}
+
+func (engine *Engine) getHLL(name string) (*hyperLogLog.HLL, error) {
+	value, err := engine.getSketch(HLL_KEY, name)
+	if err != nil {
+		return nil, err
+	}
+	hll := hyperLogLog.Deserialize(value)
+	if hll == nil {
+		return nil, fmt.Errorf("%s %s is not valid", HLL_KEY, name)
+	}
+	return hll, nil
+}
+
+// HLLCreate saves a new hyperloglog with the passed precision
+func (engine *Engine) HLLCreate(name string, precision uint8) error {
+	if precision < hyperLogLog.HLL_MIN_PRECISION || precision > hyperLogLog.HLL_MAX_PRECISION {
+		return fmt.Errorf("precision must be between %d and %d", hyperLogLog.HLL_MIN_PRECISION, hyperLogLog.HLL_MAX_PRECISION)
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	return engine.putSketch(HLL_KEY, name, hyperLogLog.CreateHLL(precision).Serialize())
+}
+
+// HLLAdd inserts the element into the hyperloglog
+func (engine *Engine) HLLAdd(name string, element string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	hll, err := engine.getHLL(name)
+	if err != nil {
+		return err
+	}
+	hll.Insert(element)
+	return engine.putSketch(HLL_KEY, name, hll.Serialize())
+}
+
+// HLLCount returns the estimated number of distinct elements in the hyperloglog
+func (engine *Engine) HLLCount(name string) (uint64, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	hll, err := engine.getHLL(name)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Round(hll.Estimate())), nil
+}
+
+// MergeHLL saves the union of the hyperloglogs with the passed names under the name dst
+func (engine *Engine) MergeHLL(dst string, names []string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	values, err := engine.getSketches(HLL_KEY, names)
+	if err != nil {
+		return err
+	}
+	var result *hyperLogLog.HLL
+	for i, value := range values {
+		hll := hyperLogLog.Deserialize(value)
+		if hll == nil {
+			return fmt.Errorf("%s %s is not valid", HLL_KEY, names[i])
+		}
+		if result == nil {
+			result = hll
+		} else if err := result.Merge(hll); err != nil {
+			return err
+		}
+	}
+	return engine.putSketch(HLL_KEY, dst, result.Serialize())
+}
+
+// HLLAddElements inserts the elements into the hyperloglog, which is created with the passed precision if it doesn't exist
+// Returns true if the hyperloglog was created or changed, so its estimate could be different
+func (engine *Engine) HLLAddElements(name string, elements []string, precision uint8) (bool, error) {
+	if err := checkName(name); err != nil {
+		return false, err
+	}
+	if precision < hyperLogLog.HLL_MIN_PRECISION || precision > hyperLogLog.HLL_MAX_PRECISION {
+		return false, fmt.Errorf("precision must be between %d and %d", hyperLogLog.HLL_MIN_PRECISION, hyperLogLog.HLL_MAX_PRECISION)
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	value, err := engine.findSketch(HLL_KEY, name)
+	if err != nil {
+		return false, err
+	}
+	var hll *hyperLogLog.HLL
+	if value == nil {
+		hll = hyperLogLog.CreateHLL(precision)
+	} else if hll = hyperLogLog.Deserialize(value); hll == nil {
+		return false, fmt.Errorf("%s %s is not valid", HLL_KEY, name)
+	}
+	for _, element := range elements {
+		hll.Insert(element)
+	}
+	serialized := hll.Serialize()
+	if value != nil && bytes.Equal(value, serialized) {
+		return false, nil
+	}
+	return true, engine.putSketch(HLL_KEY, name, serialized)
+}
+
+// HLLCountUnion returns the estimated number of distinct elements in the union of the hyperloglogs
+// Hyperloglogs that don't exist are skipped, and nothing is saved
+func (engine *Engine) HLLCountUnion(names []string) (uint64, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	var result *hyperLogLog.HLL
+	for _, name := range names {
+		value, err := engine.findSketch(HLL_KEY, name)
+		if err != nil {
+			return 0, err
+		}
+		if value == nil {
+			continue
+		}
+		hll := hyperLogLog.Deserialize(value)
+		if hll == nil {
+			return 0, fmt.Errorf("%s %s is not valid", HLL_KEY, name)
+		}
+		if result == nil {
+			result = hll
+		} else if err := result.Merge(hll); err != nil {
+			return 0, err
+		}
+	}
+	if result == nil {
+		return 0, nil
+	}
+	return uint64(math.Round(result.Estimate())), nil
+}
+
+// SimHashFingerprint computes the fingerprint of the text, saves it under the passed name and returns it
+func (engine *Engine) SimHashFingerprint(name string, text string) (uint64, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	fingerprint := simHash.GetFingerprint(text)
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, fingerprint)
+	return fingerprint, engine.putSketch(SH_KEY, name, value)
+}
+
+// SimHashDistance returns the hamming distance of two saved fingerprints, the smaller it is the more similar the texts are
+func (engine *Engine) SimHashDistance(name1 string, name2 string) (uint8, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	values, err := engine.getSketches(SH_KEY, []string{name1, name2})
+	if err != nil {
+		return 0, err
+	}
+	for i, value := range values {
+		if len(value) != 8 {
+			return 0, fmt.Errorf("%s %s is not valid", SH_KEY, []string{name1, name2}[i])
+		}
+	}
+	return simHash.HammingDistance(binary.BigEndian.Uint64(values[0]), binary.BigEndian.Uint64(values[1])), nil
+}
+
+func (engine *Engine) getCuckooFilter(name string) (*cuckooFilter.CuckooFilter, error) {
+	value, err := engine.getSketch(CF_KEY, name)
+	if err != nil {
+		return nil, err
+	}
+	cf := cuckooFilter.Deserialize(value)
+	if cf == nil {
+		return nil, fmt.Errorf("%s %s is not valid", CF_KEY, name)
+	}
+	return cf, nil
+}
+
+// CuckooCreate saves a new cuckoo filter for the expected number of elements
+func (engine *Engine) CuckooCreate(name string, expected int) error {
+	if expected < 1 {
+		return errors.New("expected number of elements must be positive")
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	return engine.putSketch(CF_KEY, name, cuckooFilter.NewCf(expected).Serialize())
+}
+
+// CuckooAdd inserts the element into the cuckoo filter, it returns an error if the filter is full
+func (engine *Engine) CuckooAdd(name string, element string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	cf, err := engine.getCuckooFilter(name)
+	if err != nil {
+		return err
+	}
+	if !cf.Insert(element) {
+		return fmt.Errorf("%s %s is full", CF_KEY, name)
+	}
+	return engine.putSketch(CF_KEY, name, cf.Serialize())
+}
+
+// CuckooExists returns false if the element surely isn't in the cuckoo filter
+func (engine *Engine) CuckooExists(name string, element string) (bool, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	cf, err := engine.getCuckooFilter(name)
+	if err != nil {
+		return false, err
+	}
+	return cf.Lookup(element), nil
+}
+
+// CuckooRemove deletes the element from the cuckoo filter, it returns false if the element wasn't found
+func (engine *Engine) CuckooRemove(name string, element string) (bool, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	cf, err := engine.getCuckooFilter(name)
+	if err != nil {
+		return false, err
+	}
+	if !cf.Delete(element) {
+		return false, nil
+	}
+	return true, engine.putSketch(CF_KEY, name, cf.Serialize())
+}
+
+// CuckooCount returns the number of elements in the cuckoo filter
+func (engine *Engine) CuckooCount(name string) (uint64, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	cf, err := engine.getCuckooFilter(name)
+	if err != nil {
+		return 0, err
+	}
+	return cf.Count(), nil
+}
+
+func (engine *Engine) getTopK(name string) (*topK.TopK, error) {
+	value, err := engine.getSketch(TK_KEY, name)
+	if err != nil {
+		return nil, err
+	}
+	tk := topK.Deserialize(value)
+	if tk == nil {
+		return nil, fmt.Errorf("%s %s is not valid", TK_KEY, name)
+	}
+	return tk, nil
+}
+
+// TopKCreate saves a new top k, counts of items are kept in a count min sketch with the passed epsilon and delta
+func (engine *Engine) TopKCreate(name string, k uint32, epsilon float64, delta float64) error {
+	if k < 1 {
+		return errors.New("k must be positive")
+	}
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return errors.New("epsilon and delta must be between 0 and 1")
+	}
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	return engine.putSketch(TK_KEY, name, topK.NewTopK(k, epsilon, delta).Serialize())
+}
+
+// TopKAdd counts one more occurrence of the item
+func (engine *Engine) TopKAdd(name string, item string) error {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	tk, err := engine.getTopK(name)
+	if err != nil {
+		return err
+	}
+	tk.Insert(item)
+	return engine.putSketch(TK_KEY, name, tk.Serialize())
+}
+
+// TopKList returns the k most frequent items, from the most frequent one
+func (engine *Engine) TopKList(name string) ([]topK.Item, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	tk, err := engine.getTopK(name)
+	if err != nil {
+		return nil, err
+	}
+	return tk.List(), nil
+}
+
+// TopKQuery returns the estimated number of occurrences of the item
+func (engine *Engine) TopKQuery(name string, item string) (uint32, error) {
+	engine.sketchLock.Lock()
+	defer engine.sketchLock.Unlock()
+	tk, err := engine.getTopK(name)
+	if err != nil {
+		return 0, err
+	}
+	return tk.Query(item), nil
+}
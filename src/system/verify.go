@@ -0,0 +1,98 @@
+package system
+
+import (
+	"sort"
+
+	config2 "github.com/natasakasikovic/Key-Value-engine/src/config"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/LRUCache"
+	lsmtree "github.com/natasakasikovic/Key-Value-engine/src/structs/LSMTree"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/WAL"
+	"github.com/natasakasikovic/Key-Value-engine/src/structs/sstable"
+	"github.com/natasakasikovic/Key-Value-engine/src/utils"
+)
+
+// VerifyReport is the result of checking the whole database, it is meant to be printed as JSON
+type VerifyReport struct {
+	Ok           bool                 `json:"ok"`
+	Tables       []sstable.TableCheck `json:"tables"`
+	Unreferenced []string             `json:"unreferenced"`          // sstable folders that the LSM tree doesn't know about
+	Wal          WAL.WALCheck         `json:"wal"`                   // records in the log that aren't flushed yet
+	Quarantined  map[string]string    `json:"quarantined,omitempty"` // names of moved sstables and where they were moved
+}
+
+// Verify checks every sstable of the LSM tree and the log, damaged sstables are reported and left as they are
+// If repair is true, damaged and unreferenced sstables are moved to sstable.QUARANTINE_PATH and removed from the LSM tree,
+// so the engine keeps working with the rest; records that were only in them are lost
+func (engine *Engine) Verify(repair bool) (*VerifyReport, error) {
+	report, err := verify(engine.LSMTree, engine.Wal, engine.Config.CompressionOn, engine.CompressionMap, repair)
+	if report != nil && len(report.Quarantined) > 0 {
+		// cached values could have been read from moved sstables
+		engine.Cache = LRUCache.NewLRUCache(engine.Config.LRUCacheMaxSize)
+	}
+	return report, err
+}
+
+// VerifyFiles does the same as Verify without opening the engine, so it works even if damaged sstables don't let the engine start
+// Sstables are taken from lsmtree.LSM_PATH as they are, and the log isn't replayed
+func VerifyFiles(repair bool) (*VerifyReport, error) {
+	config, err := config2.LoadConfig("config/config.json")
+	if err != nil {
+		return nil, err
+	}
+	dict, err := loadCompressionMap(config.CompressionOn)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := lsmtree.LoadTableNames(config.LSMTreeMaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	wal, err := WAL.NewWAL(config.WalSize, int32(config.MemTableMaxInstances))
+	if err != nil {
+		return nil, err
+	}
+	return verify(tree, wal, config.CompressionOn, dict, repair)
+}
+
+func verify(tree *lsmtree.LSMTree, wal *WAL.WAL, compressionOn bool, compressionMap map[string]uint64, repair bool) (*VerifyReport, error) {
+	report := &VerifyReport{Tables: make([]sstable.TableCheck, 0), Unreferenced: make([]string, 0)}
+	names := tree.TableNames()
+	known := make(map[string]bool, len(names))
+	var bad []string
+	for _, name := range names {
+		known[name] = true
+		check := sstable.CheckTable(name, compressionOn, compressionMap)
+		if len(check.Problems) > 0 {
+			bad = append(bad, name)
+		}
+		report.Tables = append(report.Tables, check)
+	}
+
+	dirs, err := utils.GetDirContent(sstable.PATH)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if !known[dir] {
+			report.Unreferenced = append(report.Unreferenced, dir)
+			bad = append(bad, dir)
+		}
+	}
+	report.Wal = wal.Check()
+	report.Ok = len(bad) == 0 && len(report.Wal.Problems) == 0
+
+	if !repair || len(bad) == 0 {
+		return report, nil
+	}
+	// folders after a moved one are renamed, so they are moved from the last one, which keeps names of the others
+	sort.Sort(sort.Reverse(sort.StringSlice(bad)))
+	report.Quarantined = make(map[string]string)
+	for _, name := range bad {
+		destination, err := tree.QuarantineTable(name)
+		if err != nil {
+			return report, err
+		}
+		report.Quarantined[name] = destination
+	}
+	return report, nil
+}
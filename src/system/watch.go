@@ -0,0 +1,96 @@
+package system
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/natasakasikovic/Key-Value-engine/src/model"
+)
+
+// Change is a put or delete of a key, sent to watchers after it is committed
+type Change struct {
+	Key       string
+	Value     []byte // nil if the key was deleted
+	Deleted   bool
+	Timestamp uint64
+}
+
+// returned by Watcher.Err when the watcher didn't read changes fast enough and was closed
+var ErrWatcherOverflow = errors.New("watcher fell behind, changes were dropped")
+
+// Watcher receives changes of keys with a prefix, changes of probabilistic structures aren't sent
+// Changes are kept in a buffer, a watcher whose buffer is full when a change is committed is closed,
+// so a slow watcher can't stop writes
+type Watcher struct {
+	engine  *Engine
+	prefix  string
+	changes chan Change
+	err     error
+}
+
+// Watch returns a watcher of changes of keys that begin with the prefix, buffer is the number of changes it can hold
+// It must be closed with Close when it is no longer needed
+func (engine *Engine) Watch(prefix string, buffer int) *Watcher {
+	watcher := &Watcher{engine: engine, prefix: prefix, changes: make(chan Change, buffer)}
+	engine.watchLock.Lock()
+	defer engine.watchLock.Unlock()
+	if engine.watchers == nil {
+		engine.watchers = make(map[*Watcher]struct{})
+	}
+	engine.watchers[watcher] = struct{}{}
+	return watcher
+}
+
+// Changes returns the channel of changes, it is closed when the watcher is closed
+func (watcher *Watcher) Changes() <-chan Change {
+	return watcher.changes
+}
+
+// Err returns ErrWatcherOverflow if the watcher was closed because it fell behind, it is valid after Changes is closed
+func (watcher *Watcher) Err() error {
+	watcher.engine.watchLock.Lock()
+	defer watcher.engine.watchLock.Unlock()
+	return watcher.err
+}
+
+func (watcher *Watcher) Close() {
+	watcher.engine.watchLock.Lock()
+	defer watcher.engine.watchLock.Unlock()
+	watcher.remove(nil)
+}
+
+// closes the channel, the watch lock must be held
+func (watcher *Watcher) remove(err error) {
+	if _, ok := watcher.engine.watchers[watcher]; !ok {
+		return
+	}
+	delete(watcher.engine.watchers, watcher)
+	watcher.err = err
+	close(watcher.changes)
+}
+
+// sends the committed record to watchers of its key
+func (engine *Engine) notifyWatchers(record *model.Record) {
+	if IsReservedKey(record.Key) {
+		return
+	}
+	engine.watchLock.Lock()
+	defer engine.watchLock.Unlock()
+	if len(engine.watchers) == 0 {
+		return
+	}
+	change := Change{Key: record.Key, Value: record.Value, Deleted: record.Tombstone == 1, Timestamp: record.Timestamp}
+	if change.Deleted {
+		change.Value = nil
+	}
+	for watcher := range engine.watchers {
+		if !strings.HasPrefix(change.Key, watcher.prefix) {
+			continue
+		}
+		select {
+		case watcher.changes <- change:
+		default:
+			watcher.remove(ErrWatcherOverflow)
+		}
+	}
+}